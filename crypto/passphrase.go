@@ -0,0 +1,338 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// passphraseMagic identifies the data encrypted with a passphrase
+var passphraseMagic = []byte("TRPP1")
+
+// passphraseStreamMagic identifies the streams encrypted with a passphrase
+var passphraseStreamMagic = []byte("TRPS1")
+
+// Parameters of the key derivation of the passphrase
+const (
+	passphraseSaltSize = 16
+	passphraseScryptN  = 1 << 15
+	passphraseScryptR  = 8
+	passphraseScryptP  = 1
+	passphraseKeySize  = 32
+
+	// Size of the chunks of a stream, each one sealed apart
+	passphraseChunkSize = 64 * 1024
+
+	// Size of the nonce prefix of a stream, the rest of the nonce is the
+	// counter of the chunk and the flag of the last chunk
+	passphraseNoncePrefixSize = 7
+)
+
+// passphraseKey derivates the key of a passphrase with scrypt
+func passphraseKey(passphrase string, salt []byte) ([]byte, error) {
+	return scrypt.Key([]byte(passphrase), salt, passphraseScryptN, passphraseScryptR, passphraseScryptP, passphraseKeySize)
+}
+
+// newPassphraseGCM returns the cipher of the key derivated from the passphrase
+func newPassphraseGCM(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := passphraseKey(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// EncryptWithPassphrase encrypts the data with a key derivated from the passphrase
+// The output carries the salt and the nonce needed to decrypt it
+func EncryptWithPassphrase(passphrase string, data []byte) ([]byte, error) {
+	if passphrase == "" {
+		return nil, errors.New("Passphrase is required")
+	}
+
+	salt := make([]byte, passphraseSaltSize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, err
+	}
+
+	gcm, err := newPassphraseGCM(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	header := append(append(append([]byte{}, passphraseMagic...), salt...), nonce...)
+
+	// The header is authenticated with the data
+	return gcm.Seal(header, nonce, data, header), nil
+}
+
+// DecryptWithPassphrase decrypts the data encrypted by EncryptWithPassphrase
+func DecryptWithPassphrase(passphrase string, data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, passphraseMagic) {
+		return nil, errors.New("Data was not encrypted with a passphrase")
+	}
+
+	saltEnd := len(passphraseMagic) + passphraseSaltSize
+	if len(data) < saltEnd {
+		return nil, errors.New("Encrypted data is truncated")
+	}
+
+	gcm, err := newPassphraseGCM(passphrase, data[len(passphraseMagic):saltEnd])
+	if err != nil {
+		return nil, err
+	}
+
+	headerEnd := saltEnd + gcm.NonceSize()
+	if len(data) < headerEnd {
+		return nil, errors.New("Encrypted data is truncated")
+	}
+
+	header := data[:headerEnd]
+
+	decrypted, err := gcm.Open(nil, data[saltEnd:headerEnd], data[headerEnd:], header)
+	if err != nil {
+		return nil, errors.New("Wrong passphrase or corrupted data")
+	}
+
+	return decrypted, nil
+}
+
+// passphraseStream seals or opens the chunks of a stream encrypted with a passphrase
+// Each chunk has its own nonce, made of the random prefix of the stream, the
+// counter of the chunk and the flag of the last chunk, so chunks cannot be
+// reordered, dropped or appended
+type passphraseStream struct {
+	gcm     cipher.AEAD
+	header  []byte
+	prefix  []byte
+	counter uint32
+}
+
+// nonce returns the nonce of the next chunk
+func (s *passphraseStream) nonce(last bool) ([]byte, error) {
+	if s.counter == ^uint32(0) {
+		return nil, errors.New("Encrypted stream is too long")
+	}
+
+	nonce := make([]byte, s.gcm.NonceSize())
+	copy(nonce, s.prefix)
+	binary.BigEndian.PutUint32(nonce[passphraseNoncePrefixSize:], s.counter)
+
+	if last {
+		nonce[len(nonce)-1] = 1
+	}
+
+	s.counter++
+
+	return nonce, nil
+}
+
+// passphraseWriter encrypts a stream with a passphrase
+type passphraseWriter struct {
+	passphraseStream
+	writer io.Writer
+	buffer []byte
+	closed bool
+}
+
+// NewPassphraseWriter returns a writer encrypting the stream written to it with
+// a key derivated from the passphrase, writing it to the writer
+// The stream is sealed in chunks, so it is never held whole in memory. Close
+// must be called to write the last chunk
+func NewPassphraseWriter(passphrase string, writer io.Writer) (io.WriteCloser, error) {
+	if passphrase == "" {
+		return nil, errors.New("Passphrase is required")
+	}
+
+	salt := make([]byte, passphraseSaltSize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, err
+	}
+
+	prefix := make([]byte, passphraseNoncePrefixSize)
+	if _, err := io.ReadFull(rand.Reader, prefix); err != nil {
+		return nil, err
+	}
+
+	gcm, err := newPassphraseGCM(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	header := append(append(append([]byte{}, passphraseStreamMagic...), salt...), prefix...)
+	if _, err := writer.Write(header); err != nil {
+		return nil, err
+	}
+
+	return &passphraseWriter{
+		passphraseStream: passphraseStream{gcm: gcm, header: header, prefix: prefix},
+		writer:           writer,
+		buffer:           make([]byte, 0, passphraseChunkSize),
+	}, nil
+}
+
+// Write encrypts the data, writing the chunks filled
+func (w *passphraseWriter) Write(data []byte) (int, error) {
+	if w.closed {
+		return 0, errors.New("Encrypted stream is closed")
+	}
+
+	written := 0
+	for len(data) > 0 {
+		// A full chunk is only sealed when more data comes, the last chunk is sealed on Close
+		if len(w.buffer) == passphraseChunkSize {
+			if err := w.seal(false); err != nil {
+				return written, err
+			}
+		}
+
+		size := passphraseChunkSize - len(w.buffer)
+		if size > len(data) {
+			size = len(data)
+		}
+
+		w.buffer = append(w.buffer, data[:size]...)
+		data = data[size:]
+		written += size
+	}
+
+	return written, nil
+}
+
+// Close writes the last chunk of the stream
+// The writer of the stream is not closed
+func (w *passphraseWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+
+	w.closed = true
+
+	// The last chunk is shorter than a full one, so a full chunk is sealed before it
+	if len(w.buffer) == passphraseChunkSize {
+		if err := w.seal(false); err != nil {
+			return err
+		}
+	}
+
+	return w.seal(true)
+}
+
+// seal encrypts the buffered chunk and writes it
+func (w *passphraseWriter) seal(last bool) error {
+	nonce, err := w.nonce(last)
+	if err != nil {
+		return err
+	}
+
+	if _, err := w.writer.Write(w.gcm.Seal(nil, nonce, w.buffer, w.header)); err != nil {
+		return err
+	}
+
+	w.buffer = w.buffer[:0]
+
+	return nil
+}
+
+// passphraseReader decrypts a stream encrypted with a passphrase
+type passphraseReader struct {
+	passphraseStream
+	reader    io.Reader
+	sealed    []byte
+	plaintext []byte
+	done      bool
+}
+
+// NewPassphraseReader returns a reader decrypting the stream written by the
+// writer of NewPassphraseWriter, read from the reader
+// Reading fails when the passphrase is wrong or the stream was tampered or truncated
+func NewPassphraseReader(passphrase string, reader io.Reader) (io.Reader, error) {
+	header := make([]byte, len(passphraseStreamMagic)+passphraseSaltSize+passphraseNoncePrefixSize)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, errors.New("Data was not encrypted with a passphrase")
+		}
+
+		return nil, err
+	}
+
+	if !bytes.HasPrefix(header, passphraseStreamMagic) {
+		return nil, errors.New("Data was not encrypted with a passphrase")
+	}
+
+	saltEnd := len(passphraseStreamMagic) + passphraseSaltSize
+
+	gcm, err := newPassphraseGCM(passphrase, header[len(passphraseStreamMagic):saltEnd])
+	if err != nil {
+		return nil, err
+	}
+
+	return &passphraseReader{
+		passphraseStream: passphraseStream{gcm: gcm, header: header, prefix: header[saltEnd:]},
+		reader:           reader,
+		sealed:           make([]byte, passphraseChunkSize+gcm.Overhead()),
+	}, nil
+}
+
+// Read returns the decrypted data, opening the chunks as they are read
+func (r *passphraseReader) Read(data []byte) (int, error) {
+	for len(r.plaintext) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+
+		if err := r.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	read := copy(data, r.plaintext)
+	r.plaintext = r.plaintext[read:]
+
+	return read, nil
+}
+
+// open reads and decrypts the next chunk
+// Only the last chunk is shorter than a full one
+func (r *passphraseReader) open() error {
+	size, err := io.ReadFull(r.reader, r.sealed)
+	switch {
+	case err == io.EOF:
+		return errors.New("Encrypted data is truncated")
+	case err != nil && err != io.ErrUnexpectedEOF:
+		return err
+	}
+
+	last := size < len(r.sealed)
+
+	nonce, err := r.nonce(last)
+	if err != nil {
+		return err
+	}
+
+	plaintext, err := r.gcm.Open(r.sealed[:0], nonce, r.sealed[:size], r.header)
+	if err != nil {
+		return errors.New("Wrong passphrase or corrupted data")
+	}
+
+	r.plaintext = plaintext
+	r.done = last
+
+	return nil
+}
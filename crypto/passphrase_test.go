@@ -0,0 +1,141 @@
+package crypto
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+)
+
+func TestEncryptWithPassphrase(t *testing.T) {
+	encrypted, err := EncryptWithPassphrase("passphrase", []byte("owner key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decrypted, err := DecryptWithPassphrase("passphrase", encrypted)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(decrypted) != "owner key" {
+		t.Errorf("expected the data, got %s", decrypted)
+	}
+
+	if _, err := DecryptWithPassphrase("wrong", encrypted); err == nil {
+		t.Error("a wrong passphrase should not decrypt")
+	}
+
+	for _, size := range []int{0, len(passphraseMagic) + 4, len(encrypted) - 1} {
+		if _, err := DecryptWithPassphrase("passphrase", encrypted[:size]); err == nil {
+			t.Errorf("data truncated to %d bytes should not decrypt", size)
+		}
+	}
+
+	tampered := append([]byte{}, encrypted...)
+	tampered[len(tampered)-1] ^= 1
+
+	if _, err := DecryptWithPassphrase("passphrase", tampered); err == nil {
+		t.Error("tampered data should not decrypt")
+	}
+}
+
+// encryptStream encrypts the data with the writer of NewPassphraseWriter
+func encryptStream(t *testing.T, passphrase string, data []byte) []byte {
+	t.Helper()
+
+	var encrypted bytes.Buffer
+
+	writer, err := NewPassphraseWriter(passphrase, &encrypted)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Writes in pieces not aligned to the chunks
+	for start := 0; start < len(data); start += 1000 {
+		end := start + 1000
+		if end > len(data) {
+			end = len(data)
+		}
+
+		if _, err := writer.Write(data[start:end]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return encrypted.Bytes()
+}
+
+// decryptStream decrypts the data with the reader of NewPassphraseReader
+func decryptStream(passphrase string, encrypted []byte) ([]byte, error) {
+	reader, err := NewPassphraseReader(passphrase, bytes.NewReader(encrypted))
+	if err != nil {
+		return nil, err
+	}
+
+	return ioutil.ReadAll(reader)
+}
+
+func TestPassphraseStream(t *testing.T) {
+	for _, size := range []int{0, 10, passphraseChunkSize, 2*passphraseChunkSize + 10} {
+		data := bytes.Repeat([]byte{byte(size)}, size)
+
+		encrypted := encryptStream(t, "passphrase", data)
+
+		decrypted, err := decryptStream("passphrase", encrypted)
+		if err != nil {
+			t.Fatalf("%d bytes: %v", size, err)
+		}
+
+		if !bytes.Equal(decrypted, data) {
+			t.Errorf("%d bytes: the decrypted data is different", size)
+		}
+	}
+}
+
+func TestPassphraseStreamRejectsWrongOrDamagedData(t *testing.T) {
+	data := bytes.Repeat([]byte("backup"), passphraseChunkSize/2)
+	encrypted := encryptStream(t, "passphrase", data)
+
+	headerSize := len(passphraseStreamMagic) + passphraseSaltSize + passphraseNoncePrefixSize
+	fullChunk := passphraseChunkSize + 16
+
+	if _, err := decryptStream("wrong", encrypted); err == nil {
+		t.Error("a wrong passphrase should not decrypt")
+	}
+
+	// Truncated in the header, in a chunk and at the end of a full chunk
+	for _, size := range []int{0, headerSize - 1, headerSize, headerSize + 100, headerSize + fullChunk, len(encrypted) - 1} {
+		if _, err := decryptStream("passphrase", encrypted[:size]); err == nil {
+			t.Errorf("data truncated to %d bytes should not decrypt", size)
+		}
+	}
+
+	for _, position := range []int{len(passphraseStreamMagic), headerSize - 1, headerSize + 10, len(encrypted) - 1} {
+		tampered := append([]byte{}, encrypted...)
+		tampered[position] ^= 1
+
+		if _, err := decryptStream("passphrase", tampered); err == nil {
+			t.Errorf("data tampered at %d should not decrypt", position)
+		}
+	}
+
+	// Data encrypted at once is not a stream
+	if _, err := decryptStream("passphrase", mustEncrypt(t, data)); err == nil {
+		t.Error("data not encrypted as a stream should not decrypt")
+	}
+}
+
+func mustEncrypt(t *testing.T, data []byte) []byte {
+	t.Helper()
+
+	encrypted, err := EncryptWithPassphrase("passphrase", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return encrypted
+}
@@ -0,0 +1,58 @@
+package crypto
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+)
+
+// GenerateSigningKey generates a new key pair to sign documents
+// Returns the base64 encoded private and public keys
+func GenerateSigningKey() (string, string, error) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(privateKey), base64.StdEncoding.EncodeToString(publicKey), nil
+}
+
+// Sign signs the message with the base64 encoded private key
+// Returns the base64 encoded signature
+func Sign(privateKey string, message []byte) (string, error) {
+	key, err := base64.StdEncoding.DecodeString(privateKey)
+	if err != nil {
+		return "", err
+	}
+
+	if len(key) != ed25519.PrivateKeySize {
+		return "", errors.New("Invalid private key")
+	}
+
+	signature := ed25519.Sign(ed25519.PrivateKey(key), message)
+
+	return base64.StdEncoding.EncodeToString(signature), nil
+}
+
+// ValidPublicKey returns if the base64 encoded public key can verify signatures
+func ValidPublicKey(publicKey string) bool {
+	key, err := base64.StdEncoding.DecodeString(publicKey)
+
+	return err == nil && len(key) == ed25519.PublicKeySize
+}
+
+// Verify verifies the base64 encoded signature of the message
+func Verify(publicKey string, message []byte, signature string) bool {
+	key, err := base64.StdEncoding.DecodeString(publicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return false
+	}
+
+	signatureBytes, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+
+	return ed25519.Verify(ed25519.PublicKey(key), message, signatureBytes)
+}
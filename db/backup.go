@@ -0,0 +1,31 @@
+package db
+
+import (
+	"errors"
+)
+
+// BackupTo writes a consistent copy of the database to the destination file
+func (db *OneSQLite) BackupTo(destination string) error {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	if _, err := db.db.Exec("VACUUM INTO $1", destination); err != nil {
+		return errors.New("Error copying database: " + err.Error())
+	}
+
+	return nil
+}
+
+// Path returns the path of the database file
+// Empty when the database is kept in memory
+func (db *OneSQLite) Path() string {
+	return db.dbPath
+}
+
+// Close closes the connections to the database
+func (db *OneSQLite) Close() error {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	return db.db.Close()
+}
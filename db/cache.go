@@ -0,0 +1,235 @@
+package db
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"gitlab.com/tramonto-one/go-tramonto/entities"
+)
+
+type dbArtifact struct {
+	TestIpns    string    `db:"test_ipns"`
+	Hash        string    `db:"hash"`
+	Name        string    `db:"name"`
+	Description string    `db:"description"`
+	Headers     string    `db:"headers"`
+	CreatedAt   time.Time `db:"created_at"`
+}
+
+type dbMember struct {
+	TestIpns  string    `db:"test_ipns"`
+	Email     string    `db:"email"`
+	Name      string    `db:"name"`
+	Role      string    `db:"role"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// secretHash identifies the secret that decrypted a cached metadata
+// The secret itself is not stored with the plaintext
+func secretHash(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// CacheMetadata caches a metadata read from or published to IPFS
+// The metadata is only found again with the same secret
+// When the IPNS is given, the artifacts and members of the test are replaced
+// by the ones in the metadata
+func (db *OneSQLite) CacheMetadata(ipnsHash, ipfsHash, secret string, metadata entities.Metadata) error {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	document, err := metadata.ToJSON()
+	if err != nil {
+		return errors.New("Error parsing metadata: " + err.Error())
+	}
+
+	tx, err := db.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// IPFS contents are immutable, so a cached document never changes
+	if _, err := tx.Exec(`
+		INSERT OR IGNORE INTO metadata_cache (ipfs_hash, secret_hash, document)
+		VALUES ($1, $2, $3)`, ipfsHash, secretHash(secret), string(document)); err != nil {
+		return errors.New("Error caching metadata: " + err.Error())
+	}
+
+	if ipnsHash != "" {
+		if _, err := tx.Exec("DELETE FROM artifacts WHERE test_ipns = $1", ipnsHash); err != nil {
+			return errors.New("Error caching artifacts: " + err.Error())
+		}
+
+		for _, artifact := range metadata.Artifacts {
+			headers, err := json.Marshal(artifact.Headers)
+			if err != nil {
+				return errors.New("Error parsing headers: " + err.Error())
+			}
+
+			if _, err := tx.Exec(`
+				INSERT OR REPLACE INTO artifacts (test_ipns, hash, name, description, headers, created_at)
+				VALUES ($1, $2, $3, $4, $5, $6)`,
+				ipnsHash, artifact.Hash, artifact.Name, artifact.Description, string(headers), artifact.CreatedAt); err != nil {
+				return errors.New("Error caching artifacts: " + err.Error())
+			}
+		}
+
+		if _, err := tx.Exec("DELETE FROM members WHERE test_ipns = $1", ipnsHash); err != nil {
+			return errors.New("Error caching members: " + err.Error())
+		}
+
+		for _, member := range metadata.Members {
+			if _, err := tx.Exec(`
+				INSERT OR REPLACE INTO members (test_ipns, email, name, role, created_at)
+				VALUES ($1, $2, $3, $4, $5)`,
+				ipnsHash, member.Email, member.Name, member.Role, member.CreatedAt); err != nil {
+				return errors.New("Error caching members: " + err.Error())
+			}
+		}
+
+		if err := indexTest(tx, ipnsHash, metadata); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// FindCachedMetadata returns the cached metadata of an IPFS hash decrypted with the secret
+// Returns false when the metadata is not cached
+func (db *OneSQLite) FindCachedMetadata(ipfsHash, secret string) (entities.Metadata, bool, error) {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	var document string
+	if err := db.db.Get(&document, `
+		SELECT document
+		FROM metadata_cache
+		WHERE ipfs_hash = $1 AND secret_hash = $2`, ipfsHash, secretHash(secret)); err != nil {
+		if err == sql.ErrNoRows {
+			return entities.Metadata{}, false, nil
+		}
+
+		return entities.Metadata{}, false, errors.New("Error finding cached metadata: " + err.Error())
+	}
+
+	metadata, err := entities.MetadataFromJSON([]byte(document))
+	if err != nil {
+		return entities.Metadata{}, false, errors.New("Error parsing cached metadata: " + err.Error())
+	}
+
+	return metadata, true, nil
+}
+
+// AttachCachedDetails fills the tests with their cached artifacts and members
+// and if they have edits pending sync
+func (db *OneSQLite) AttachCachedDetails(tests []entities.Test) error {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	if len(tests) == 0 {
+		return nil
+	}
+
+	ipnsHashes := []string{}
+	for _, test := range tests {
+		ipnsHashes = append(ipnsHashes, test.Ipns)
+	}
+
+	artifactsQuery, args, err := sqlx.In(`
+		SELECT *
+		FROM artifacts
+		WHERE test_ipns IN (?)
+		ORDER BY created_at`, ipnsHashes)
+	if err != nil {
+		return err
+	}
+
+	artifacts := []dbArtifact{}
+	if err := db.db.Select(&artifacts, artifactsQuery, args...); err != nil {
+		return errors.New("Error finding artifacts: " + err.Error())
+	}
+
+	membersQuery, args, err := sqlx.In(`
+		SELECT *
+		FROM members
+		WHERE test_ipns IN (?)
+		ORDER BY created_at`, ipnsHashes)
+	if err != nil {
+		return err
+	}
+
+	members := []dbMember{}
+	if err := db.db.Select(&members, membersQuery, args...); err != nil {
+		return errors.New("Error finding members: " + err.Error())
+	}
+
+	pendingQuery, args, err := sqlx.In(`
+		SELECT DISTINCT test_ipns
+		FROM outbox
+		WHERE test_ipns IN (?) AND given_up_at IS NULL`, ipnsHashes)
+	if err != nil {
+		return err
+	}
+
+	pendingIpns := []string{}
+	if err := db.db.Select(&pendingIpns, pendingQuery, args...); err != nil {
+		return errors.New("Error finding pending operations: " + err.Error())
+	}
+
+	pendingByTest := map[string]bool{}
+	for _, ipns := range pendingIpns {
+		pendingByTest[ipns] = true
+	}
+
+	artifactsByTest := map[string][]entities.Artifact{}
+	for _, artifact := range artifacts {
+		var headers map[string][]string
+		if err := json.Unmarshal([]byte(artifact.Headers), &headers); err != nil {
+			return errors.New("Error parsing headers: " + err.Error())
+		}
+
+		artifactsByTest[artifact.TestIpns] = append(artifactsByTest[artifact.TestIpns], entities.Artifact{
+			Name:        artifact.Name,
+			Description: artifact.Description,
+			CreatedAt:   artifact.CreatedAt,
+			Hash:        artifact.Hash,
+			Headers:     headers,
+		})
+	}
+
+	membersByTest := map[string][]entities.Member{}
+	for _, member := range members {
+		membersByTest[member.TestIpns] = append(membersByTest[member.TestIpns], entities.Member{
+			Name:      member.Name,
+			Email:     member.Email,
+			Role:      member.Role,
+			CreatedAt: member.CreatedAt,
+		})
+	}
+
+	for index := range tests {
+		ipns := tests[index].Ipns
+
+		tests[index].Metadata.Artifacts = artifactsByTest[ipns]
+		if tests[index].Metadata.Artifacts == nil {
+			tests[index].Metadata.Artifacts = []entities.Artifact{}
+		}
+
+		tests[index].PendingSync = pendingByTest[ipns]
+
+		tests[index].Metadata.Members = membersByTest[ipns]
+		if tests[index].Metadata.Members == nil {
+			tests[index].Metadata.Members = []entities.Member{}
+		}
+	}
+
+	return nil
+}
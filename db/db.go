@@ -35,6 +35,25 @@ func OpenOneSQLite(repoPath string) (*OneSQLite, error) {
 	return oneSQLite, nil
 }
 
+// OpenMemorySQLite creates a new instance of the One SQLite database kept in memory
+// The database has no file, so it is lost when closed and cannot be backed up
+func OpenMemorySQLite() (*OneSQLite, error) {
+	conn, err := sqlx.Open("sqlite3", ":memory:")
+	if err != nil {
+		return nil, err
+	}
+
+	// Each connection opens its own memory database
+	conn.SetMaxOpenConns(1)
+
+	oneSQLite := &OneSQLite{
+		db:  conn,
+		mux: new(sync.Mutex),
+	}
+
+	return oneSQLite, nil
+}
+
 // MigrateTables initializes and/or migrates the tables of the sqlite
 func (d *OneSQLite) MigrateTables() error {
 	d.mux.Lock()
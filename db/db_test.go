@@ -0,0 +1,63 @@
+package db
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"gitlab.com/tramonto-one/go-tramonto/entities"
+)
+
+// openTestDB opens a migrated database in a temporary directory
+// The returned function closes the database and removes the directory
+func openTestDB(t *testing.T) (*OneSQLite, func()) {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "tramonto-db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := OpenOneSQLite(dir)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+
+	closeDB := func() {
+		db.db.Close()
+		os.RemoveAll(dir)
+	}
+
+	if err := db.MigrateTables(); err != nil {
+		closeDB()
+		t.Fatal(err)
+	}
+
+	return db, closeDB
+}
+
+// insertSharedTest inserts a shared test with the name
+func insertSharedTest(t *testing.T, db *OneSQLite, name, ipns string) entities.Test {
+	t.Helper()
+
+	metadata, err := entities.NewMetadata(name, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	test := entities.Test{
+		Ipfs:           "QmIpfs" + ipns,
+		Ipns:           ipns,
+		IpnsKeyCreated: true,
+		IsOwner:        true,
+		Secret:         "secret",
+		Metadata:       metadata,
+	}
+
+	if err := db.InsertTest(test); err != nil {
+		t.Fatal(err)
+	}
+
+	return test
+}
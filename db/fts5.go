@@ -0,0 +1,9 @@
+//go:build !sqlite_fts5
+// +build !sqlite_fts5
+
+package db
+
+// The search index is a FTS5 virtual table, which go-sqlite3 only compiles
+// with the sqlite_fts5 build tag. Without it the migrations fail at runtime,
+// so the build is stopped here instead: build with -tags sqlite_fts5
+var _ = buildWithTheSqliteFts5Tag
@@ -0,0 +1,96 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"gitlab.com/tramonto-one/go-tramonto/entities"
+)
+
+type dbJob struct {
+	Type            string       `db:"type"`
+	IntervalSeconds int          `db:"interval_seconds"`
+	NextRunAt       time.Time    `db:"next_run_at"`
+	LastRunAt       sql.NullTime `db:"last_run_at"`
+	LastResult      string       `db:"last_result"`
+	LastError       string       `db:"last_error"`
+}
+
+// toEntity parses the stored job to the entity
+func (j dbJob) toEntity() entities.Job {
+	var lastRunAt *time.Time
+	if j.LastRunAt.Valid {
+		lastRunAt = &j.LastRunAt.Time
+	}
+
+	return entities.Job{
+		Type:            j.Type,
+		IntervalSeconds: j.IntervalSeconds,
+		NextRunAt:       j.NextRunAt,
+		LastRunAt:       lastRunAt,
+		LastResult:      j.LastResult,
+		LastError:       j.LastError,
+	}
+}
+
+// RegisterJob adds a job due at the time, or updates the interval of a registered one
+func (db *OneSQLite) RegisterJob(jobType string, intervalSeconds int, nextRunAt time.Time) error {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	if _, err := db.db.Exec(`
+		INSERT INTO jobs (type, interval_seconds, next_run_at) VALUES ($1, $2, $3)
+		ON CONFLICT (type) DO UPDATE SET interval_seconds = excluded.interval_seconds`,
+		jobType, intervalSeconds, nextRunAt.UTC()); err != nil {
+		return errors.New("Error registering job: " + err.Error())
+	}
+
+	return nil
+}
+
+// FindJobs finds all the registered jobs
+func (db *OneSQLite) FindJobs() ([]entities.Job, error) {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	jobs := []dbJob{}
+	if err := db.db.Select(&jobs, "SELECT * FROM jobs ORDER BY type"); err != nil {
+		return []entities.Job{}, errors.New("Error finding jobs: " + err.Error())
+	}
+
+	result := []entities.Job{}
+	for _, job := range jobs {
+		result = append(result, job.toEntity())
+	}
+
+	return result, nil
+}
+
+// ScheduleJob changes when a job runs next
+func (db *OneSQLite) ScheduleJob(jobType string, nextRunAt time.Time) error {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	if _, err := db.db.Exec("UPDATE jobs SET next_run_at = $1 WHERE type = $2", nextRunAt.UTC(), jobType); err != nil {
+		return errors.New("Error scheduling job: " + err.Error())
+	}
+
+	return nil
+}
+
+// CompleteJob registers the result of a run and when the job runs next
+func (db *OneSQLite) CompleteJob(jobType, result, lastError string, ranAt, nextRunAt time.Time) error {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	if _, err := db.db.Exec(`
+		UPDATE jobs
+		SET last_run_at = $1, last_result = $2, last_error = $3, next_run_at = $4
+		WHERE type = $5`,
+		ranAt.UTC(), result, lastError, nextRunAt.UTC(), jobType); err != nil {
+		return errors.New("Error completing job: " + err.Error())
+	}
+
+	return nil
+}
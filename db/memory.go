@@ -0,0 +1,531 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gitlab.com/tramonto-one/go-tramonto/entities"
+)
+
+type memoryTest struct {
+	test      entities.Test
+	isActive  bool
+	createdAt time.Time
+	updatedAt time.Time
+	sequence  int64
+}
+
+// sortValue returns the value of the test sorted by the sort key
+// Dates have the precision of the database, so both stores page the same way
+func (m *memoryTest) sortValue(sortKey string) string {
+	switch sortKey {
+	case entities.SortByCreatedAt:
+		return m.createdAt.UTC().Format(sqliteTimeFormat)
+	case entities.SortByName:
+		return m.test.Metadata.Name
+	default:
+		return m.updatedAt.UTC().Format(sqliteTimeFormat)
+	}
+}
+
+// MemoryTestRepository stores the tests in memory
+// The tests are lost when the process ends
+type MemoryTestRepository struct {
+	tests     []*memoryTest
+	sequence  int64
+	sequences map[string]int
+	mux       *sync.Mutex
+}
+
+// NewMemoryTestRepository creates a new empty in-memory repository
+func NewMemoryTestRepository() *MemoryTestRepository {
+	return &MemoryTestRepository{
+		tests:     []*memoryTest{},
+		sequences: map[string]int{},
+		mux:       new(sync.Mutex),
+	}
+}
+
+// MemoryTestRepository stores the tests in memory
+var _ TestRepository = (*MemoryTestRepository)(nil)
+
+// InsertTest inserts a new test to the memory
+func (m *MemoryTestRepository) InsertTest(test entities.Test) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	return m.insertTest(test)
+}
+
+// InsertTestWithUniqueName inserts a new test when no other test has its name
+// The name is checked and the test inserted holding the lock
+// Returns ErrTestNameExists when the name is already used
+func (m *MemoryTestRepository) InsertTestWithUniqueName(test entities.Test) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	if m.existsTestWithName(test.Metadata.Name) {
+		return ErrTestNameExists
+	}
+
+	return m.insertTest(test)
+}
+
+// insertTest inserts a new test, the caller holds the lock
+func (m *MemoryTestRepository) insertTest(test entities.Test) error {
+	// The IPNS hash is unique, as in the database
+	for _, stored := range m.tests {
+		if test.Ipns != "" && stored.test.Ipns == test.Ipns {
+			return errors.New("Error inserting test: test already exists")
+		}
+	}
+
+	// Only the columns of the database are kept
+	now := time.Now()
+	m.sequence++
+	m.tests = append(m.tests, &memoryTest{
+		test: entities.Test{
+			Ipfs:           test.Ipfs,
+			Ipns:           test.Ipns,
+			IpnsKeyCreated: test.IpnsKeyCreated,
+			IsOwner:        test.IsOwner,
+			Secret:         test.Secret,
+			Metadata: entities.Metadata{
+				ID:          test.Metadata.ID,
+				Name:        test.Metadata.Name,
+				Description: test.Metadata.Description,
+			},
+		},
+		isActive:  true,
+		createdAt: now,
+		updatedAt: now,
+		sequence:  m.sequence,
+	})
+
+	return nil
+}
+
+// findTests returns the stored tests matching the filter, favorites first
+// then the recently updated, as in the database
+func (m *MemoryTestRepository) findTests(filter func(stored *memoryTest) bool) []entities.Test {
+	matched := []*memoryTest{}
+	for _, stored := range m.tests {
+		if filter(stored) {
+			matched = append(matched, stored)
+		}
+	}
+
+	sort.SliceStable(matched, func(i, j int) bool {
+		if matched[i].test.IsFavorite != matched[j].test.IsFavorite {
+			return matched[i].test.IsFavorite
+		}
+
+		if !matched[i].updatedAt.Equal(matched[j].updatedAt) {
+			return matched[i].updatedAt.After(matched[j].updatedAt)
+		}
+
+		return matched[i].sequence > matched[j].sequence
+	})
+
+	result := []entities.Test{}
+	for _, stored := range matched {
+		result = append(result, stored.test)
+	}
+
+	return result
+}
+
+// FindTests finds all active tests
+func (m *MemoryTestRepository) FindTests() ([]entities.Test, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	return m.findTests(func(stored *memoryTest) bool {
+		return stored.isActive
+	}), nil
+}
+
+// FindFavoriteTests finds the active tests marked as favorite
+func (m *MemoryTestRepository) FindFavoriteTests() ([]entities.Test, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	return m.findTests(func(stored *memoryTest) bool {
+		return stored.isActive && stored.test.IsFavorite
+	}), nil
+}
+
+// FindArchivedTests finds all archived tests
+func (m *MemoryTestRepository) FindArchivedTests() ([]entities.Test, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	return m.findTests(func(stored *memoryTest) bool {
+		return !stored.isActive
+	}), nil
+}
+
+// FindTestsBySuite finds the active tests of a suite sorted by name
+func (m *MemoryTestRepository) FindTestsBySuite(suiteID string) ([]entities.Test, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	tests := m.findTests(func(stored *memoryTest) bool {
+		return stored.isActive && stored.test.SuiteID == suiteID
+	})
+
+	sort.SliceStable(tests, func(i, j int) bool {
+		return tests[i].Metadata.Name < tests[j].Metadata.Name
+	})
+
+	return tests, nil
+}
+
+// FindTestsByQuery finds a page of tests matching the query
+// Tests are paginated by cursor, with the same cursors of the database
+func (m *MemoryTestRepository) FindTestsByQuery(query entities.TestQuery) (entities.TestPage, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	page := entities.TestPage{Tests: []entities.Test{}}
+
+	if err := query.Validate(); err != nil {
+		return page, err
+	}
+
+	// Dates are sorted from the newest and names alphabetically
+	ascending := query.Sort == entities.SortByName
+
+	var cursor *queryCursor
+	if query.Cursor != "" {
+		decoded, err := decodeCursor(query.Cursor)
+		if err != nil {
+			return page, err
+		}
+
+		if decoded.Sort != query.Sort {
+			return page, errors.New("Invalid cursor: the cursor is of another sort")
+		}
+
+		cursor = &decoded
+	}
+
+	// before returns if the value and sequence come before the others in the sort
+	before := func(value string, sequence int64, otherValue string, otherSequence int64) bool {
+		if value != otherValue {
+			return (value < otherValue) == ascending
+		}
+
+		return (sequence < otherSequence) == ascending
+	}
+
+	text := strings.ToLower(strings.TrimSpace(query.Text))
+
+	matched := []*memoryTest{}
+	for _, stored := range m.tests {
+		switch {
+		case query.Status == entities.StatusActive && !stored.isActive,
+			query.Status == entities.StatusArchived && stored.isActive,
+			query.Ownership == entities.OwnershipOwned && !stored.test.IsOwner,
+			query.Ownership == entities.OwnershipShared && stored.test.IsOwner,
+			query.Favorite && !stored.test.IsFavorite:
+			continue
+		}
+
+		updatedAt := stored.updatedAt.UTC().Format(sqliteTimeFormat)
+		if query.UpdatedFrom != nil && updatedAt < query.UpdatedFrom.UTC().Format(sqliteTimeFormat) {
+			continue
+		}
+
+		if query.UpdatedTo != nil && updatedAt > query.UpdatedTo.UTC().Format(sqliteTimeFormat) {
+			continue
+		}
+
+		if text != "" &&
+			!strings.Contains(strings.ToLower(stored.test.Metadata.Name), text) &&
+			!strings.Contains(strings.ToLower(stored.test.Metadata.Description), text) {
+			continue
+		}
+
+		// Continues after the last test of the previous page
+		if cursor != nil {
+			if stored.test.IsFavorite != cursor.IsFavorite {
+				if stored.test.IsFavorite {
+					continue
+				}
+			} else if !before(cursor.Value, cursor.RowID, stored.sortValue(query.Sort), stored.sequence) {
+				continue
+			}
+		}
+
+		matched = append(matched, stored)
+	}
+
+	sort.SliceStable(matched, func(i, j int) bool {
+		if matched[i].test.IsFavorite != matched[j].test.IsFavorite {
+			return matched[i].test.IsFavorite
+		}
+
+		return before(matched[i].sortValue(query.Sort), matched[i].sequence, matched[j].sortValue(query.Sort), matched[j].sequence)
+	})
+
+	hasNextPage := len(matched) > query.Limit
+	if hasNextPage {
+		matched = matched[:query.Limit]
+	}
+
+	for _, stored := range matched {
+		page.Tests = append(page.Tests, stored.test)
+	}
+
+	if hasNextPage {
+		last := matched[len(matched)-1]
+
+		nextCursor, err := encodeCursor(queryCursor{
+			Sort:       query.Sort,
+			IsFavorite: last.test.IsFavorite,
+			Value:      last.sortValue(query.Sort),
+			RowID:      last.sequence,
+		})
+		if err != nil {
+			return entities.TestPage{Tests: []entities.Test{}}, err
+		}
+
+		page.NextCursor = nextCursor
+	}
+
+	return page, nil
+}
+
+// findTest returns the stored test with the IPNS hash
+func (m *MemoryTestRepository) findTest(ipnsHash string, onlyActive bool) *memoryTest {
+	for _, stored := range m.tests {
+		if stored.test.Ipns == ipnsHash && (stored.isActive || !onlyActive) {
+			return stored
+		}
+	}
+
+	return nil
+}
+
+// FindTestByIpns returns a single active test by its IPNS hash
+func (m *MemoryTestRepository) FindTestByIpns(ipnsHash string) (entities.Test, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	stored := m.findTest(ipnsHash, true)
+	if stored == nil {
+		return entities.Test{}, sql.ErrNoRows
+	}
+
+	return stored.test, nil
+}
+
+// FindAnyTestByIpns returns a single test by its IPNS hash, active or archived
+func (m *MemoryTestRepository) FindAnyTestByIpns(ipnsHash string) (entities.Test, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	stored := m.findTest(ipnsHash, false)
+	if stored == nil {
+		return entities.Test{}, sql.ErrNoRows
+	}
+
+	return stored.test, nil
+}
+
+// existsTestWithName returns if there is a test with the given name
+func (m *MemoryTestRepository) existsTestWithName(name string) bool {
+	for _, stored := range m.tests {
+		if stored.test.Metadata.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ExistsTestWithName returns if there is a test with the given name
+func (m *MemoryTestRepository) ExistsTestWithName(name string) (bool, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	return m.existsTestWithName(name), nil
+}
+
+// NextTestName generates the next sequential name with the given prefix
+// Names already used by tests are skipped
+func (m *MemoryTestRepository) NextTestName(prefix string) (string, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	for {
+		m.sequences[prefix]++
+
+		name := fmt.Sprintf("%s%04d", prefix, m.sequences[prefix])
+		if !m.existsTestWithName(name) {
+			return name, nil
+		}
+	}
+}
+
+// updateTest changes the active test with the IPNS hash
+// touch marks the test as updated, as the database does for the same change
+func (m *MemoryTestRepository) updateTest(ipnsHash string, touch bool, update func(test *entities.Test)) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	stored := m.findTest(ipnsHash, true)
+	if stored == nil {
+		return errors.New("No test updated with IPNS hash equals to " + ipnsHash)
+	}
+
+	update(&stored.test)
+
+	if touch {
+		stored.updatedAt = time.Now()
+	}
+
+	return nil
+}
+
+// UpdateIPFSHash updates the IPFS hash of a test
+func (m *MemoryTestRepository) UpdateIPFSHash(ipns, newIpfs string) error {
+	return m.updateTest(ipns, true, func(test *entities.Test) {
+		test.Ipfs = newIpfs
+	})
+}
+
+// UpdateIPFSHashFrom updates the IPFS hash of a test still pointing to the expected one
+func (m *MemoryTestRepository) UpdateIPFSHashFrom(ipns, expectedIpfs, newIpfs string) (bool, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	stored := m.findTest(ipns, true)
+	if stored == nil || stored.test.Ipfs != expectedIpfs {
+		return false, nil
+	}
+
+	stored.test.Ipfs = newIpfs
+	stored.updatedAt = time.Now()
+
+	return true, nil
+}
+
+// SetOwner marks if the node owns the IPNS key of a test
+func (m *MemoryTestRepository) SetOwner(ipnsHash string, isOwner bool) error {
+	return m.updateTest(ipnsHash, true, func(test *entities.Test) {
+		test.IsOwner = isOwner
+	})
+}
+
+// SetFavorite marks or unmarks an active test as favorite
+func (m *MemoryTestRepository) SetFavorite(ipnsHash string, isFavorite bool) error {
+	return m.updateTest(ipnsHash, false, func(test *entities.Test) {
+		test.IsFavorite = isFavorite
+	})
+}
+
+// SetTestSuite moves an active test to a suite
+func (m *MemoryTestRepository) SetTestSuite(ipnsHash, suiteID string) error {
+	return m.updateTest(ipnsHash, true, func(test *entities.Test) {
+		test.SuiteID = suiteID
+	})
+}
+
+// MarkSynced registers when the IPNS of a test was last published or resolved
+// Tests not stored are ignored, as in the database
+func (m *MemoryTestRepository) MarkSynced(ipnsHash string, syncedAt time.Time) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	if stored := m.findTest(ipnsHash, false); stored != nil {
+		syncedAt = syncedAt.UTC()
+		stored.test.LastSyncedAt = &syncedAt
+		stored.test.LastSyncError = ""
+	}
+
+	return nil
+}
+
+// MarkSyncError registers why the last publish or resolve of a test failed
+// Tests not stored are ignored, as in the database
+func (m *MemoryTestRepository) MarkSyncError(ipnsHash, reason string) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	if stored := m.findTest(ipnsHash, false); stored != nil {
+		stored.test.LastSyncError = reason
+	}
+
+	return nil
+}
+
+// setTestActive sets if a test is active or archived
+func (m *MemoryTestRepository) setTestActive(ipnsHash string, isActive bool) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	for _, stored := range m.tests {
+		if stored.test.Ipns == ipnsHash && stored.isActive != isActive {
+			stored.isActive = isActive
+			return nil
+		}
+	}
+
+	return errors.New("No test updated with IPNS hash equals to " + ipnsHash)
+}
+
+// ArchiveTest archives an active test, hiding it from the listings
+func (m *MemoryTestRepository) ArchiveTest(ipnsHash string) error {
+	return m.setTestActive(ipnsHash, false)
+}
+
+// RestoreTest restores an archived test
+func (m *MemoryTestRepository) RestoreTest(ipnsHash string) error {
+	return m.setTestActive(ipnsHash, true)
+}
+
+// DeleteTest permanently deletes a test, active or archived
+func (m *MemoryTestRepository) DeleteTest(ipnsHash string) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	for index, stored := range m.tests {
+		if stored.test.Ipns == ipnsHash {
+			m.tests = append(m.tests[:index], m.tests[index+1:]...)
+			return nil
+		}
+	}
+
+	return errors.New("No test deleted with IPNS hash equals to " + ipnsHash)
+}
+
+// SaveSharedTest saves the IPNS hash of a test recently shared
+func (m *MemoryTestRepository) SaveSharedTest(ipfsHash, ipnsHash string) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	updated := false
+	for _, stored := range m.tests {
+		if stored.test.Ipfs != ipfsHash || stored.test.Ipns != "" {
+			continue
+		}
+
+		stored.test.Ipns = ipnsHash
+		stored.test.IpnsKeyCreated = true
+		stored.updatedAt = time.Now()
+		updated = true
+	}
+
+	if !updated {
+		return errors.New("No test found with the given IPFS hash")
+	}
+
+	return nil
+}
@@ -35,6 +35,314 @@ var migrations = []darwin.Migration{
 			);
 		`,
 	},
+	darwin.Migration{
+		Version:     2,
+		Description: "Create the templates table",
+		Script: `
+			CREATE TABLE templates (
+				id          VARCHAR   NOT NULL PRIMARY KEY,
+				name        TEXT      NOT NULL,
+				document    TEXT      NOT NULL,
+				ipfs_hash   VARCHAR,
+				secret      TEXT,
+				created_at  TIMESTAMP NOT NULL
+										DEFAULT (CURRENT_TIMESTAMP),
+				updated_at  TIMESTAMP NOT NULL
+										DEFAULT (CURRENT_TIMESTAMP)
+			);
+		`,
+	},
+	darwin.Migration{
+		Version:     3,
+		Description: "Add the test ID and the name sequences",
+		Script: `
+			ALTER TABLE tests ADD COLUMN test_id VARCHAR;
+
+			UPDATE tests SET test_id = name;
+
+			CREATE TABLE name_sequences (
+				prefix VARCHAR NOT NULL PRIMARY KEY,
+				value  INTEGER NOT NULL
+									DEFAULT 0
+			);
+		`,
+	},
+	darwin.Migration{
+		Version:     4,
+		Description: "Create the signing key table",
+		Script: `
+			CREATE TABLE signing_keys (
+				id          INTEGER   NOT NULL PRIMARY KEY
+										CHECK (id = 1),
+				private_key TEXT      NOT NULL,
+				public_key  TEXT      NOT NULL,
+				created_at  TIMESTAMP NOT NULL
+										DEFAULT (CURRENT_TIMESTAMP)
+			);
+		`,
+	},
+	darwin.Migration{
+		Version:     5,
+		Description: "Create the projects and suites",
+		Script: `
+			CREATE TABLE projects (
+				id          VARCHAR   NOT NULL PRIMARY KEY,
+				name        TEXT      NOT NULL,
+				description TEXT      NOT NULL,
+				created_at  TIMESTAMP NOT NULL
+										DEFAULT (CURRENT_TIMESTAMP)
+			);
+
+			CREATE TABLE suites (
+				id          VARCHAR   NOT NULL PRIMARY KEY,
+				project_id  VARCHAR   REFERENCES projects (id),
+				name        TEXT      NOT NULL,
+				description TEXT      NOT NULL,
+				secret      TEXT,
+				ipfs_hash   VARCHAR,
+				ipns_hash   VARCHAR,
+				is_owner    BOOLEAN   NOT NULL
+										DEFAULT true,
+				created_at  TIMESTAMP NOT NULL
+										DEFAULT (CURRENT_TIMESTAMP),
+				updated_at  TIMESTAMP NOT NULL
+										DEFAULT (CURRENT_TIMESTAMP)
+			);
+
+			ALTER TABLE tests ADD COLUMN suite_id VARCHAR REFERENCES suites (id);
+		`,
+	},
+	darwin.Migration{
+		Version:     6,
+		Description: "Cache the metadata, artifacts and members of the tests",
+		Script: `
+			CREATE TABLE metadata_cache (
+				ipfs_hash VARCHAR   NOT NULL PRIMARY KEY,
+				document  TEXT      NOT NULL,
+				cached_at TIMESTAMP NOT NULL
+									DEFAULT (CURRENT_TIMESTAMP)
+			);
+
+			CREATE TABLE artifacts (
+				test_ipns   VARCHAR   NOT NULL,
+				hash        VARCHAR   NOT NULL,
+				name        TEXT      NOT NULL,
+				description TEXT      NOT NULL,
+				headers     TEXT      NOT NULL,
+				created_at  TIMESTAMP NOT NULL,
+				PRIMARY KEY (test_ipns, hash)
+			);
+
+			CREATE TABLE members (
+				test_ipns  VARCHAR   NOT NULL,
+				email      VARCHAR   NOT NULL,
+				name       TEXT      NOT NULL,
+				role       TEXT      NOT NULL,
+				created_at TIMESTAMP NOT NULL,
+				PRIMARY KEY (test_ipns, email)
+			);
+		`,
+	},
+	darwin.Migration{
+		Version:     7,
+		Description: "Creating the full-text search index",
+		Script: `
+			CREATE VIRTUAL TABLE search_index USING fts5(
+				test_ipns UNINDEXED,
+				kind      UNINDEXED,
+				target    UNINDEXED,
+				title,
+				content,
+				tokenize = 'unicode61 remove_diacritics 2'
+			);
+		`,
+	},
+	darwin.Migration{
+		Version:     8,
+		Description: "Creating the outbox of operations pending sync",
+		Script: `
+			CREATE TABLE outbox (
+				id              INTEGER   NOT NULL PRIMARY KEY AUTOINCREMENT,
+				kind            VARCHAR   NOT NULL,
+				test_ipns       VARCHAR   NOT NULL,
+				base_ipfs       VARCHAR   NOT NULL,
+				edited_ipfs     VARCHAR   NOT NULL,
+				attempts        INTEGER   NOT NULL
+										DEFAULT 0,
+				last_error      TEXT      NOT NULL
+										DEFAULT '',
+				next_attempt_at TIMESTAMP NOT NULL,
+				created_at      TIMESTAMP NOT NULL
+										DEFAULT (CURRENT_TIMESTAMP)
+			);
+
+			CREATE INDEX outbox_test_ipns ON outbox (test_ipns);
+		`,
+	},
+	darwin.Migration{
+		Version:     9,
+		Description: "Add the sync state of the tests and the IPNS history",
+		Script: `
+			ALTER TABLE tests ADD COLUMN last_synced_at TIMESTAMP;
+			ALTER TABLE tests ADD COLUMN last_sync_error TEXT NOT NULL DEFAULT '';
+
+			CREATE TABLE ipns_history (
+				id         INTEGER   NOT NULL PRIMARY KEY AUTOINCREMENT,
+				test_ipns  VARCHAR   NOT NULL,
+				ipfs_hash  VARCHAR   NOT NULL,
+				source     VARCHAR   NOT NULL,
+				created_at TIMESTAMP NOT NULL
+									DEFAULT (CURRENT_TIMESTAMP)
+			);
+
+			CREATE INDEX ipns_history_test_ipns ON ipns_history (test_ipns);
+		`,
+	},
+	// Only the IPNS hash is unique. Two IPNS records may point to the same IPFS
+	// content, as records published by other owners are out of the control of
+	// the device, and tests already stored that way could not be merged without
+	// losing one of them
+	darwin.Migration{
+		Version:     10,
+		Description: "Merge the tests imported more than once and add a unique index to the IPNS hash",
+		Script: `
+			UPDATE tests
+			SET is_key_generated = (
+					SELECT MAX(duplicate.is_key_generated) FROM tests AS duplicate WHERE duplicate.ipns_hash = tests.ipns_hash
+				),
+				is_favorite = (
+					SELECT MAX(duplicate.is_favorite) FROM tests AS duplicate WHERE duplicate.ipns_hash = tests.ipns_hash
+				),
+				created_at = (
+					SELECT MIN(duplicate.created_at) FROM tests AS duplicate WHERE duplicate.ipns_hash = tests.ipns_hash
+				),
+				last_synced_at = (
+					SELECT MAX(duplicate.last_synced_at) FROM tests AS duplicate WHERE duplicate.ipns_hash = tests.ipns_hash
+				),
+				test_id = COALESCE(test_id, (
+					SELECT duplicate.test_id FROM tests AS duplicate
+					WHERE duplicate.ipns_hash = tests.ipns_hash AND duplicate.test_id IS NOT NULL
+					ORDER BY duplicate.is_owner DESC, duplicate.updated_at DESC
+					LIMIT 1
+				)),
+				suite_id = COALESCE(suite_id, (
+					SELECT duplicate.suite_id FROM tests AS duplicate
+					WHERE duplicate.ipns_hash = tests.ipns_hash AND duplicate.suite_id IS NOT NULL
+					ORDER BY duplicate.updated_at DESC
+					LIMIT 1
+				))
+			WHERE ipns_hash IS NOT NULL AND ipns_hash <> '';
+
+			DELETE FROM tests
+			WHERE rowid IN (
+				SELECT rowid
+				FROM (
+					SELECT rowid, ROW_NUMBER() OVER (
+						PARTITION BY ipns_hash
+						ORDER BY is_owner DESC, is_active DESC, updated_at DESC, rowid DESC
+					) AS position
+					FROM tests
+					WHERE ipns_hash IS NOT NULL AND ipns_hash <> ''
+				)
+				WHERE position > 1
+			);
+
+			CREATE UNIQUE INDEX tests_ipns_hash ON tests (ipns_hash)
+			WHERE ipns_hash IS NOT NULL AND ipns_hash <> '';
+		`,
+	},
+	darwin.Migration{
+		Version:     11,
+		Description: "Creating the settings table",
+		Script: `
+			CREATE TABLE settings (
+				key        VARCHAR   NOT NULL PRIMARY KEY,
+				value      TEXT      NOT NULL,
+				updated_at TIMESTAMP NOT NULL
+									DEFAULT (CURRENT_TIMESTAMP)
+			);
+		`,
+	},
+	darwin.Migration{
+		Version:     12,
+		Description: "Creating the background jobs table",
+		Script: `
+			CREATE TABLE jobs (
+				type             VARCHAR   NOT NULL PRIMARY KEY,
+				interval_seconds INTEGER   NOT NULL,
+				next_run_at      TIMESTAMP NOT NULL,
+				last_run_at      TIMESTAMP,
+				last_result      VARCHAR   NOT NULL
+										DEFAULT '',
+				last_error       TEXT      NOT NULL
+										DEFAULT ''
+			);
+		`,
+	},
+	darwin.Migration{
+		Version:     13,
+		Description: "Creating the IPNS records republished by the owner",
+		Script: `
+			CREATE TABLE ipns_records (
+				ipns_hash    VARCHAR   NOT NULL PRIMARY KEY,
+				key_name     VARCHAR   NOT NULL,
+				ipfs_hash    VARCHAR   NOT NULL,
+				published_at TIMESTAMP NOT NULL,
+				last_error   TEXT      NOT NULL
+									DEFAULT ''
+			);
+
+			INSERT INTO ipns_records (ipns_hash, key_name, ipfs_hash, published_at)
+			SELECT ipns_hash, test_id, ipfs_hash, updated_at
+			FROM tests
+			WHERE is_owner
+				AND ipns_hash IS NOT NULL AND ipns_hash <> ''
+				AND ipfs_hash IS NOT NULL AND ipfs_hash <> ''
+				AND test_id IS NOT NULL AND test_id <> '';
+		`,
+	},
+	darwin.Migration{
+		Version:     14,
+		Description: "Key the cached metadata by the secret that decrypted it",
+		Script: `
+			DROP TABLE metadata_cache;
+
+			CREATE TABLE metadata_cache (
+				ipfs_hash   VARCHAR   NOT NULL,
+				secret_hash VARCHAR   NOT NULL,
+				document    TEXT      NOT NULL,
+				cached_at   TIMESTAMP NOT NULL
+									DEFAULT (CURRENT_TIMESTAMP),
+				PRIMARY KEY (ipfs_hash, secret_hash)
+			);
+		`,
+	},
+	darwin.Migration{
+		Version:     15,
+		Description: "Add the terminal state of the outbox operations",
+		Script: `
+			ALTER TABLE outbox ADD COLUMN given_up_at TIMESTAMP;
+		`,
+	},
+	darwin.Migration{
+		Version:     16,
+		Description: "Record the artifact contents and comments already indexed",
+		Script: `
+			CREATE TABLE indexed_contents (
+				test_ipns  VARCHAR   NOT NULL,
+				kind       VARCHAR   NOT NULL,
+				target     VARCHAR   NOT NULL,
+				indexed_at TIMESTAMP NOT NULL
+								DEFAULT (CURRENT_TIMESTAMP),
+				PRIMARY KEY (test_ipns, kind, target)
+			);
+
+			INSERT OR IGNORE INTO indexed_contents (test_ipns, kind, target)
+			SELECT test_ipns, kind, target
+			FROM search_index
+			WHERE kind IN ('artifactContent', 'comment');
+		`,
+	},
 }
 
 // migrate will execute the migrations to the SQLite database
@@ -0,0 +1,140 @@
+package db
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/GuiaBolso/darwin"
+)
+
+func TestMigrationMergesDuplicatedTests(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tramonto-db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	db, err := OpenOneSQLite(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.db.Close()
+
+	// Migrates up to the version before the unique index
+	driver := darwin.NewGenericDriver(db.db.DB, darwin.SqliteDialect{})
+	if err := darwin.New(driver, migrations[:9], nil).Migrate(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The owned copy is kept over the newer shared one, then the active over the archived
+	rows := []struct {
+		name       string
+		ipfs       string
+		ipns       string
+		isOwner    bool
+		isActive   bool
+		isFavorite bool
+		suiteID    interface{}
+		updatedAt  string
+	}{
+		{"TR0001", "QmIpfs1", "QmA", true, true, false, nil, "2026-01-01 10:00:00"},
+		{"TR0001-shared", "QmIpfs2", "QmA", false, true, true, "suite", "2026-01-02 10:00:00"},
+		{"TR0002-archived", "QmIpfs3", "QmB", false, false, false, nil, "2026-01-02 10:00:00"},
+		{"TR0002", "QmIpfs4", "QmB", false, true, false, nil, "2026-01-01 10:00:00"},
+		{"TR0003", "QmIpfs5", "QmC", false, true, false, nil, "2026-01-01 10:00:00"},
+		{"TR0004", "QmIpfs5", "QmD", false, true, false, nil, "2026-01-02 10:00:00"},
+	}
+
+	for _, row := range rows {
+		if _, err := db.db.Exec(`
+			INSERT INTO tests (test_id, name, description, secret, ipfs_hash, ipns_hash, is_key_generated, is_owner, is_active, is_favorite, suite_id, updated_at)
+			VALUES ($1, $1, '', 'secret', $2, $3, 1, $4, $5, $6, $7, $8)`,
+			row.name, row.ipfs, row.ipns, row.isOwner, row.isActive, row.isFavorite, row.suiteID, row.updatedAt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The records of the test are kept by its IPNS hash
+	if _, err := db.db.Exec(`
+		INSERT INTO suites (id, name, description) VALUES ('suite', 'Release', '');
+		INSERT INTO members (test_ipns, email, name, role, created_at) VALUES ('QmA', 'john@tramonto.one', 'John', 'qa', CURRENT_TIMESTAMP);
+		INSERT INTO artifacts (test_ipns, hash, name, description, headers, created_at) VALUES ('QmA', 'QmLog', 'log', '', '{}', CURRENT_TIMESTAMP);
+		INSERT INTO outbox (kind, test_ipns, base_ipfs, edited_ipfs, next_attempt_at) VALUES ('publishTest', 'QmA', 'QmIpfs2', 'QmIpfs6', CURRENT_TIMESTAMP);
+		INSERT INTO ipns_history (test_ipns, ipfs_hash, source) VALUES ('QmA', 'QmIpfs2', 'resolve');`); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.MigrateTables(); err != nil {
+		t.Fatal(err)
+	}
+
+	names := []string{}
+	if err := db.db.Select(&names, "SELECT name FROM tests ORDER BY name"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Tests with the same content and different IPNS records are kept
+	expected := []string{"TR0001", "TR0002", "TR0003", "TR0004"}
+	if len(names) != len(expected) {
+		t.Fatalf("expected tests %v, got %v", expected, names)
+	}
+
+	for index := range expected {
+		if names[index] != expected[index] {
+			t.Fatalf("expected tests %v, got %v", expected, names)
+		}
+	}
+
+	// The kept test has what was set in the removed copy
+	test, err := db.FindTestByIpns("QmA")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !test.IsOwner || test.Ipfs != "QmIpfs1" || test.SuiteID != "suite" {
+		t.Errorf("unexpected test %+v", test)
+	}
+
+	favorites, err := db.FindFavoriteTests()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectNames(t, favorites, "TR0001")
+
+	suiteTests, err := db.FindTestsBySuite("suite")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectNames(t, suiteTests, "TR0001")
+
+	for _, table := range []string{"members", "artifacts", "outbox", "ipns_history"} {
+		var count int
+		if err := db.db.Get(&count, "SELECT COUNT(*) FROM "+table+" WHERE test_ipns = 'QmA'"); err != nil {
+			t.Fatal(err)
+		}
+
+		if count != 1 {
+			t.Errorf("expected the %s of the merged test, got %d", table, count)
+		}
+	}
+
+	if pending, err := db.HasPendingOperations("QmA"); err != nil || !pending {
+		t.Errorf("the merged test should keep its pending operations, got %v %v", pending, err)
+	}
+
+	// The IPNS hashes are unique from now on
+	if _, err := db.db.Exec(`
+		INSERT INTO tests (test_id, name, description, secret, ipfs_hash, ipns_hash, is_key_generated, is_owner)
+		VALUES ('TR0005', 'TR0005', '', 'secret', 'QmIpfs7', 'QmA', 1, 0)`); err == nil {
+		t.Error("a test with a duplicated IPNS hash should not be inserted")
+	}
+
+	if _, err := db.db.Exec(`
+		INSERT INTO tests (test_id, name, description, secret, ipfs_hash, ipns_hash, is_key_generated, is_owner)
+		VALUES ('TR0005', 'TR0005', '', 'secret', 'QmIpfs1', 'QmE', 1, 0)`); err != nil {
+		t.Errorf("a test with a duplicated IPFS hash should be inserted, got %v", err)
+	}
+}
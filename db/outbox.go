@@ -0,0 +1,207 @@
+package db
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"gitlab.com/tramonto-one/go-tramonto/entities"
+)
+
+type dbOutboxOperation struct {
+	ID            int64      `db:"id"`
+	Kind          string     `db:"kind"`
+	TestIpns      string     `db:"test_ipns"`
+	BaseIpfs      string     `db:"base_ipfs"`
+	EditedIpfs    string     `db:"edited_ipfs"`
+	Attempts      int        `db:"attempts"`
+	LastError     string     `db:"last_error"`
+	NextAttemptAt time.Time  `db:"next_attempt_at"`
+	GivenUpAt     *time.Time `db:"given_up_at"`
+	CreatedAt     time.Time  `db:"created_at"`
+}
+
+// toEntity parses the stored operation to the entity
+func (o dbOutboxOperation) toEntity() entities.OutboxOperation {
+	return entities.OutboxOperation{
+		ID:            o.ID,
+		Kind:          o.Kind,
+		TestIpns:      o.TestIpns,
+		BaseIpfs:      o.BaseIpfs,
+		EditedIpfs:    o.EditedIpfs,
+		Attempts:      o.Attempts,
+		LastError:     o.LastError,
+		NextAttemptAt: o.NextAttemptAt,
+		GivenUpAt:     o.GivenUpAt,
+		CreatedAt:     o.CreatedAt,
+	}
+}
+
+// EnqueueOperation adds an operation to the end of the outbox
+// The operation is due right away
+func (db *OneSQLite) EnqueueOperation(operation entities.OutboxOperation) error {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	if _, err := db.db.Exec(`
+		INSERT INTO outbox (kind, test_ipns, base_ipfs, edited_ipfs, last_error, next_attempt_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		operation.Kind, operation.TestIpns, operation.BaseIpfs, operation.EditedIpfs, operation.LastError, time.Now().UTC()); err != nil {
+		return errors.New("Error enqueuing operation: " + err.Error())
+	}
+
+	return nil
+}
+
+// FindPendingOperations finds the operations of the outbox not given up in order
+func (db *OneSQLite) FindPendingOperations() ([]entities.OutboxOperation, error) {
+	return db.findOperations("SELECT * FROM outbox WHERE given_up_at IS NULL ORDER BY id")
+}
+
+// FindFailedOperations finds the operations of the outbox given up in order
+func (db *OneSQLite) FindFailedOperations() ([]entities.OutboxOperation, error) {
+	return db.findOperations("SELECT * FROM outbox WHERE given_up_at IS NOT NULL ORDER BY id")
+}
+
+// findOperations finds the operations of the outbox returned by the query
+func (db *OneSQLite) findOperations(query string) ([]entities.OutboxOperation, error) {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	operations := []dbOutboxOperation{}
+	if err := db.db.Select(&operations, query); err != nil {
+		return []entities.OutboxOperation{}, errors.New("Error finding operations: " + err.Error())
+	}
+
+	result := []entities.OutboxOperation{}
+	for _, operation := range operations {
+		result = append(result, operation.toEntity())
+	}
+
+	return result, nil
+}
+
+// HasPendingOperations returns if a test has operations in the outbox not given up
+func (db *OneSQLite) HasPendingOperations(testIpns string) (bool, error) {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	var count int
+	if err := db.db.Get(&count, "SELECT COUNT(*) FROM outbox WHERE test_ipns = $1 AND given_up_at IS NULL", testIpns); err != nil {
+		return false, errors.New("Error counting operations: " + err.Error())
+	}
+
+	return count > 0, nil
+}
+
+// HasPendingOperationsAfter returns if a test has operations not given up
+// enqueued after the operation with the ID
+func (db *OneSQLite) HasPendingOperationsAfter(testIpns string, id int64) (bool, error) {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	var count int
+	if err := db.db.Get(&count, "SELECT COUNT(*) FROM outbox WHERE test_ipns = $1 AND id > $2 AND given_up_at IS NULL", testIpns, id); err != nil {
+		return false, errors.New("Error counting operations: " + err.Error())
+	}
+
+	return count > 0, nil
+}
+
+// CompleteOperation removes a synced operation from the outbox
+func (db *OneSQLite) CompleteOperation(id int64) error {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	if _, err := db.db.Exec("DELETE FROM outbox WHERE id = $1", id); err != nil {
+		return errors.New("Error completing operation: " + err.Error())
+	}
+
+	return nil
+}
+
+// FailOperation registers a failed attempt of an operation
+// The operation is retried after a backoff that grows with the attempts
+func (db *OneSQLite) FailOperation(id int64, reason string) error {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	var attempts int
+	if err := db.db.Get(&attempts, "SELECT attempts FROM outbox WHERE id = $1", id); err != nil {
+		return errors.New("Error finding operation: " + err.Error())
+	}
+
+	attempts++
+	nextAttemptAt := time.Now().UTC().Add(entities.OutboxBackoff(attempts))
+
+	if _, err := db.db.Exec(`
+		UPDATE outbox
+		SET attempts = $1, last_error = $2, next_attempt_at = $3
+		WHERE id = $4`, attempts, reason, nextAttemptAt, id); err != nil {
+		return errors.New("Error updating operation: " + err.Error())
+	}
+
+	return nil
+}
+
+// GiveUpOperation registers the last failed attempt of an operation
+// The operation is not tried again until it is retried. The next operation of
+// the test was edited over the one given up, so it is rebased onto its base
+// revision: otherwise the merge would take the edit given up as removed
+func (db *OneSQLite) GiveUpOperation(id int64, reason string) error {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	tx, err := db.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	operation := dbOutboxOperation{}
+	if err := tx.Get(&operation, "SELECT * FROM outbox WHERE id = $1", id); err != nil {
+		return errors.New("Error finding operation: " + err.Error())
+	}
+
+	if _, err := tx.Exec(`
+		UPDATE outbox
+		SET attempts = attempts + 1, last_error = $1, given_up_at = $2
+		WHERE id = $3`, reason, time.Now().UTC(), id); err != nil {
+		return errors.New("Error updating operation: " + err.Error())
+	}
+
+	if _, err := tx.Exec(`
+		UPDATE outbox
+		SET base_ipfs = $1
+		WHERE test_ipns = $2 AND base_ipfs = $3 AND id > $4 AND given_up_at IS NULL`,
+		operation.BaseIpfs, operation.TestIpns, operation.EditedIpfs, id); err != nil {
+		return errors.New("Error rebasing operations: " + err.Error())
+	}
+
+	return tx.Commit()
+}
+
+// RetryOperation makes an operation given up due right away
+func (db *OneSQLite) RetryOperation(id int64) error {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	sqlResult, err := db.db.Exec(`
+		UPDATE outbox
+		SET attempts = 0, given_up_at = NULL, next_attempt_at = $1
+		WHERE id = $2 AND given_up_at IS NOT NULL`, time.Now().UTC(), id)
+	if err != nil {
+		return errors.New("Error updating operation: " + err.Error())
+	}
+
+	rowsAffected, err := sqlResult.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return errors.New("No operation given up with ID " + strconv.FormatInt(id, 10))
+	}
+
+	return nil
+}
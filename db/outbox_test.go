@@ -0,0 +1,181 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"gitlab.com/tramonto-one/go-tramonto/entities"
+)
+
+func enqueueTestOperation(t *testing.T, db *OneSQLite, ipns, baseIpfs, editedIpfs string) {
+	t.Helper()
+
+	if err := db.EnqueueOperation(entities.OutboxOperation{
+		Kind:       entities.OperationPublishRevision,
+		TestIpns:   ipns,
+		BaseIpfs:   baseIpfs,
+		EditedIpfs: editedIpfs,
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOutboxKeepsOrder(t *testing.T) {
+	db, closeDB := openTestDB(t)
+	defer closeDB()
+
+	enqueueTestOperation(t, db, "QmA", "QmBase", "QmEdit1")
+	enqueueTestOperation(t, db, "QmB", "QmBase", "QmEdit2")
+	enqueueTestOperation(t, db, "QmA", "QmEdit1", "QmEdit3")
+
+	operations, err := db.FindPendingOperations()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"QmEdit1", "QmEdit2", "QmEdit3"}
+	if len(operations) != len(expected) {
+		t.Fatalf("expected %d operations, got %d", len(expected), len(operations))
+	}
+
+	for index, operation := range operations {
+		if operation.EditedIpfs != expected[index] {
+			t.Errorf("operation %d should publish %s, got %s", index, expected[index], operation.EditedIpfs)
+		}
+
+		if operation.NextAttemptAt.After(time.Now()) {
+			t.Errorf("operation %d should be due right away", index)
+		}
+	}
+
+	if later, err := db.HasPendingOperationsAfter("QmA", operations[0].ID); err != nil || !later {
+		t.Errorf("expected a later operation of QmA, got %v %v", later, err)
+	}
+
+	if later, err := db.HasPendingOperationsAfter("QmA", operations[2].ID); err != nil || later {
+		t.Errorf("expected no later operation of QmA, got %v %v", later, err)
+	}
+}
+
+func TestOutboxBacksOffFailedOperations(t *testing.T) {
+	db, closeDB := openTestDB(t)
+	defer closeDB()
+
+	enqueueTestOperation(t, db, "QmA", "QmBase", "QmEdit")
+
+	operations, err := db.FindPendingOperations()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for attempt := 1; attempt <= 2; attempt++ {
+		before := time.Now()
+
+		if err := db.FailOperation(operations[0].ID, "offline"); err != nil {
+			t.Fatal(err)
+		}
+
+		failed, err := db.FindPendingOperations()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if failed[0].Attempts != attempt || failed[0].LastError != "offline" {
+			t.Errorf("unexpected operation after %d attempts %+v", attempt, failed[0])
+		}
+
+		if backoff := failed[0].NextAttemptAt.Sub(before); backoff < entities.OutboxBackoff(attempt)-time.Second {
+			t.Errorf("operation should wait %s after %d attempts, waits %s", entities.OutboxBackoff(attempt), attempt, backoff)
+		}
+	}
+}
+
+func TestOutboxGivesUpOperations(t *testing.T) {
+	db, closeDB := openTestDB(t)
+	defer closeDB()
+
+	insertSharedTest(t, db, "TR0001", "QmA")
+	enqueueTestOperation(t, db, "QmA", "QmBase", "QmEdit")
+
+	operations, err := db.FindPendingOperations()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.GiveUpOperation(operations[0].ID, "conflict"); err != nil {
+		t.Fatal(err)
+	}
+
+	if pending, err := db.HasPendingOperations("QmA"); err != nil || pending {
+		t.Errorf("an operation given up should not be pending, got %v %v", pending, err)
+	}
+
+	tests, err := db.FindTests()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.AttachCachedDetails(tests); err != nil {
+		t.Fatal(err)
+	}
+
+	if tests[0].PendingSync {
+		t.Error("a test with operations given up should not be pending sync")
+	}
+
+	failed, err := db.FindFailedOperations()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(failed) != 1 || failed[0].GivenUpAt == nil || failed[0].LastError != "conflict" {
+		t.Fatalf("unexpected operations given up %+v", failed)
+	}
+
+	if err := db.RetryOperation(failed[0].ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.RetryOperation(failed[0].ID); err == nil {
+		t.Error("an operation not given up should not be retried")
+	}
+
+	if pending, err := db.HasPendingOperations("QmA"); err != nil || !pending {
+		t.Errorf("a retried operation should be pending, got %v %v", pending, err)
+	}
+}
+
+func TestOutboxRebasesTheOperationsAfterAGivenUpOne(t *testing.T) {
+	db, closeDB := openTestDB(t)
+	defer closeDB()
+
+	enqueueTestOperation(t, db, "QmA", "QmBase", "QmEdit1")
+	enqueueTestOperation(t, db, "QmB", "QmEdit1", "QmEdit2")
+	enqueueTestOperation(t, db, "QmA", "QmEdit1", "QmEdit3")
+	enqueueTestOperation(t, db, "QmA", "QmEdit3", "QmEdit4")
+
+	operations, err := db.FindPendingOperations()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.GiveUpOperation(operations[0].ID, "conflict"); err != nil {
+		t.Fatal(err)
+	}
+
+	pending, err := db.FindPendingOperations()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"QmEdit1", "QmBase", "QmEdit3"}
+	if len(pending) != len(expected) {
+		t.Fatalf("expected %d operations pending, got %+v", len(expected), pending)
+	}
+
+	for index, operation := range pending {
+		if operation.BaseIpfs != expected[index] {
+			t.Errorf("operation %d should be based on %s, got %s", index, expected[index], operation.BaseIpfs)
+		}
+	}
+}
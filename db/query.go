@@ -0,0 +1,183 @@
+package db
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"gitlab.com/tramonto-one/go-tramonto/entities"
+)
+
+// sqliteTimeFormat is the format of the timestamps written by CURRENT_TIMESTAMP
+const sqliteTimeFormat = "2006-01-02 15:04:05"
+
+// Columns of the sort keys
+var sortColumns = map[string]string{
+	entities.SortByUpdatedAt: "updated_at",
+	entities.SortByCreatedAt: "created_at",
+	entities.SortByName:      "name",
+}
+
+// queryCursor is the position of the last test of a page
+type queryCursor struct {
+	Sort       string `json:"s"`
+	IsFavorite bool   `json:"f"`
+	Value      string `json:"v"`
+	RowID      int64  `json:"r"`
+}
+
+// encodeCursor encodes the cursor as an opaque string
+func encodeCursor(cursor queryCursor) (string, error) {
+	cursorJSON, err := json.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(cursorJSON), nil
+}
+
+// decodeCursor decodes an opaque cursor
+func decodeCursor(encoded string) (queryCursor, error) {
+	var cursor queryCursor
+
+	cursorJSON, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return cursor, errors.New("Invalid cursor")
+	}
+
+	if err := json.Unmarshal(cursorJSON, &cursor); err != nil {
+		return cursor, errors.New("Invalid cursor")
+	}
+
+	return cursor, nil
+}
+
+type dbTestRow struct {
+	dbTest
+	RowID     int64  `db:"row_id"`
+	SortValue string `db:"sort_value"`
+}
+
+// FindTestsByQuery finds a page of tests matching the query
+// Tests are paginated by cursor, so pages stay stable while tests are updated
+func (db *OneSQLite) FindTestsByQuery(query entities.TestQuery) (entities.TestPage, error) {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	page := entities.TestPage{Tests: []entities.Test{}}
+
+	if err := query.Validate(); err != nil {
+		return page, err
+	}
+
+	column := sortColumns[query.Sort]
+
+	// Dates are sorted from the newest and names alphabetically
+	direction, comparison := "DESC", "<"
+	if query.Sort == entities.SortByName {
+		direction, comparison = "ASC", ">"
+	}
+
+	sortValue := "CAST(" + column + " AS TEXT)"
+
+	conditions := []string{}
+	args := []interface{}{}
+
+	switch query.Status {
+	case entities.StatusActive:
+		conditions = append(conditions, "is_active = 1")
+	case entities.StatusArchived:
+		conditions = append(conditions, "is_active = 0")
+	}
+
+	switch query.Ownership {
+	case entities.OwnershipOwned:
+		conditions = append(conditions, "is_owner = 1")
+	case entities.OwnershipShared:
+		conditions = append(conditions, "is_owner = 0")
+	}
+
+	if query.Favorite {
+		conditions = append(conditions, "is_favorite = 1")
+	}
+
+	if query.UpdatedFrom != nil {
+		conditions = append(conditions, "CAST(updated_at AS TEXT) >= ?")
+		args = append(args, query.UpdatedFrom.UTC().Format(sqliteTimeFormat))
+	}
+
+	if query.UpdatedTo != nil {
+		conditions = append(conditions, "CAST(updated_at AS TEXT) <= ?")
+		args = append(args, query.UpdatedTo.UTC().Format(sqliteTimeFormat))
+	}
+
+	if text := strings.TrimSpace(query.Text); text != "" {
+		pattern := "%" + strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(text) + "%"
+
+		conditions = append(conditions, `(name LIKE ? ESCAPE '\' OR description LIKE ? ESCAPE '\')`)
+		args = append(args, pattern, pattern)
+	}
+
+	if query.Cursor != "" {
+		cursor, err := decodeCursor(query.Cursor)
+		if err != nil {
+			return page, err
+		}
+
+		if cursor.Sort != query.Sort {
+			return page, errors.New("Invalid cursor: the cursor is of another sort")
+		}
+
+		// Continues after the last test of the previous page
+		conditions = append(conditions, `(is_favorite < ?
+			OR (is_favorite = ? AND (`+sortValue+` `+comparison+` ?
+			OR (`+sortValue+` = ? AND rowid `+comparison+` ?))))`)
+		args = append(args, cursor.IsFavorite, cursor.IsFavorite, cursor.Value, cursor.Value, cursor.RowID)
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	// Reads one test more to know if there is a next page
+	args = append(args, query.Limit+1)
+
+	rows := []dbTestRow{}
+	if err := db.db.Select(&rows, db.db.Rebind(`
+		SELECT *, rowid AS row_id, `+sortValue+` AS sort_value
+		FROM tests
+		`+where+`
+		ORDER BY is_favorite DESC, `+sortValue+` `+direction+`, rowid `+direction+`
+		LIMIT ?`), args...); err != nil {
+		return page, errors.New("Error finding tests: " + err.Error())
+	}
+
+	hasNextPage := len(rows) > query.Limit
+	if hasNextPage {
+		rows = rows[:query.Limit]
+	}
+
+	for _, row := range rows {
+		page.Tests = append(page.Tests, row.toEntity())
+	}
+
+	if hasNextPage {
+		last := rows[len(rows)-1]
+
+		nextCursor, err := encodeCursor(queryCursor{
+			Sort:       query.Sort,
+			IsFavorite: last.IsFavorite,
+			Value:      last.SortValue,
+			RowID:      last.RowID,
+		})
+		if err != nil {
+			return entities.TestPage{Tests: []entities.Test{}}, err
+		}
+
+		page.NextCursor = nextCursor
+	}
+
+	return page, nil
+}
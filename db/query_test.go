@@ -0,0 +1,148 @@
+package db
+
+import (
+	"fmt"
+	"testing"
+
+	"gitlab.com/tramonto-one/go-tramonto/entities"
+)
+
+// findAllPages follows the cursors of the query and returns the names of each page
+func findAllPages(t *testing.T, db *OneSQLite, query entities.TestQuery) [][]string {
+	t.Helper()
+
+	pages := [][]string{}
+
+	for {
+		page, err := db.FindTestsByQuery(query)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		pages = append(pages, testNames(page.Tests))
+
+		if page.NextCursor == "" {
+			return pages
+		}
+
+		query.Cursor = page.NextCursor
+	}
+}
+
+func TestFindTestsByQueryPaginatesTies(t *testing.T) {
+	db, closeDB := openTestDB(t)
+	defer closeDB()
+
+	for _, name := range []string{"TR0001", "TR0002", "TR0003", "TR0004", "TR0005"} {
+		insertSharedTest(t, db, name, "Qm"+name)
+	}
+
+	// Every test was updated in the same second, the insertion order breaks the tie
+	if _, err := db.db.Exec("UPDATE tests SET updated_at = '2026-01-01 10:00:00'"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.SetFavorite("QmTR0002", true); err != nil {
+		t.Fatal(err)
+	}
+
+	pages := findAllPages(t, db, entities.TestQuery{
+		Status:    entities.StatusActive,
+		Ownership: entities.OwnershipAll,
+		Sort:      entities.SortByUpdatedAt,
+		Limit:     2,
+	})
+
+	expected := [][]string{{"TR0002", "TR0005"}, {"TR0004", "TR0003"}, {"TR0001"}}
+	if len(pages) != len(expected) {
+		t.Fatalf("expected pages %v, got %v", expected, pages)
+	}
+
+	for index := range expected {
+		if len(pages[index]) != len(expected[index]) {
+			t.Fatalf("expected pages %v, got %v", expected, pages)
+		}
+
+		for position := range expected[index] {
+			if pages[index][position] != expected[index][position] {
+				t.Fatalf("expected pages %v, got %v", expected, pages)
+			}
+		}
+	}
+}
+
+func TestFindTestsByQueryKeepsPagesWhileUpdating(t *testing.T) {
+	db, closeDB := openTestDB(t)
+	defer closeDB()
+
+	for index, name := range []string{"TR0001", "TR0002", "TR0003", "TR0004"} {
+		insertSharedTest(t, db, name, "Qm"+name)
+
+		if _, err := db.db.Exec("UPDATE tests SET updated_at = ? WHERE name = ?", fmt.Sprintf("2026-01-01 10:00:%02d", index), name); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	query := entities.TestQuery{
+		Status:    entities.StatusActive,
+		Ownership: entities.OwnershipAll,
+		Sort:      entities.SortByUpdatedAt,
+		Limit:     2,
+	}
+
+	page, err := db.FindTestsByQuery(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectNames(t, page.Tests, "TR0004", "TR0003")
+
+	// A test of the first page is updated before the next page is read
+	if _, err := db.db.Exec("UPDATE tests SET updated_at = '2026-01-01 11:00:00' WHERE name = 'TR0003'"); err != nil {
+		t.Fatal(err)
+	}
+
+	query.Cursor = page.NextCursor
+	if page, err = db.FindTestsByQuery(query); err != nil {
+		t.Fatal(err)
+	}
+
+	expectNames(t, page.Tests, "TR0002", "TR0001")
+
+	if page.NextCursor != "" {
+		t.Errorf("the last page should have no cursor, got %s", page.NextCursor)
+	}
+}
+
+func TestFindTestsByQueryRejectsCursors(t *testing.T) {
+	db, closeDB := openTestDB(t)
+	defer closeDB()
+
+	insertSharedTest(t, db, "TR0001", "QmA")
+	insertSharedTest(t, db, "TR0002", "QmB")
+
+	query := entities.TestQuery{
+		Status:    entities.StatusActive,
+		Ownership: entities.OwnershipAll,
+		Sort:      entities.SortByName,
+		Limit:     1,
+		Cursor:    "not a cursor",
+	}
+
+	if _, err := db.FindTestsByQuery(query); err == nil {
+		t.Error("expected an error for an invalid cursor")
+	}
+
+	query.Cursor = ""
+	page, err := db.FindTestsByQuery(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	query.Cursor = page.NextCursor
+	query.Sort = entities.SortByCreatedAt
+
+	if _, err := db.FindTestsByQuery(query); err == nil {
+		t.Error("expected an error for a cursor of another sort")
+	}
+}
@@ -0,0 +1,103 @@
+package db
+
+import (
+	"errors"
+	"time"
+
+	"gitlab.com/tramonto-one/go-tramonto/entities"
+)
+
+// ErrTestNameExists is returned when a test is created with the name of another test
+var ErrTestNameExists = errors.New("A test with the same name already exists")
+
+// TestRepository represents the storage of the tests
+// The tests are returned without the cached artifacts and members of their
+// metadata, which are kept with the other records in the SQLite database
+type TestRepository interface {
+	// InsertTest inserts a new test
+	InsertTest(test entities.Test) error
+
+	// InsertTestWithUniqueName inserts a new test when no other test has its name
+	// The name is checked and the test inserted at once
+	// Returns ErrTestNameExists when the name is already used
+	InsertTestWithUniqueName(test entities.Test) error
+
+	// FindTests finds all active tests
+	// Favorites come first, then the recently updated
+	FindTests() ([]entities.Test, error)
+
+	// FindTestsByQuery finds a page of tests matching the query
+	FindTestsByQuery(query entities.TestQuery) (entities.TestPage, error)
+
+	// FindFavoriteTests finds the active tests marked as favorite
+	FindFavoriteTests() ([]entities.Test, error)
+
+	// FindArchivedTests finds all archived tests
+	FindArchivedTests() ([]entities.Test, error)
+
+	// FindTestsBySuite finds the active tests of a suite sorted by name
+	FindTestsBySuite(suiteID string) ([]entities.Test, error)
+
+	// FindTestByIpns returns a single active test by its IPNS hash
+	// Returns sql.ErrNoRows when the test does not exist
+	FindTestByIpns(ipnsHash string) (entities.Test, error)
+
+	// FindAnyTestByIpns returns a single test by its IPNS hash, active or archived
+	// Returns sql.ErrNoRows when the test does not exist
+	FindAnyTestByIpns(ipnsHash string) (entities.Test, error)
+
+	// ExistsTestWithName returns if there is a test with the given name
+	ExistsTestWithName(name string) (bool, error)
+
+	// NextTestName generates the next sequential name with the given prefix
+	// Names already used by tests are skipped
+	NextTestName(prefix string) (string, error)
+
+	// UpdateIPFSHash updates the IPFS hash of a test
+	UpdateIPFSHash(ipns, newIpfs string) error
+
+	// UpdateIPFSHashFrom updates the IPFS hash of a test still pointing to the expected one
+	// Returns if the test was updated, it is not when another revision was stored meanwhile
+	UpdateIPFSHashFrom(ipns, expectedIpfs, newIpfs string) (bool, error)
+
+	// SaveSharedTest saves the IPNS hash of a test recently shared
+	SaveSharedTest(ipfsHash, ipnsHash string) error
+
+	// SetOwner marks if the node owns the IPNS key of a test
+	SetOwner(ipnsHash string, isOwner bool) error
+
+	// SetFavorite marks or unmarks an active test as favorite
+	SetFavorite(ipnsHash string, isFavorite bool) error
+
+	// SetTestSuite moves an active test to a suite, an empty ID removes it from its suite
+	SetTestSuite(ipnsHash, suiteID string) error
+
+	// MarkSynced registers when the IPNS of a test was last published or resolved
+	MarkSynced(ipnsHash string, syncedAt time.Time) error
+
+	// MarkSyncError registers why the last publish or resolve of a test failed
+	MarkSyncError(ipnsHash, reason string) error
+
+	// ArchiveTest archives an active test, hiding it from the listings
+	ArchiveTest(ipnsHash string) error
+
+	// RestoreTest restores an archived test
+	RestoreTest(ipnsHash string) error
+
+	// DeleteTest permanently deletes a test, active or archived
+	DeleteTest(ipnsHash string) error
+}
+
+// TestRecordsDeleter is implemented by the test repositories storing the tests
+// in the database of their records, so both are deleted at once
+type TestRecordsDeleter interface {
+	// DeleteTestWithRecords deletes a test with its caches, history and
+	// pending operations in a single transaction
+	DeleteTestWithRecords(ipnsHash, ipfsHash string) error
+}
+
+// OneSQLite stores the tests in the SQLite database
+var (
+	_ TestRepository     = (*OneSQLite)(nil)
+	_ TestRecordsDeleter = (*OneSQLite)(nil)
+)
@@ -0,0 +1,416 @@
+package db
+
+import (
+	"database/sql"
+	"sync"
+	"testing"
+	"time"
+
+	"gitlab.com/tramonto-one/go-tramonto/entities"
+)
+
+// testRepositories runs the test against every repository of tests
+func testRepositories(t *testing.T, test func(t *testing.T, tests TestRepository)) {
+	t.Run("sqlite", func(t *testing.T) {
+		db, closeDB := openTestDB(t)
+		defer closeDB()
+
+		test(t, db)
+	})
+
+	t.Run("memory", func(t *testing.T) {
+		test(t, NewMemoryTestRepository())
+	})
+}
+
+// insertRepositoryTest inserts a shared test with the name to the repository
+func insertRepositoryTest(t *testing.T, tests TestRepository, name, ipns string) {
+	t.Helper()
+
+	metadata, err := entities.NewMetadata(name, "Crashes on "+name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tests.InsertTest(entities.Test{
+		Ipfs:           "QmIpfs" + ipns,
+		Ipns:           ipns,
+		IpnsKeyCreated: true,
+		IsOwner:        true,
+		Secret:         "secret",
+		Metadata:       metadata,
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// testNames returns the names of the tests in order
+func testNames(tests []entities.Test) []string {
+	names := []string{}
+	for _, test := range tests {
+		names = append(names, test.Metadata.Name)
+	}
+
+	return names
+}
+
+// expectNames fails when the names are not the expected ones in order
+func expectNames(t *testing.T, tests []entities.Test, expected ...string) {
+	t.Helper()
+
+	names := testNames(tests)
+	if len(names) != len(expected) {
+		t.Fatalf("expected tests %v, got %v", expected, names)
+	}
+
+	for index := range expected {
+		if names[index] != expected[index] {
+			t.Fatalf("expected tests %v, got %v", expected, names)
+		}
+	}
+}
+
+func TestRepositoryFindTests(t *testing.T) {
+	testRepositories(t, func(t *testing.T, tests TestRepository) {
+		insertRepositoryTest(t, tests, "TR0001", "QmA")
+		insertRepositoryTest(t, tests, "TR0002", "QmB")
+
+		if err := tests.InsertTest(entities.Test{Ipfs: "QmIpfsQmOther", Ipns: "QmA"}); err == nil {
+			t.Error("a test with the same IPNS hash should not be inserted")
+		}
+
+		test, err := tests.FindTestByIpns("QmA")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if test.Metadata.Name != "TR0001" || test.Ipfs != "QmIpfsQmA" || !test.IsOwner || test.Secret != "secret" {
+			t.Errorf("unexpected test %+v", test)
+		}
+
+		if _, err := tests.FindTestByIpns("QmMissing"); err != sql.ErrNoRows {
+			t.Errorf("expected sql.ErrNoRows, got %v", err)
+		}
+
+		if err := tests.SetFavorite("QmA", true); err != nil {
+			t.Fatal(err)
+		}
+
+		all, err := tests.FindTests()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expectNames(t, all, "TR0001", "TR0002")
+
+		favorites, err := tests.FindFavoriteTests()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expectNames(t, favorites, "TR0001")
+
+		// Two IPNS records may point to the same content
+		if err := tests.InsertTest(entities.Test{Ipfs: "QmIpfsQmA", Ipns: "QmC", Metadata: entities.Metadata{Name: "TR0003"}}); err != nil {
+			t.Fatalf("a test with the same IPFS hash should be inserted, got %v", err)
+		}
+
+		if test, err := tests.FindTestByIpns("QmC"); err != nil || test.Metadata.Name != "TR0003" {
+			t.Errorf("unexpected test %+v %v", test, err)
+		}
+	})
+}
+
+func TestRepositoryUpdatesTests(t *testing.T) {
+	testRepositories(t, func(t *testing.T, tests TestRepository) {
+		if err := tests.InsertTest(entities.Test{Ipfs: "QmIpfs", Metadata: entities.Metadata{Name: "TR0001"}}); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := tests.SaveSharedTest("QmIpfs", "QmA"); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := tests.SaveSharedTest("QmMissing", "QmB"); err == nil {
+			t.Error("a missing test should not be shared")
+		}
+
+		if err := tests.SaveSharedTest("QmIpfs", "QmB"); err == nil {
+			t.Error("a test already shared should not be shared again")
+		}
+
+		if err := tests.UpdateIPFSHash("QmA", "QmEdited"); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := tests.SetOwner("QmA", true); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := tests.SetTestSuite("QmA", "suite"); err != nil {
+			t.Fatal(err)
+		}
+
+		syncedAt := time.Now()
+		if err := tests.MarkSynced("QmA", syncedAt); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := tests.MarkSyncError("QmA", "offline"); err != nil {
+			t.Fatal(err)
+		}
+
+		test, err := tests.FindTestByIpns("QmA")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if test.Ipfs != "QmEdited" || !test.IpnsKeyCreated || !test.IsOwner || test.SuiteID != "suite" {
+			t.Errorf("unexpected test %+v", test)
+		}
+
+		if test.LastSyncedAt == nil || test.LastSyncedAt.Unix() != syncedAt.Unix() || test.LastSyncError != "offline" {
+			t.Errorf("unexpected sync state %v %q", test.LastSyncedAt, test.LastSyncError)
+		}
+
+		suiteTests, err := tests.FindTestsBySuite("suite")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expectNames(t, suiteTests, "TR0001")
+
+		if err := tests.UpdateIPFSHash("QmMissing", "QmEdited"); err == nil {
+			t.Error("a missing test should not be updated")
+		}
+
+		// The hash is only replaced while the test points to the expected one
+		if updated, err := tests.UpdateIPFSHashFrom("QmA", "QmIpfs", "QmResolved"); err != nil || updated {
+			t.Errorf("a test moved to another revision should not be updated, got %v %v", updated, err)
+		}
+
+		if updated, err := tests.UpdateIPFSHashFrom("QmA", "QmEdited", "QmResolved"); err != nil || !updated {
+			t.Errorf("a test pointing to the expected revision should be updated, got %v %v", updated, err)
+		}
+
+		if test, err := tests.FindTestByIpns("QmA"); err != nil || test.Ipfs != "QmResolved" {
+			t.Errorf("unexpected test %+v %v", test, err)
+		}
+	})
+}
+
+func TestRepositoryArchivesTests(t *testing.T) {
+	testRepositories(t, func(t *testing.T, tests TestRepository) {
+		insertRepositoryTest(t, tests, "TR0001", "QmA")
+		insertRepositoryTest(t, tests, "TR0002", "QmB")
+
+		if err := tests.ArchiveTest("QmA"); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := tests.ArchiveTest("QmA"); err == nil {
+			t.Error("an archived test should not be archived again")
+		}
+
+		if _, err := tests.FindTestByIpns("QmA"); err != sql.ErrNoRows {
+			t.Errorf("an archived test should not be found, got %v", err)
+		}
+
+		if _, err := tests.FindAnyTestByIpns("QmA"); err != nil {
+			t.Errorf("an archived test should be found by FindAnyTestByIpns, got %v", err)
+		}
+
+		active, err := tests.FindTests()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expectNames(t, active, "TR0002")
+
+		archived, err := tests.FindArchivedTests()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expectNames(t, archived, "TR0001")
+
+		if err := tests.RestoreTest("QmA"); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := tests.FindTestByIpns("QmA"); err != nil {
+			t.Errorf("a restored test should be found, got %v", err)
+		}
+
+		if err := tests.ArchiveTest("QmB"); err != nil {
+			t.Fatal(err)
+		}
+
+		// Archived tests are deleted too
+		if err := tests.DeleteTest("QmB"); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := tests.DeleteTest("QmB"); err == nil {
+			t.Error("a deleted test should not be deleted again")
+		}
+
+		if _, err := tests.FindAnyTestByIpns("QmB"); err != sql.ErrNoRows {
+			t.Errorf("a deleted test should not be found, got %v", err)
+		}
+	})
+}
+
+func TestRepositoryNamesTests(t *testing.T) {
+	testRepositories(t, func(t *testing.T, tests TestRepository) {
+		insertRepositoryTest(t, tests, "TR0002", "QmA")
+
+		first, err := tests.NextTestName("TR")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// The name of the existing test is skipped
+		second, err := tests.NextTestName("TR")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if first != "TR0001" || second != "TR0003" {
+			t.Errorf("expected TR0001 and TR0003, got %s and %s", first, second)
+		}
+
+		if name, err := tests.NextTestName("BUG"); err != nil || name != "BUG0001" {
+			t.Errorf("each prefix should have its own sequence, got %s %v", name, err)
+		}
+
+		if err := tests.ArchiveTest("QmA"); err != nil {
+			t.Fatal(err)
+		}
+
+		// Archived tests keep their names
+		if exists, err := tests.ExistsTestWithName("TR0002"); err != nil || !exists {
+			t.Errorf("expected TR0002 to exist, got %v %v", exists, err)
+		}
+
+		if exists, err := tests.ExistsTestWithName("TR0004"); err != nil || exists {
+			t.Errorf("expected TR0004 not to exist, got %v %v", exists, err)
+		}
+	})
+}
+
+func TestRepositoryInsertsTestsWithUniqueNames(t *testing.T) {
+	testRepositories(t, func(t *testing.T, tests TestRepository) {
+		insertRepositoryTest(t, tests, "TR0001", "QmA")
+
+		err := tests.InsertTestWithUniqueName(entities.Test{Ipfs: "QmIpfsQmB", Metadata: entities.Metadata{Name: "TR0001"}})
+		if err != ErrTestNameExists {
+			t.Errorf("expected ErrTestNameExists, got %v", err)
+		}
+
+		// Only one of the concurrent creates gets the name
+		var wg sync.WaitGroup
+		results := make(chan error, 10)
+		for index := 0; index < 10; index++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				results <- tests.InsertTestWithUniqueName(entities.Test{Ipfs: "QmIpfsQmC", Metadata: entities.Metadata{Name: "Login"}})
+			}()
+		}
+
+		wg.Wait()
+		close(results)
+
+		inserted := 0
+		for err := range results {
+			if err == nil {
+				inserted++
+			} else if err != ErrTestNameExists {
+				t.Fatal(err)
+			}
+		}
+
+		if inserted != 1 {
+			t.Errorf("expected a single test named Login, got %d", inserted)
+		}
+	})
+}
+
+func TestRepositoryQueriesTests(t *testing.T) {
+	testRepositories(t, func(t *testing.T, tests TestRepository) {
+		insertRepositoryTest(t, tests, "TR0003", "QmC")
+		insertRepositoryTest(t, tests, "TR0001", "QmA")
+		insertRepositoryTest(t, tests, "TR0004", "QmD")
+		insertRepositoryTest(t, tests, "TR0002", "QmB")
+		insertRepositoryTest(t, tests, "TR0005", "QmE")
+
+		if err := tests.SetFavorite("QmD", true); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := tests.SetOwner("QmE", false); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := tests.ArchiveTest("QmC"); err != nil {
+			t.Fatal(err)
+		}
+
+		// Favorites come first, then the names in order
+		query := entities.TestQuery{Status: entities.StatusActive, Ownership: entities.OwnershipAll, Sort: entities.SortByName, Limit: 2}
+
+		page, err := tests.FindTestsByQuery(query)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expectNames(t, page.Tests, "TR0004", "TR0001")
+
+		if page.NextCursor == "" {
+			t.Fatal("expected a cursor to the next page")
+		}
+
+		query.Cursor = page.NextCursor
+		if page, err = tests.FindTestsByQuery(query); err != nil {
+			t.Fatal(err)
+		}
+
+		expectNames(t, page.Tests, "TR0002", "TR0005")
+
+		if page.NextCursor != "" {
+			t.Errorf("the last page should have no cursor, got %s", page.NextCursor)
+		}
+
+		// A cursor of another sort is rejected
+		query.Sort = entities.SortByUpdatedAt
+		if _, err := tests.FindTestsByQuery(query); err == nil {
+			t.Error("expected an error for a cursor of another sort")
+		}
+
+		filtered, err := tests.FindTestsByQuery(entities.TestQuery{
+			Status:    entities.StatusAll,
+			Ownership: entities.OwnershipOwned,
+			Sort:      entities.SortByName,
+			Text:      "crashes on tr000",
+			Limit:     10,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expectNames(t, filtered.Tests, "TR0004", "TR0001", "TR0002", "TR0003")
+
+		archived, err := tests.FindTestsByQuery(entities.TestQuery{
+			Status:    entities.StatusArchived,
+			Ownership: entities.OwnershipAll,
+			Sort:      entities.SortByName,
+			Limit:     10,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expectNames(t, archived.Tests, "TR0003")
+	})
+}
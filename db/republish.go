@@ -0,0 +1,72 @@
+package db
+
+import (
+	"errors"
+	"time"
+
+	"gitlab.com/tramonto-one/go-tramonto/entities"
+)
+
+type dbIPNSRecord struct {
+	IpnsHash    string    `db:"ipns_hash"`
+	KeyName     string    `db:"key_name"`
+	IpfsHash    string    `db:"ipfs_hash"`
+	PublishedAt time.Time `db:"published_at"`
+	LastError   string    `db:"last_error"`
+}
+
+// SaveIPNSRecord registers the revision an owned test published to its IPNS
+func (db *OneSQLite) SaveIPNSRecord(ipnsHash, keyName, ipfsHash string) error {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	if _, err := db.db.Exec(`
+		INSERT INTO ipns_records (ipns_hash, key_name, ipfs_hash, published_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (ipns_hash) DO UPDATE
+		SET key_name = excluded.key_name,
+			ipfs_hash = excluded.ipfs_hash,
+			published_at = excluded.published_at,
+			last_error = ''`,
+		ipnsHash, keyName, ipfsHash, time.Now().UTC()); err != nil {
+		return errors.New("Error saving IPNS record: " + err.Error())
+	}
+
+	return nil
+}
+
+// FindIPNSRecords finds the records published by the owned tests
+func (db *OneSQLite) FindIPNSRecords() ([]entities.IPNSRecord, error) {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	records := []dbIPNSRecord{}
+	if err := db.db.Select(&records, "SELECT * FROM ipns_records ORDER BY published_at"); err != nil {
+		return []entities.IPNSRecord{}, errors.New("Error finding IPNS records: " + err.Error())
+	}
+
+	result := []entities.IPNSRecord{}
+	for _, record := range records {
+		result = append(result, entities.IPNSRecord{
+			IpnsHash:    record.IpnsHash,
+			KeyName:     record.KeyName,
+			IpfsHash:    record.IpfsHash,
+			PublishedAt: record.PublishedAt,
+			LastError:   record.LastError,
+		})
+	}
+
+	return result, nil
+}
+
+// RecordRepublishError registers a failed republish of a record
+func (db *OneSQLite) RecordRepublishError(ipnsHash, reason string) error {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	if _, err := db.db.Exec("UPDATE ipns_records SET last_error = $1 WHERE ipns_hash = $2", reason, ipnsHash); err != nil {
+		return errors.New("Error updating IPNS record: " + err.Error())
+	}
+
+	return nil
+}
@@ -0,0 +1,188 @@
+package db
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"gitlab.com/tramonto-one/go-tramonto/entities"
+)
+
+// indexTest replaces the indexed name, description, artifacts and members of a test
+// Artifact contents and comments are indexed apart, as they are not in the metadata
+func indexTest(tx *sqlx.Tx, ipnsHash string, metadata entities.Metadata) error {
+	if _, err := tx.Exec(`
+		DELETE FROM search_index
+		WHERE test_ipns = $1 AND kind IN ($2, $3, $4)`,
+		ipnsHash, entities.SearchKindTest, entities.SearchKindArtifact, entities.SearchKindMember); err != nil {
+		return errors.New("Error cleaning search index: " + err.Error())
+	}
+
+	insert := `
+		INSERT INTO search_index (test_ipns, kind, target, title, content)
+		VALUES ($1, $2, $3, $4, $5)`
+
+	if _, err := tx.Exec(insert, ipnsHash, entities.SearchKindTest, metadata.ID, metadata.Name, metadata.Description); err != nil {
+		return errors.New("Error indexing test: " + err.Error())
+	}
+
+	for _, artifact := range metadata.Artifacts {
+		if _, err := tx.Exec(insert, ipnsHash, entities.SearchKindArtifact, artifact.Hash, artifact.Name, artifact.Description); err != nil {
+			return errors.New("Error indexing artifact: " + err.Error())
+		}
+	}
+
+	for _, member := range metadata.Members {
+		if _, err := tx.Exec(insert, ipnsHash, entities.SearchKindMember, member.Email, member.Name, member.Email); err != nil {
+			return errors.New("Error indexing member: " + err.Error())
+		}
+	}
+
+	return nil
+}
+
+// indexDocument replaces a single document of the search index
+// The document is recorded as indexed
+func (db *OneSQLite) indexDocument(ipnsHash, kind, target, title, content string) error {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	tx, err := db.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		DELETE FROM search_index
+		WHERE test_ipns = $1 AND kind = $2 AND target = $3`, ipnsHash, kind, target); err != nil {
+		return errors.New("Error cleaning search index: " + err.Error())
+	}
+
+	if _, err := tx.Exec(`
+		INSERT INTO search_index (test_ipns, kind, target, title, content)
+		VALUES ($1, $2, $3, $4, $5)`, ipnsHash, kind, target, title, content); err != nil {
+		return errors.New("Error indexing document: " + err.Error())
+	}
+
+	if err := markIndexed(tx, ipnsHash, kind, target); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// markIndexed records a content of a test as indexed
+func markIndexed(tx *sqlx.Tx, ipnsHash, kind, target string) error {
+	if _, err := tx.Exec(`
+		INSERT OR IGNORE INTO indexed_contents (test_ipns, kind, target)
+		VALUES ($1, $2, $3)`, ipnsHash, kind, target); err != nil {
+		return errors.New("Error recording indexed content: " + err.Error())
+	}
+
+	return nil
+}
+
+// MarkContentIndexed records a content of a test as indexed without indexing it
+// Used for the artifacts that are not text files
+func (db *OneSQLite) MarkContentIndexed(ipnsHash, kind, target string) error {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	tx, err := db.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := markIndexed(tx, ipnsHash, kind, target); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// FindIndexedContents finds the targets of a kind already indexed for a test
+func (db *OneSQLite) FindIndexedContents(ipnsHash, kind string) (map[string]bool, error) {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	targets := []string{}
+	if err := db.db.Select(&targets, `
+		SELECT target
+		FROM indexed_contents
+		WHERE test_ipns = $1 AND kind = $2`, ipnsHash, kind); err != nil {
+		return nil, errors.New("Error finding indexed contents: " + err.Error())
+	}
+
+	indexed := map[string]bool{}
+	for _, target := range targets {
+		indexed[target] = true
+	}
+
+	return indexed, nil
+}
+
+// IndexArtifactContent indexes the text content of an artifact
+func (db *OneSQLite) IndexArtifactContent(ipnsHash, artifactHash, name, content string) error {
+	return db.indexDocument(ipnsHash, entities.SearchKindArtifactContent, artifactHash, name, content)
+}
+
+// IndexComment indexes the body of a comment
+func (db *OneSQLite) IndexComment(ipnsHash string, comment entities.Comment) error {
+	return db.indexDocument(ipnsHash, entities.SearchKindComment, comment.ID, comment.Author, comment.Body)
+}
+
+// ftsQuery converts the user query to a FTS5 query
+// Every word is quoted, so the query never has a syntax error, and matched as a prefix
+func ftsQuery(query string) string {
+	terms := []string{}
+
+	for _, word := range strings.Fields(query) {
+		terms = append(terms, `"`+strings.ReplaceAll(word, `"`, `""`)+`"*`)
+	}
+
+	return strings.Join(terms, " ")
+}
+
+// Search finds the indexed documents of the given tests matching the query
+// Results are ranked by relevance, the names of the tests are not filled
+func (db *OneSQLite) Search(query string, ipnsHashes []string, limit int) ([]entities.SearchResult, error) {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	results := []entities.SearchResult{}
+
+	match := ftsQuery(query)
+	if match == "" || len(ipnsHashes) == 0 {
+		return results, nil
+	}
+
+	searchQuery, args, err := sqlx.In(`
+		SELECT test_ipns, kind, target, title,
+			snippet(search_index, -1, '[', ']', '...', 12), bm25(search_index)
+		FROM search_index
+		WHERE search_index MATCH ? AND test_ipns IN (?)
+		ORDER BY bm25(search_index)
+		LIMIT ?`, match, ipnsHashes, limit)
+	if err != nil {
+		return results, err
+	}
+
+	rows, err := db.db.Queryx(searchQuery, args...)
+	if err != nil {
+		return results, errors.New("Error searching: " + err.Error())
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		result := entities.SearchResult{}
+		if err := rows.Scan(&result.TestIpns, &result.Kind, &result.Target, &result.Title, &result.Snippet, &result.Rank); err != nil {
+			return results, errors.New("Error reading search result: " + err.Error())
+		}
+
+		results = append(results, result)
+	}
+
+	return results, rows.Err()
+}
@@ -0,0 +1,72 @@
+package db
+
+import (
+	"testing"
+
+	oneCrypto "gitlab.com/tramonto-one/go-tramonto/crypto"
+	"gitlab.com/tramonto-one/go-tramonto/entities"
+)
+
+func TestIndexedContents(t *testing.T) {
+	db, closeDB := openTestDB(t)
+	defer closeDB()
+
+	insertSharedTest(t, db, "TR0001", "QmA")
+
+	privateKey, publicKey, err := oneCrypto.GenerateSigningKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	comment, err := entities.NewComment("", "", "ana@example.com", "Crashes on the login screen", privateKey, publicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.IndexComment("QmA", comment); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.MarkContentIndexed("QmA", entities.SearchKindArtifactContent, "QmBinary"); err != nil {
+		t.Fatal(err)
+	}
+
+	comments, err := db.FindIndexedContents("QmA", entities.SearchKindComment)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(comments) != 1 || !comments[comment.ID] {
+		t.Errorf("comment should be indexed, got %v", comments)
+	}
+
+	artifacts, err := db.FindIndexedContents("QmA", entities.SearchKindArtifactContent)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(artifacts) != 1 || !artifacts["QmBinary"] {
+		t.Errorf("binary artifact should be recorded as indexed, got %v", artifacts)
+	}
+
+	results, err := db.Search("login", []string{"QmA"}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) != 1 || results[0].Kind != entities.SearchKindComment {
+		t.Errorf("only the comment should be found, got %+v", results)
+	}
+
+	if results, err := db.Search("login", []string{"QmB"}, 10); err != nil || len(results) != 0 {
+		t.Errorf("only the given tests should be searched, got %+v %v", results, err)
+	}
+
+	if err := db.DeleteTestRecords("QmA", "QmIpfsQmA"); err != nil {
+		t.Fatal(err)
+	}
+
+	if comments, err := db.FindIndexedContents("QmA", entities.SearchKindComment); err != nil || len(comments) != 0 {
+		t.Errorf("a deleted test should have no indexed contents, got %v %v", comments, err)
+	}
+}
@@ -0,0 +1,47 @@
+package db
+
+import "errors"
+
+// FindSettings finds the stored value of each setting
+func (db *OneSQLite) FindSettings() (map[string]string, error) {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	rows := []struct {
+		Key   string `db:"key"`
+		Value string `db:"value"`
+	}{}
+
+	if err := db.db.Select(&rows, "SELECT key, value FROM settings"); err != nil {
+		return nil, errors.New("Error finding settings: " + err.Error())
+	}
+
+	values := map[string]string{}
+	for _, row := range rows {
+		values[row.Key] = row.Value
+	}
+
+	return values, nil
+}
+
+// SaveSettings stores the values of the settings in one transaction
+func (db *OneSQLite) SaveSettings(values map[string]string) error {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	tx, err := db.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for key, value := range values {
+		if _, err := tx.Exec(`
+			INSERT INTO settings (key, value) VALUES ($1, $2)
+			ON CONFLICT (key) DO UPDATE SET value = excluded.value, updated_at = CURRENT_TIMESTAMP`, key, value); err != nil {
+			return errors.New("Error saving setting: " + err.Error())
+		}
+	}
+
+	return tx.Commit()
+}
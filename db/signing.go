@@ -0,0 +1,43 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+)
+
+// FindSigningKey returns the private and public signing keys of the device
+// Returns sql.ErrNoRows when the keys were not generated yet
+func (db *OneSQLite) FindSigningKey() (string, string, error) {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	keys := struct {
+		PrivateKey string `db:"private_key"`
+		PublicKey  string `db:"public_key"`
+	}{}
+
+	if err := db.db.Get(&keys, "SELECT private_key, public_key FROM signing_keys WHERE id = 1"); err != nil {
+		if err == sql.ErrNoRows {
+			return "", "", err
+		}
+
+		return "", "", errors.New("Error finding signing key: " + err.Error())
+	}
+
+	return keys.PrivateKey, keys.PublicKey, nil
+}
+
+// SaveSigningKey saves the signing keys of the device
+// Keys already saved are kept, as they may have signed contents
+func (db *OneSQLite) SaveSigningKey(privateKey, publicKey string) error {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	if _, err := db.db.Exec(`
+		INSERT OR IGNORE INTO signing_keys (id, private_key, public_key)
+		VALUES (1, $1, $2)`, privateKey, publicKey); err != nil {
+		return errors.New("Error saving signing key: " + err.Error())
+	}
+
+	return nil
+}
@@ -0,0 +1,214 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"gitlab.com/tramonto-one/go-tramonto/entities"
+)
+
+type dbProject struct {
+	ID          string    `db:"id"`
+	Name        string    `db:"name"`
+	Description string    `db:"description"`
+	CreatedAt   time.Time `db:"created_at"`
+}
+
+type dbSuite struct {
+	ID          string         `db:"id"`
+	ProjectID   sql.NullString `db:"project_id"`
+	Name        string         `db:"name"`
+	Description string         `db:"description"`
+	Secret      sql.NullString `db:"secret"`
+	IpfsHash    sql.NullString `db:"ipfs_hash"`
+	IpnsHash    sql.NullString `db:"ipns_hash"`
+	IsOwner     bool           `db:"is_owner"`
+	CreatedAt   time.Time      `db:"created_at"`
+	UpdatedAt   time.Time      `db:"updated_at"`
+}
+
+// toEntity parses the stored suite to the entity
+func (s dbSuite) toEntity() entities.Suite {
+	return entities.Suite{
+		ID:          s.ID,
+		ProjectID:   s.ProjectID.String,
+		Name:        s.Name,
+		Description: s.Description,
+		CreatedAt:   s.CreatedAt,
+		Tests:       []entities.SuiteTest{},
+		Ipfs:        s.IpfsHash.String,
+		Ipns:        s.IpnsHash.String,
+		Secret:      s.Secret.String,
+		IsOwner:     s.IsOwner,
+	}
+}
+
+// nullString converts empty strings to NULL
+func nullString(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: value != ""}
+}
+
+// InsertProject inserts a new project to the database
+func (db *OneSQLite) InsertProject(project entities.Project) error {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	if _, err := db.db.Exec(`
+		INSERT INTO projects (id, name, description, created_at)
+		VALUES ($1, $2, $3, $4)`,
+		project.ID, project.Name, project.Description, project.CreatedAt); err != nil {
+		return errors.New("Error inserting project: " + err.Error())
+	}
+
+	return nil
+}
+
+// FindProjects finds all the projects
+func (db *OneSQLite) FindProjects() ([]entities.Project, error) {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	projects := []dbProject{}
+
+	if err := db.db.Select(&projects, "SELECT * FROM projects ORDER BY name"); err != nil {
+		return []entities.Project{}, errors.New("Error finding projects: " + err.Error())
+	}
+
+	result := []entities.Project{}
+	for _, project := range projects {
+		result = append(result, entities.Project{
+			ID:          project.ID,
+			Name:        project.Name,
+			Description: project.Description,
+			CreatedAt:   project.CreatedAt,
+		})
+	}
+
+	return result, nil
+}
+
+// FindProjectByID returns a single project by its ID
+// Returns sql.ErrNoRows when the project does not exist
+func (db *OneSQLite) FindProjectByID(id string) (entities.Project, error) {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	project := dbProject{}
+
+	if err := db.db.Get(&project, "SELECT * FROM projects WHERE id = $1", id); err != nil {
+		return entities.Project{}, err
+	}
+
+	return entities.Project{
+		ID:          project.ID,
+		Name:        project.Name,
+		Description: project.Description,
+		CreatedAt:   project.CreatedAt,
+	}, nil
+}
+
+// SaveSuite inserts or updates a suite in the database
+func (db *OneSQLite) SaveSuite(suite entities.Suite) error {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	if _, err := db.db.Exec(`
+		INSERT INTO suites (id, project_id, name, description, secret, ipfs_hash, ipns_hash, is_owner, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (id) DO UPDATE
+		SET name = excluded.name, description = excluded.description, secret = excluded.secret,
+			ipfs_hash = excluded.ipfs_hash, ipns_hash = excluded.ipns_hash, updated_at = CURRENT_TIMESTAMP`,
+		suite.ID, nullString(suite.ProjectID), suite.Name, suite.Description, nullString(suite.Secret),
+		nullString(suite.Ipfs), nullString(suite.Ipns), suite.IsOwner, suite.CreatedAt); err != nil {
+		return errors.New("Error saving suite: " + err.Error())
+	}
+
+	return nil
+}
+
+// FindSuites finds the suites of a project
+// An empty projectID finds the suites without project
+func (db *OneSQLite) FindSuites(projectID string) ([]entities.Suite, error) {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	suites := []dbSuite{}
+
+	if err := db.db.Select(&suites, `
+		SELECT *
+		FROM suites
+		WHERE IFNULL(project_id, '') = $1
+		ORDER BY name`, projectID); err != nil {
+		return []entities.Suite{}, errors.New("Error finding suites: " + err.Error())
+	}
+
+	result := []entities.Suite{}
+	for _, suite := range suites {
+		result = append(result, suite.toEntity())
+	}
+
+	return result, nil
+}
+
+// FindSuiteByID returns a single suite by its ID
+func (db *OneSQLite) FindSuiteByID(id string) (entities.Suite, error) {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	suite := dbSuite{}
+
+	if err := db.db.Get(&suite, "SELECT * FROM suites WHERE id = $1", id); err != nil {
+		return entities.Suite{}, err
+	}
+
+	return suite.toEntity(), nil
+}
+
+// SetTestSuite moves a test to a suite
+func (db *OneSQLite) SetTestSuite(ipnsHash, suiteID string) error {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	sqlResult, err := db.db.Exec(`
+		UPDATE tests
+		SET suite_id = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE ipns_hash = $2 AND is_active = 1`, nullString(suiteID), ipnsHash)
+	if err != nil {
+		return errors.New("Error updating test suite: " + err.Error())
+	}
+
+	rowsAffected, err := sqlResult.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return errors.New("No test updated with IPNS hash equals to " + ipnsHash)
+	}
+
+	return nil
+}
+
+// FindTestsBySuite finds the active tests of a suite
+func (db *OneSQLite) FindTestsBySuite(suiteID string) ([]entities.Test, error) {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	tests := []dbTest{}
+
+	if err := db.db.Select(&tests, `
+		SELECT *
+		FROM tests
+		WHERE suite_id = $1 AND is_active = 1
+		ORDER BY name`, suiteID); err != nil {
+		return []entities.Test{}, errors.New("Error finding tests: " + err.Error())
+	}
+
+	result := []entities.Test{}
+	for _, test := range tests {
+		result = append(result, test.toEntity())
+	}
+
+	return result, nil
+}
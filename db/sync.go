@@ -0,0 +1,66 @@
+package db
+
+import (
+	"errors"
+	"time"
+
+	"gitlab.com/tramonto-one/go-tramonto/entities"
+)
+
+type dbSyncEntry struct {
+	ID        int64     `db:"id"`
+	TestIpns  string    `db:"test_ipns"`
+	IpfsHash  string    `db:"ipfs_hash"`
+	Source    string    `db:"source"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// RecordSync registers the revision a publish or resolve of a test found
+// A history entry is added when the IPNS points to another revision
+func (db *OneSQLite) RecordSync(ipnsHash, ipfsHash, source string) error {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	// Resolving the same revision again is not a new entry
+	if _, err := db.db.Exec(`
+		INSERT INTO ipns_history (test_ipns, ipfs_hash, source)
+		SELECT $1, $2, $3
+		WHERE $2 IS NOT (
+			SELECT ipfs_hash
+			FROM ipns_history
+			WHERE test_ipns = $1
+			ORDER BY id DESC
+			LIMIT 1
+		)`, ipnsHash, ipfsHash, source); err != nil {
+		return errors.New("Error recording IPNS history: " + err.Error())
+	}
+
+	return nil
+}
+
+// FindSyncHistory finds the revisions the IPNS of a test pointed to
+// The most recent entries come first
+func (db *OneSQLite) FindSyncHistory(ipnsHash string) ([]entities.SyncEntry, error) {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	entries := []dbSyncEntry{}
+	if err := db.db.Select(&entries, `
+		SELECT *
+		FROM ipns_history
+		WHERE test_ipns = $1
+		ORDER BY id DESC`, ipnsHash); err != nil {
+		return []entities.SyncEntry{}, errors.New("Error finding IPNS history: " + err.Error())
+	}
+
+	result := []entities.SyncEntry{}
+	for _, entry := range entries {
+		result = append(result, entities.SyncEntry{
+			IpfsHash:  entry.IpfsHash,
+			Source:    entry.Source,
+			CreatedAt: entry.CreatedAt,
+		})
+	}
+
+	return result, nil
+}
@@ -0,0 +1,103 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"gitlab.com/tramonto-one/go-tramonto/entities"
+)
+
+type dbTemplate struct {
+	ID        string         `db:"id"`
+	Name      string         `db:"name"`
+	Document  string         `db:"document"`
+	IpfsHash  sql.NullString `db:"ipfs_hash"`
+	Secret    sql.NullString `db:"secret"`
+	CreatedAt time.Time      `db:"created_at"`
+	UpdatedAt time.Time      `db:"updated_at"`
+}
+
+// toEntity parses the stored template to the entity
+func (t dbTemplate) toEntity() (entities.Template, error) {
+	template, err := entities.TemplateFromJSON([]byte(t.Document))
+	if err != nil {
+		return entities.Template{}, err
+	}
+
+	template.Ipfs = t.IpfsHash.String
+	template.Secret = t.Secret.String
+
+	return template, nil
+}
+
+// SaveTemplate inserts or replaces a template in the database
+func (db *OneSQLite) SaveTemplate(template entities.Template) error {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	// The IPFS hash and secret are stored in their own columns
+	document := template
+	document.Ipfs = ""
+	document.Secret = ""
+
+	documentJSON, err := document.ToJSON()
+	if err != nil {
+		return errors.New("Error parsing template: " + err.Error())
+	}
+
+	if _, err := db.db.Exec(`
+		INSERT INTO templates (id, name, document, ipfs_hash, secret)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (id) DO UPDATE
+		SET name = excluded.name, document = excluded.document, ipfs_hash = excluded.ipfs_hash,
+			secret = excluded.secret, updated_at = CURRENT_TIMESTAMP`,
+		template.ID, template.Name, string(documentJSON), template.Ipfs, template.Secret); err != nil {
+		return errors.New("Error saving template: " + err.Error())
+	}
+
+	return nil
+}
+
+// FindTemplates finds all the templates
+func (db *OneSQLite) FindTemplates() ([]entities.Template, error) {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	templates := []dbTemplate{}
+
+	if err := db.db.Select(&templates, `
+		SELECT *
+		FROM templates
+		ORDER BY name`); err != nil {
+		return []entities.Template{}, errors.New("Error finding templates: " + err.Error())
+	}
+
+	// Parses to entity
+	result := []entities.Template{}
+
+	for _, template := range templates {
+		entity, err := template.toEntity()
+		if err != nil {
+			return []entities.Template{}, err
+		}
+
+		result = append(result, entity)
+	}
+
+	return result, nil
+}
+
+// FindTemplateByID returns a single template by its ID
+func (db *OneSQLite) FindTemplateByID(id string) (entities.Template, error) {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	template := dbTemplate{}
+
+	if err := db.db.Get(&template, "SELECT * FROM templates WHERE id = $1", id); err != nil {
+		return entities.Template{}, err
+	}
+
+	return template.toEntity()
+}
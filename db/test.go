@@ -1,26 +1,59 @@
 package db
 
 import (
+	"database/sql"
 	"errors"
+	"fmt"
 	"time"
 
+	"github.com/jmoiron/sqlx"
+
 	// Importing to use sqlite3
 	_ "github.com/mattn/go-sqlite3"
 	"gitlab.com/tramonto-one/go-tramonto/entities"
 )
 
 type dbTest struct {
-	Name           string    `db:"name"`
-	Description    string    `db:"description"`
-	Secret         string    `db:"secret"`
-	IpfsHash       string    `db:"ipfs_hash"`
-	IpnsHash       string    `db:"ipns_hash"`
-	IsKeyGenerated bool      `db:"is_key_generated"`
-	IsOwner        bool      `db:"is_owner"`
-	IsFavorite     bool      `db:"is_favorite"`
-	CreatedAt      time.Time `db:"created_at"`
-	UpdatedAt      time.Time `db:"updated_at"`
-	IsActive       bool      `db:"is_active"`
+	Name           string         `db:"name"`
+	Description    string         `db:"description"`
+	Secret         string         `db:"secret"`
+	IpfsHash       string         `db:"ipfs_hash"`
+	IpnsHash       string         `db:"ipns_hash"`
+	IsKeyGenerated bool           `db:"is_key_generated"`
+	IsOwner        bool           `db:"is_owner"`
+	IsFavorite     bool           `db:"is_favorite"`
+	CreatedAt      time.Time      `db:"created_at"`
+	UpdatedAt      time.Time      `db:"updated_at"`
+	IsActive       bool           `db:"is_active"`
+	TestID         sql.NullString `db:"test_id"`
+	SuiteID        sql.NullString `db:"suite_id"`
+	LastSyncedAt   sql.NullTime   `db:"last_synced_at"`
+	LastSyncError  string         `db:"last_sync_error"`
+}
+
+// toEntity parses the stored test to the entity
+func (t dbTest) toEntity() entities.Test {
+	var lastSyncedAt *time.Time
+	if t.LastSyncedAt.Valid {
+		lastSyncedAt = &t.LastSyncedAt.Time
+	}
+
+	return entities.Test{
+		Ipfs:           t.IpfsHash,
+		Ipns:           t.IpnsHash,
+		IpnsKeyCreated: t.IsKeyGenerated,
+		IsOwner:        t.IsOwner,
+		IsFavorite:     t.IsFavorite,
+		Secret:         t.Secret,
+		SuiteID:        t.SuiteID.String,
+		LastSyncedAt:   lastSyncedAt,
+		LastSyncError:  t.LastSyncError,
+		Metadata: entities.Metadata{
+			ID:          t.TestID.String,
+			Name:        t.Name,
+			Description: t.Description,
+		},
+	}
 }
 
 // InsertTest inserts a new test to the database
@@ -28,25 +61,42 @@ func (db *OneSQLite) InsertTest(test entities.Test) error {
 	db.mux.Lock()
 	defer db.mux.Unlock()
 
-	// Starts transaction
-	tx := db.db.MustBegin()
+	// Inserts data, the IPNS hash is unique
+	if _, err := db.db.Exec(`
+		INSERT INTO tests (test_id, name, description, secret, ipfs_hash, ipns_hash, is_key_generated, is_owner)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8);`,
+		test.Metadata.ID, test.Metadata.Name, test.Metadata.Description, test.Secret, test.Ipfs, test.Ipns, test.IpnsKeyCreated, test.IsOwner); err != nil {
+		return errors.New("Error inserting test: " + err.Error())
+	}
+
+	return nil
+}
 
-	// Inserts data
-	sqlResult := tx.MustExec(`
-		INSERT INTO tests (name, description, secret, ipfs_hash, ipns_hash, is_key_generated, is_owner)
-		VALUES ($1, $2, $3, $4, $5, $6, $7);`,
-		test.Metadata.Name, test.Metadata.Description, test.Secret, test.Ipfs, test.Ipns, test.IpnsKeyCreated, test.IsOwner)
+// InsertTestWithUniqueName inserts a new test when no other test has its name
+// The name is checked and the test inserted in a single statement
+// Returns ErrTestNameExists when the name is already used
+func (db *OneSQLite) InsertTestWithUniqueName(test entities.Test) error {
+	db.mux.Lock()
+	defer db.mux.Unlock()
 
-	_, err := sqlResult.RowsAffected()
+	result, err := db.db.Exec(`
+		INSERT INTO tests (test_id, name, description, secret, ipfs_hash, ipns_hash, is_key_generated, is_owner)
+		SELECT $1, $2, $3, $4, $5, $6, $7, $8
+		WHERE NOT EXISTS (SELECT 1 FROM tests WHERE name = $2);`,
+		test.Metadata.ID, test.Metadata.Name, test.Metadata.Description, test.Secret, test.Ipfs, test.Ipns, test.IpnsKeyCreated, test.IsOwner)
 	if err != nil {
-		panic(err)
+		return errors.New("Error inserting test: " + err.Error())
 	}
 
-	// Commits
-	if err := tx.Commit(); err != nil {
+	inserted, err := result.RowsAffected()
+	if err != nil {
 		return errors.New("Error inserting test: " + err.Error())
 	}
 
+	if inserted == 0 {
+		return ErrTestNameExists
+	}
+
 	return nil
 }
 
@@ -63,7 +113,7 @@ func (db *OneSQLite) FindTests() ([]entities.Test, error) {
 		SELECT *
 		FROM tests
 		WHERE is_active = 1
-		ORDER BY updated_at DESC`); err != nil {
+		ORDER BY is_favorite DESC, updated_at DESC`); err != nil {
 		return []entities.Test{}, errors.New("Error finding tests: " + err.Error())
 	}
 
@@ -71,17 +121,7 @@ func (db *OneSQLite) FindTests() ([]entities.Test, error) {
 	result := []entities.Test{}
 
 	for _, test := range tests {
-		result = append(result, entities.Test{
-			Ipfs:           test.IpfsHash,
-			Ipns:           test.IpnsHash,
-			IpnsKeyCreated: test.IsKeyGenerated,
-			IsOwner:        test.IsOwner,
-			Secret:         test.Secret,
-			Metadata: entities.Metadata{
-				Name:        test.Name,
-				Description: test.Description,
-			},
-		})
+		result = append(result, test.toEntity())
 	}
 
 	return result, nil
@@ -92,15 +132,16 @@ func (db *OneSQLite) SaveSharedTest(ipfsHash, ipnsHash string) error {
 	db.mux.Lock()
 	defer db.mux.Unlock()
 
-	// Starts the transaction
-	tx := db.db.MustBegin()
-
-	// Updates the shared test
-	sqlResult := db.db.MustExec(`
+	// Updates the shared test, the IPNS hash is unique
+	// Tests already shared may have the same IPFS hash, so they are not updated
+	sqlResult, err := db.db.Exec(`
 		UPDATE tests
 		SET ipns_hash = $1, is_key_generated = 1, updated_at = CURRENT_TIMESTAMP
-		WHERE ipfs_hash = $2
+		WHERE ipfs_hash = $2 AND (ipns_hash IS NULL OR ipns_hash = '')
 	`, ipnsHash, ipfsHash)
+	if err != nil {
+		return errors.New("Error saving shared test: " + err.Error())
+	}
 
 	rowsAffected, err := sqlResult.RowsAffected()
 	if err != nil {
@@ -111,10 +152,6 @@ func (db *OneSQLite) SaveSharedTest(ipfsHash, ipnsHash string) error {
 		return errors.New("No test found with the given IPFS hash")
 	}
 
-	if err = tx.Commit(); err != nil {
-		return err
-	}
-
 	return nil
 }
 
@@ -130,17 +167,7 @@ func (db *OneSQLite) FindTestByIpns(ipnsHash string) (entities.Test, error) {
 		return entities.Test{}, err
 	}
 
-	return entities.Test{
-		Ipfs:           test.IpfsHash,
-		Ipns:           test.IpnsHash,
-		IpnsKeyCreated: test.IsKeyGenerated,
-		IsOwner:        test.IsOwner,
-		Secret:         test.Secret,
-		Metadata: entities.Metadata{
-			Name:        test.Name,
-			Description: test.Description,
-		},
-	}, nil
+	return test.toEntity(), nil
 }
 
 // UpdateIPFSHash Updates the IPFS hash of a test
@@ -149,13 +176,20 @@ func (db *OneSQLite) UpdateIPFSHash(ipns, newIpfs string) error {
 	defer db.mux.Unlock()
 
 	// Starts transaction
-	tx := db.db.MustBegin()
+	tx, err := db.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
 
 	// Executes the update of the data
-	dbResponse := db.db.MustExec(`
+	dbResponse, err := tx.Exec(`
 		UPDATE tests
 		SET ipfs_hash = $1, updated_at = CURRENT_TIMESTAMP
 		WHERE ipns_hash = $2 AND is_active = 1`, newIpfs, ipns)
+	if err != nil {
+		return errors.New("Error updating test: " + err.Error())
+	}
 
 	// Verifies affected rows
 	affectedRows, err := dbResponse.RowsAffected()
@@ -168,9 +202,411 @@ func (db *OneSQLite) UpdateIPFSHash(ipns, newIpfs string) error {
 	}
 
 	// Commits if it is everything ok
-	if err = tx.Commit(); err != nil {
+	return tx.Commit()
+}
+
+// UpdateIPFSHashFrom updates the IPFS hash of a test still pointing to the expected one
+// Returns if the test was updated
+func (db *OneSQLite) UpdateIPFSHashFrom(ipns, expectedIpfs, newIpfs string) (bool, error) {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	dbResponse, err := db.db.Exec(`
+		UPDATE tests
+		SET ipfs_hash = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE ipns_hash = $2 AND ipfs_hash = $3 AND is_active = 1`, newIpfs, ipns, expectedIpfs)
+	if err != nil {
+		return false, errors.New("Error updating test: " + err.Error())
+	}
+
+	affectedRows, err := dbResponse.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affectedRows > 0, nil
+}
+
+// ExistsTestWithName returns if there is a test with the given name
+func (db *OneSQLite) ExistsTestWithName(name string) (bool, error) {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	var count int
+	if err := db.db.Get(&count, "SELECT COUNT(*) FROM tests WHERE name = $1", name); err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// NextTestName generates the next sequential name with the given prefix
+// Names already used by tests in the device are skipped
+func (db *OneSQLite) NextTestName(prefix string) (string, error) {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	tx, err := db.db.Beginx()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	for {
+		// Increments the sequence of the prefix
+		if _, err := tx.Exec(`
+			INSERT INTO name_sequences (prefix, value) VALUES ($1, 1)
+			ON CONFLICT (prefix) DO UPDATE SET value = value + 1`, prefix); err != nil {
+			return "", errors.New("Error incrementing sequence: " + err.Error())
+		}
+
+		var value int
+		if err := tx.Get(&value, "SELECT value FROM name_sequences WHERE prefix = $1", prefix); err != nil {
+			return "", errors.New("Error reading sequence: " + err.Error())
+		}
+
+		name := fmt.Sprintf("%s%04d", prefix, value)
+
+		// Verifies the name is not in use
+		var count int
+		if err := tx.Get(&count, "SELECT COUNT(*) FROM tests WHERE name = $1", name); err != nil {
+			return "", err
+		}
+
+		if count > 0 {
+			continue
+		}
+
+		if err := tx.Commit(); err != nil {
+			return "", err
+		}
+
+		return name, nil
+	}
+}
+
+// SetFavorite marks or unmarks a test as favorite
+func (db *OneSQLite) SetFavorite(ipnsHash string, isFavorite bool) error {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	sqlResult, err := db.db.Exec(`
+		UPDATE tests
+		SET is_favorite = $1
+		WHERE ipns_hash = $2 AND is_active = 1`, isFavorite, ipnsHash)
+	if err != nil {
+		return errors.New("Error updating favorite: " + err.Error())
+	}
+
+	rowsAffected, err := sqlResult.RowsAffected()
+	if err != nil {
 		return err
 	}
 
+	if rowsAffected == 0 {
+		return errors.New("No test updated with IPNS hash equals to " + ipnsHash)
+	}
+
+	return nil
+}
+
+// SetOwner marks if the node owns the IPNS key of a test
+func (db *OneSQLite) SetOwner(ipnsHash string, isOwner bool) error {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	sqlResult, err := db.db.Exec(`
+		UPDATE tests
+		SET is_owner = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE ipns_hash = $2 AND is_active = 1`, isOwner, ipnsHash)
+	if err != nil {
+		return errors.New("Error updating owner: " + err.Error())
+	}
+
+	rowsAffected, err := sqlResult.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return errors.New("No test updated with IPNS hash equals to " + ipnsHash)
+	}
+
+	return nil
+}
+
+// FindFavoriteTests finds the active tests marked as favorite
+func (db *OneSQLite) FindFavoriteTests() ([]entities.Test, error) {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	tests := []dbTest{}
+
+	if err := db.db.Select(&tests, `
+		SELECT *
+		FROM tests
+		WHERE is_active = 1 AND is_favorite = 1
+		ORDER BY updated_at DESC`); err != nil {
+		return []entities.Test{}, errors.New("Error finding favorite tests: " + err.Error())
+	}
+
+	result := []entities.Test{}
+	for _, test := range tests {
+		result = append(result, test.toEntity())
+	}
+
+	return result, nil
+}
+
+// MarkSynced registers when the IPNS of a test was last published or resolved
+// The last sync error is cleared
+func (db *OneSQLite) MarkSynced(ipnsHash string, syncedAt time.Time) error {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	if _, err := db.db.Exec(`
+		UPDATE tests
+		SET last_synced_at = $1, last_sync_error = ''
+		WHERE ipns_hash = $2`, syncedAt.UTC(), ipnsHash); err != nil {
+		return errors.New("Error updating sync state: " + err.Error())
+	}
+
+	return nil
+}
+
+// MarkSyncError registers why the last publish or resolve of a test failed
+func (db *OneSQLite) MarkSyncError(ipnsHash, reason string) error {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	if _, err := db.db.Exec(`
+		UPDATE tests
+		SET last_sync_error = $1
+		WHERE ipns_hash = $2`, reason, ipnsHash); err != nil {
+		return errors.New("Error updating sync state: " + err.Error())
+	}
+
+	return nil
+}
+
+// setTestActive sets if a test is active or archived
+func (db *OneSQLite) setTestActive(ipnsHash string, isActive bool) error {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	sqlResult, err := db.db.Exec(`
+		UPDATE tests
+		SET is_active = $1
+		WHERE ipns_hash = $2 AND is_active = $3`, isActive, ipnsHash, !isActive)
+	if err != nil {
+		return errors.New("Error updating test: " + err.Error())
+	}
+
+	rowsAffected, err := sqlResult.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return errors.New("No test updated with IPNS hash equals to " + ipnsHash)
+	}
+
+	return nil
+}
+
+// ArchiveTest archives an active test, hiding it from the listings
+func (db *OneSQLite) ArchiveTest(ipnsHash string) error {
+	return db.setTestActive(ipnsHash, false)
+}
+
+// RestoreTest restores an archived test
+func (db *OneSQLite) RestoreTest(ipnsHash string) error {
+	return db.setTestActive(ipnsHash, true)
+}
+
+// FindArchivedTests finds all archived tests
+func (db *OneSQLite) FindArchivedTests() ([]entities.Test, error) {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	tests := []dbTest{}
+
+	if err := db.db.Select(&tests, `
+		SELECT *
+		FROM tests
+		WHERE is_active = 0
+		ORDER BY updated_at DESC`); err != nil {
+		return []entities.Test{}, errors.New("Error finding archived tests: " + err.Error())
+	}
+
+	result := []entities.Test{}
+	for _, test := range tests {
+		result = append(result, test.toEntity())
+	}
+
+	return result, nil
+}
+
+// FindAnyTestByIpns returns a single test by its IPNS hash, active or archived
+func (db *OneSQLite) FindAnyTestByIpns(ipnsHash string) (entities.Test, error) {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	test := dbTest{}
+
+	if err := db.db.Get(&test, "SELECT * FROM tests WHERE ipns_hash = $1", ipnsHash); err != nil {
+		return entities.Test{}, err
+	}
+
+	return test.toEntity(), nil
+}
+
+// FindTestRevisions finds the IPFS hashes of the revisions of a test recorded by the node
+// The IPNS history and the revisions in the outbox, the current revision is in the test
+func (db *OneSQLite) FindTestRevisions(ipnsHash string) ([]string, error) {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	hashes := []string{}
+	if err := db.db.Select(&hashes, `
+		SELECT ipfs_hash FROM ipns_history WHERE test_ipns = $1
+		UNION
+		SELECT base_ipfs FROM outbox WHERE test_ipns = $1
+		UNION
+		SELECT edited_ipfs FROM outbox WHERE test_ipns = $1`, ipnsHash); err != nil {
+		return []string{}, errors.New("Error finding revisions: " + err.Error())
+	}
+
+	return hashes, nil
+}
+
+// DeleteTest permanently deletes a test, active or archived
+func (db *OneSQLite) DeleteTest(ipnsHash string) error {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	sqlResult, err := db.db.Exec("DELETE FROM tests WHERE ipns_hash = $1", ipnsHash)
+	if err != nil {
+		return errors.New("Error deleting test: " + err.Error())
+	}
+
+	rowsAffected, err := sqlResult.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return errors.New("No test deleted with IPNS hash equals to " + ipnsHash)
+	}
+
+	return nil
+}
+
+// DeleteTestRecords deletes the records of a test kept apart from the test:
+// its cached metadata, artifacts and members, search index, pending operations
+// and IPNS history. The IPFS hash is the current revision of the test
+func (db *OneSQLite) DeleteTestRecords(ipnsHash, ipfsHash string) error {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	tx, err := db.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := deleteTestRecords(tx, ipnsHash, ipfsHash); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DeleteTestWithRecords permanently deletes a test, active or archived, with
+// the records kept apart from it in one transaction
+// The IPFS hash is the current revision of the test
+func (db *OneSQLite) DeleteTestWithRecords(ipnsHash, ipfsHash string) error {
+	db.mux.Lock()
+	defer db.mux.Unlock()
+
+	tx, err := db.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// The records are deleted first, their revisions are found from the test
+	if err := deleteTestRecords(tx, ipnsHash, ipfsHash); err != nil {
+		return err
+	}
+
+	sqlResult, err := tx.Exec("DELETE FROM tests WHERE ipns_hash = $1", ipnsHash)
+	if err != nil {
+		return errors.New("Error deleting test: " + err.Error())
+	}
+
+	rowsAffected, err := sqlResult.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return errors.New("No test deleted with IPNS hash equals to " + ipnsHash)
+	}
+
+	return tx.Commit()
+}
+
+// deleteTestRecords deletes the records of a test in the transaction
+func deleteTestRecords(tx *sqlx.Tx, ipnsHash, ipfsHash string) error {
+	// The revisions are found in the history and the outbox before they are deleted,
+	// the documents still used by another test are kept
+	if _, err := tx.Exec(`
+		DELETE FROM metadata_cache
+		WHERE ipfs_hash IN (
+			SELECT ipfs_hash FROM tests WHERE ipns_hash = $1
+			UNION
+			SELECT $2
+			UNION
+			SELECT ipfs_hash FROM ipns_history WHERE test_ipns = $1
+			UNION
+			SELECT base_ipfs FROM outbox WHERE test_ipns = $1
+			UNION
+			SELECT edited_ipfs FROM outbox WHERE test_ipns = $1
+		)
+		AND ipfs_hash NOT IN (
+			SELECT ipfs_hash FROM tests WHERE ipns_hash <> $1 AND ipfs_hash IS NOT NULL
+		)`, ipnsHash, ipfsHash); err != nil {
+		return errors.New("Error deleting cached metadata: " + err.Error())
+	}
+
+	if _, err := tx.Exec("DELETE FROM artifacts WHERE test_ipns = $1", ipnsHash); err != nil {
+		return errors.New("Error deleting artifacts: " + err.Error())
+	}
+
+	if _, err := tx.Exec("DELETE FROM members WHERE test_ipns = $1", ipnsHash); err != nil {
+		return errors.New("Error deleting members: " + err.Error())
+	}
+
+	if _, err := tx.Exec("DELETE FROM search_index WHERE test_ipns = $1", ipnsHash); err != nil {
+		return errors.New("Error deleting search index: " + err.Error())
+	}
+
+	if _, err := tx.Exec("DELETE FROM indexed_contents WHERE test_ipns = $1", ipnsHash); err != nil {
+		return errors.New("Error deleting search index: " + err.Error())
+	}
+
+	if _, err := tx.Exec("DELETE FROM outbox WHERE test_ipns = $1", ipnsHash); err != nil {
+		return errors.New("Error deleting pending operations: " + err.Error())
+	}
+
+	if _, err := tx.Exec("DELETE FROM ipns_history WHERE test_ipns = $1", ipnsHash); err != nil {
+		return errors.New("Error deleting IPNS history: " + err.Error())
+	}
+
+	if _, err := tx.Exec("DELETE FROM ipns_records WHERE ipns_hash = $1", ipnsHash); err != nil {
+		return errors.New("Error deleting IPNS record: " + err.Error())
+	}
+
 	return nil
 }
@@ -0,0 +1,118 @@
+package db
+
+import (
+	"sort"
+	"testing"
+
+	"gitlab.com/tramonto-one/go-tramonto/entities"
+)
+
+func TestFindTestRevisions(t *testing.T) {
+	db, closeDB := openTestDB(t)
+	defer closeDB()
+
+	insertSharedTest(t, db, "TR0001", "QmA")
+	insertSharedTest(t, db, "TR0002", "QmB")
+
+	if err := db.RecordSync("QmA", "QmResolved", entities.SyncSourceResolve); err != nil {
+		t.Fatal(err)
+	}
+
+	enqueueTestOperation(t, db, "QmA", "QmResolved", "QmEdited")
+	enqueueTestOperation(t, db, "QmB", "QmOther", "QmOtherEdited")
+
+	hashes, err := db.FindTestRevisions("QmA")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sort.Strings(hashes)
+
+	expected := []string{"QmEdited", "QmResolved"}
+	sort.Strings(expected)
+
+	if len(hashes) != len(expected) {
+		t.Fatalf("expected revisions %v, got %v", expected, hashes)
+	}
+
+	for index := range expected {
+		if hashes[index] != expected[index] {
+			t.Fatalf("expected revisions %v, got %v", expected, hashes)
+		}
+	}
+
+	if err := db.DeleteTestRecords("QmA", "QmIpfsQmA"); err != nil {
+		t.Fatal(err)
+	}
+
+	if hashes, err := db.FindTestRevisions("QmA"); err != nil || len(hashes) != 0 {
+		t.Errorf("a deleted test should have no revisions, got %v %v", hashes, err)
+	}
+}
+
+func TestDeleteTestRecordsDeletesCachedMetadata(t *testing.T) {
+	db, closeDB := openTestDB(t)
+	defer closeDB()
+
+	insertSharedTest(t, db, "TR0001", "QmA")
+	insertSharedTest(t, db, "TR0002", "QmB")
+
+	// The second test shares a revision with the history of the first one
+	for _, hash := range []string{"QmResolved", "QmIpfsQmB"} {
+		if err := db.RecordSync("QmA", hash, entities.SyncSourceResolve); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	enqueueTestOperation(t, db, "QmA", "QmResolved", "QmEdited")
+
+	metadata, err := entities.NewMetadata("TR0001", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, hash := range []string{"QmIpfsQmA", "QmResolved", "QmEdited", "QmIpfsQmB"} {
+		if err := db.CacheMetadata("", hash, "secret", metadata); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := db.DeleteTestRecords("QmA", "QmIpfsQmA"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, hash := range []string{"QmIpfsQmA", "QmResolved", "QmEdited"} {
+		if _, cached, err := db.FindCachedMetadata(hash, "secret"); err != nil || cached {
+			t.Errorf("the revision %s of a deleted test should not be cached, got %v %v", hash, cached, err)
+		}
+	}
+
+	if _, cached, err := db.FindCachedMetadata("QmIpfsQmB", "secret"); err != nil || !cached {
+		t.Errorf("the revision used by another test should be cached, got %v %v", cached, err)
+	}
+}
+
+func TestDeleteTestWithRecords(t *testing.T) {
+	db, closeDB := openTestDB(t)
+	defer closeDB()
+
+	insertSharedTest(t, db, "TR0001", "QmA")
+	enqueueTestOperation(t, db, "QmA", "QmIpfsQmA", "QmEdited")
+
+	// Nothing is deleted when the test does not exist
+	if err := db.DeleteTestWithRecords("QmMissing", "QmIpfsQmA"); err == nil {
+		t.Error("a missing test should not be deleted")
+	}
+
+	if err := db.DeleteTestWithRecords("QmA", "QmIpfsQmA"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := db.FindAnyTestByIpns("QmA"); err == nil {
+		t.Error("the test should be deleted")
+	}
+
+	if pending, err := db.HasPendingOperations("QmA"); err != nil || pending {
+		t.Errorf("the pending operations of the test should be deleted, got %v %v", pending, err)
+	}
+}
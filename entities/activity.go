@@ -0,0 +1,147 @@
+package entities
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Actions registered in the activity log
+const (
+	ActivityCreateTest   = "createTest"
+	ActivityAddMember    = "addMember"
+	ActivitySetMemberKey = "setMemberKey"
+	ActivityAddArtifact  = "addArtifact"
+	ActivityAddComment   = "addComment"
+	ActivitySetApprovers = "setApprovers"
+	ActivityApprove      = "approve"
+	ActivityReject       = "reject"
+	ActivityAddRun       = "addRun"
+	ActivityFinishRun    = "finishRun"
+)
+
+// Activity represents an entry of the activity log of a test
+// Each entry is chained to the previous one by its hash
+type Activity struct {
+	Actor        string    `json:"actor"`
+	Action       string    `json:"action"`
+	Target       string    `json:"target"`
+	CreatedAt    time.Time `json:"createdAt"`
+	PreviousHash string    `json:"previousHash"`
+	Hash         string    `json:"hash"`
+}
+
+// computeHash returns the hash of the entry content chained to the previous hash
+func (a Activity) computeHash() string {
+	content := strings.Join([]string{
+		a.PreviousHash,
+		a.Actor,
+		a.Action,
+		a.Target,
+		a.CreatedAt.UTC().Format(time.RFC3339Nano),
+	}, "\n")
+
+	hash := sha256.Sum256([]byte(content))
+
+	return hex.EncodeToString(hash[:])
+}
+
+// chainActivity links the activity to the end of the log
+func chainActivity(log []Activity, activity Activity) Activity {
+	activity.PreviousHash = ""
+	if len(log) > 0 {
+		activity.PreviousHash = log[len(log)-1].Hash
+	}
+
+	activity.Hash = activity.computeHash()
+
+	return activity
+}
+
+// AppendActivity appends a new entry to the activity log
+func (m *Metadata) AppendActivity(actor, action, target string) Activity {
+	activity := chainActivity(m.Activities, Activity{
+		Actor:     actor,
+		Action:    action,
+		Target:    target,
+		CreatedAt: now(),
+	})
+
+	m.Activities = append(m.Activities, activity)
+
+	return activity
+}
+
+// VerifyActivityLog verifies the hash chain of the activity log
+// Each entry is chained to the one before it and the first entry to none
+// Returns an error describing the first entry that was tampered
+func VerifyActivityLog(log []Activity) error {
+	previousHash := ""
+
+	for index, activity := range log {
+		if activity.PreviousHash != previousHash {
+			return fmt.Errorf("Activity %d is not chained to the previous entry", index)
+		}
+
+		if activity.Hash != activity.computeHash() {
+			return fmt.Errorf("Activity %d was tampered, its hash does not match the content", index)
+		}
+
+		previousHash = activity.Hash
+	}
+
+	return nil
+}
+
+// VerifyActivityLogContinuity verifies that the log of a newer revision
+// starts with every entry of an older revision, detecting truncated or rewritten logs
+func VerifyActivityLogContinuity(previous, current Metadata) error {
+	if err := VerifyActivityLog(current.Activities); err != nil {
+		return err
+	}
+
+	if len(current.Activities) < len(previous.Activities) {
+		return fmt.Errorf("Activity log was truncated from %d to %d entries", len(previous.Activities), len(current.Activities))
+	}
+
+	for index, activity := range previous.Activities {
+		if current.Activities[index].Hash != activity.Hash {
+			return fmt.Errorf("Activity %d was rewritten since the previous revision", index)
+		}
+	}
+
+	return nil
+}
+
+// contentKey identifies the content of an entry, whatever entry it is chained to
+func (a Activity) contentKey() string {
+	a.PreviousHash = ""
+
+	return a.computeHash()
+}
+
+// mergeActivities merges the activity logs of two concurrent edits
+// The remote log is kept as published and the local entries made after the
+// base are chained again at its end, unless the remote log has them already
+func mergeActivities(base, local, remote []Activity) []Activity {
+	merged := append([]Activity{}, remote...)
+
+	if len(local) <= len(base) {
+		return merged
+	}
+
+	published := map[string]bool{}
+	for _, activity := range remote {
+		published[activity.contentKey()] = true
+	}
+
+	for _, activity := range local[len(base):] {
+		if !published[activity.contentKey()] {
+			merged = append(merged, chainActivity(merged, activity))
+		}
+	}
+
+	return merged
+}
@@ -0,0 +1,125 @@
+package entities
+
+import "testing"
+
+func TestActivityLogVerification(t *testing.T) {
+	metadata, err := NewMetadata("TR0001", "Desc")
+	if err != nil {
+		t.Error(err)
+	}
+
+	metadata.AppendActivity("QmNode", ActivityCreateTest, "TR0001")
+	metadata.AppendActivity("QmNode", ActivityAddMember, "john@tramonto.one")
+
+	if err := VerifyActivityLog(metadata.Activities); err != nil {
+		t.Error(err)
+	}
+
+	// Tampering an entry breaks the chain
+	tampered := append([]Activity{}, metadata.Activities...)
+	tampered[0].Target = "TR0002"
+
+	if err := VerifyActivityLog(tampered); err == nil {
+		t.Error("tampered log should not be valid")
+	}
+
+	// The hashes are not keyed, so a forged entry can have a valid hash of its own
+	newForged := func(previousHash string) Activity {
+		forged := Activity{Actor: "QmForger", Action: ActivityAddMember, Target: "eve@tramonto.one", CreatedAt: now(), PreviousHash: previousHash}
+		forged.Hash = forged.computeHash()
+
+		return forged
+	}
+
+	log := metadata.Activities
+	inserted := map[string][]Activity{
+		"unchained in the middle":     {log[0], newForged(""), log[1]},
+		"chained to an earlier entry": {log[0], log[1], newForged(log[0].Hash)},
+		"chained before the next one": {log[0], newForged(log[0].Hash), log[1]},
+		"unchained at the end":        {log[0], log[1], newForged("")},
+	}
+
+	for name, forgedLog := range inserted {
+		if err := VerifyActivityLog(forgedLog); err == nil {
+			t.Errorf("log with a forged entry %s should not be valid", name)
+		}
+	}
+}
+
+func TestActivityLogContinuity(t *testing.T) {
+	previous, err := NewMetadata("TR0001", "Desc")
+	if err != nil {
+		t.Error(err)
+	}
+
+	previous.AppendActivity("QmNode", ActivityCreateTest, "TR0001")
+	previous.AppendActivity("QmNode", ActivityAddArtifact, "QmArtifact")
+
+	current := previous
+	current.Activities = append([]Activity{}, previous.Activities...)
+	current.AppendActivity("QmNode", ActivityAddMember, "john@tramonto.one")
+
+	if err := VerifyActivityLogContinuity(previous, current); err != nil {
+		t.Error(err)
+	}
+
+	// Dropping the last entries keeps a valid chain but loses history
+	truncated := current
+	truncated.Activities = current.Activities[:1]
+
+	if err := VerifyActivityLogContinuity(previous, truncated); err == nil {
+		t.Error("truncated log should not be valid")
+	}
+
+	// Rewriting an entry keeps a valid chain from it but loses the original
+	rewritten := previous
+	rewritten.Activities = append([]Activity{}, previous.Activities[:1]...)
+	rewritten.AppendActivity("QmNode", ActivityAddArtifact, "QmOther")
+
+	if err := VerifyActivityLogContinuity(previous, rewritten); err == nil {
+		t.Error("rewritten log should not be valid")
+	}
+}
+
+func TestMergeMetadataKeepsLocalActivities(t *testing.T) {
+	base, err := NewMetadata("TR0001", "Desc")
+	if err != nil {
+		t.Error(err)
+	}
+
+	base.AppendActivity("QmNode", ActivityCreateTest, "TR0001")
+
+	local := base
+	local.Activities = append([]Activity{}, base.Activities...)
+	local.AppendActivity("QmLocal", ActivityAddArtifact, "QmLocalArtifact")
+
+	remote := base
+	remote.Activities = append([]Activity{}, base.Activities...)
+	remote.AppendActivity("QmRemote", ActivityAddArtifact, "QmRemoteArtifact")
+
+	merged := MergeMetadata(base, local, remote)
+
+	if len(merged.Activities) != 3 {
+		t.Error("merged activities are wrong", merged.Activities)
+	}
+
+	// The published revision is kept and the local entries are chained after it
+	if err := VerifyActivityLogContinuity(remote, merged); err != nil {
+		t.Error(err)
+	}
+
+	if merged.Activities[2].Target != "QmLocalArtifact" || merged.Activities[2].PreviousHash != merged.Activities[1].Hash {
+		t.Error("the local activity should be chained after the remote ones", merged.Activities)
+	}
+
+	// Merging again does not repeat the local entries
+	if again := MergeMetadata(base, local, merged); len(again.Activities) != 3 {
+		t.Error("local activities should not be repeated", again.Activities)
+	}
+
+	merged.AppendActivity("QmLocal", ActivityAddMember, "john@tramonto.one")
+
+	if err := VerifyActivityLog(merged.Activities); err != nil {
+		t.Error(err)
+	}
+}
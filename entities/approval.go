@@ -0,0 +1,276 @@
+package entities
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	oneCrypto "gitlab.com/tramonto-one/go-tramonto/crypto"
+)
+
+// Decisions of an approval
+const (
+	ApprovalApproved = "approved"
+	ApprovalRejected = "rejected"
+)
+
+// Approval represents the decision of a member over a revision of the test
+type Approval struct {
+	Member       string    `json:"member"`
+	Decision     string    `json:"decision"`
+	Note         string    `json:"note"`
+	CreatedAt    time.Time `json:"createdAt"`
+	RevisionHash string    `json:"revisionHash"`
+	PublicKey    string    `json:"publicKey"`
+	Signature    string    `json:"signature"`
+}
+
+// ApprovalState represents the approval of a test by its required approvers
+type ApprovalState struct {
+	Approved bool     `json:"approved"`
+	Pending  []string `json:"pending"`
+	Rejected []string `json:"rejected"`
+}
+
+// NewApproval creates a new approval of the revision, signed with the private key
+func NewApproval(member, decision, note, revisionHash, privateKey, publicKey string) (Approval, error) {
+	if decision != ApprovalApproved && decision != ApprovalRejected {
+		return Approval{}, errors.New("Decision must be " + ApprovalApproved + " or " + ApprovalRejected)
+	}
+
+	approval := Approval{
+		Member:       member,
+		Decision:     decision,
+		Note:         note,
+		CreatedAt:    now(),
+		RevisionHash: revisionHash,
+		PublicKey:    publicKey,
+	}
+
+	signature, err := oneCrypto.Sign(privateKey, approval.signedContent())
+	if err != nil {
+		return Approval{}, errors.New("Error signing approval: " + err.Error())
+	}
+
+	approval.Signature = signature
+
+	return approval, nil
+}
+
+// signedContent returns the content covered by the signature
+func (a Approval) signedContent() []byte {
+	return []byte(strings.Join([]string{
+		strings.ToLower(a.Member),
+		a.Decision,
+		a.Note,
+		a.CreatedAt.UTC().Format(time.RFC3339Nano),
+		a.RevisionHash,
+	}, "\n"))
+}
+
+// Verify returns if the signature of the approval is valid
+func (a Approval) Verify() bool {
+	return oneCrypto.Verify(a.PublicKey, a.signedContent(), a.Signature)
+}
+
+// verifyApproval returns if the approval is signed with the key registered to its member
+func (m Metadata) verifyApproval(approval Approval) bool {
+	member, exists := m.findMember(approval.Member)
+
+	return exists && member.PublicKey != "" && member.PublicKey == approval.PublicKey && approval.Verify()
+}
+
+// revisionHashVersion is the version of the content hashed by RevisionHash
+// The content is an explicit subset of the metadata, so schema upgrades and new
+// fields do not change the hash of an unchanged revision. Changing the content
+// must bump the version
+const revisionHashVersion = 1
+
+// revisionContent is the content of a revision approvals are given over
+type revisionContent struct {
+	Version      int                `json:"version"`
+	ID           string             `json:"id"`
+	Name         string             `json:"name"`
+	Description  string             `json:"description"`
+	TestCases    []revisionTestCase `json:"testCases"`
+	CustomFields map[string]string  `json:"customFields"`
+	Artifacts    []revisionArtifact `json:"artifacts"`
+	Members      []revisionMember   `json:"members"`
+}
+
+type revisionTestCase struct {
+	ID          string `json:"id"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Expected    string `json:"expected"`
+}
+
+type revisionArtifact struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Hash        string `json:"hash"`
+}
+
+type revisionMember struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+// RevisionHash returns the hash of the content approvals are given over
+// The content is the name, description, test cases, custom fields, artifacts and
+// members without their keys. Approvals, comments, runs and the activity log do
+// not change the revision
+func (m Metadata) RevisionHash() (string, error) {
+	content := revisionContent{
+		Version:      revisionHashVersion,
+		ID:           m.ID,
+		Name:         m.Name,
+		Description:  m.Description,
+		TestCases:    []revisionTestCase{},
+		CustomFields: map[string]string{},
+		Artifacts:    []revisionArtifact{},
+		Members:      []revisionMember{},
+	}
+
+	for _, testCase := range m.TestCases {
+		content.TestCases = append(content.TestCases, revisionTestCase{
+			ID:          testCase.ID,
+			Title:       testCase.Title,
+			Description: testCase.Description,
+			Expected:    testCase.Expected,
+		})
+	}
+
+	for key, value := range m.CustomFields {
+		content.CustomFields[key] = value
+	}
+
+	for _, artifact := range m.Artifacts {
+		content.Artifacts = append(content.Artifacts, revisionArtifact{
+			Name:        artifact.Name,
+			Description: artifact.Description,
+			Hash:        artifact.Hash,
+		})
+	}
+
+	for _, member := range m.Members {
+		content.Members = append(content.Members, revisionMember{
+			Name:  member.Name,
+			Email: strings.ToLower(member.Email),
+			Role:  member.Role,
+		})
+	}
+
+	// Maps are encoded with sorted keys
+	contentJSON, err := json.Marshal(content)
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.Sum256(contentJSON)
+
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// SetRequiredApprovers defines the members that must approve the test
+func (m *Metadata) SetRequiredApprovers(emails []string) error {
+	approvers := []string{}
+	for _, email := range emails {
+		member, exists := m.findMember(email)
+		if !exists {
+			return errors.New(email + " is not a member of this test")
+		}
+
+		approvers = append(approvers, strings.ToLower(member.Email))
+	}
+
+	m.RequiredApprovers = approvers
+	m.UpdatedAt = now()
+
+	return nil
+}
+
+// AddApproval adds a signed approval to the test
+// The approval must be over the current revision and signed with the key
+// registered to the member
+func (m *Metadata) AddApproval(approval Approval) error {
+	member, exists := m.findMember(approval.Member)
+	if !exists {
+		return errors.New("Approver is not a member of this test")
+	}
+
+	if member.PublicKey == "" {
+		return errors.New("Approver has no registered key")
+	}
+
+	if approval.PublicKey != member.PublicKey {
+		return errors.New("Approval is not signed with the key of the member")
+	}
+
+	if !approval.Verify() {
+		return errors.New("Approval signature is invalid")
+	}
+
+	revisionHash, err := m.RevisionHash()
+	if err != nil {
+		return err
+	}
+
+	if approval.RevisionHash != revisionHash {
+		return errors.New("Approval is not over the current revision")
+	}
+
+	m.Approvals = append(m.Approvals, approval)
+	m.UpdatedAt = now()
+
+	return nil
+}
+
+// ApprovalState computes the approval of the current revision
+// Each required approver counts with its latest decision over the revision
+// signed with the key registered to the approver
+func (m Metadata) ApprovalState() ApprovalState {
+	state := ApprovalState{
+		Pending:  []string{},
+		Rejected: []string{},
+	}
+
+	revisionHash, err := m.RevisionHash()
+	if err != nil {
+		return state
+	}
+
+	// Latest decision of each member over the current revision
+	decisions := map[string]Approval{}
+	for _, approval := range m.Approvals {
+		if approval.RevisionHash != revisionHash || !m.verifyApproval(approval) {
+			continue
+		}
+
+		member := strings.ToLower(approval.Member)
+		if latest, exists := decisions[member]; exists && latest.CreatedAt.After(approval.CreatedAt) {
+			continue
+		}
+
+		decisions[member] = approval
+	}
+
+	for _, approver := range m.RequiredApprovers {
+		approval, exists := decisions[strings.ToLower(approver)]
+
+		switch {
+		case !exists:
+			state.Pending = append(state.Pending, approver)
+		case approval.Decision == ApprovalRejected:
+			state.Rejected = append(state.Rejected, approver)
+		}
+	}
+
+	state.Approved = len(m.RequiredApprovers) > 0 && len(state.Pending) == 0 && len(state.Rejected) == 0
+
+	return state
+}
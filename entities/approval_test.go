@@ -0,0 +1,269 @@
+package entities
+
+import (
+	"encoding/json"
+	"testing"
+
+	oneCrypto "gitlab.com/tramonto-one/go-tramonto/crypto"
+)
+
+// addKeyedMember adds a member with a new signing key, returns its private key
+func addKeyedMember(t *testing.T, metadata *Metadata, name, email string) string {
+	t.Helper()
+
+	member, _ := NewMember(name, email, "qa")
+	if err := metadata.AddMember(member); err != nil {
+		t.Fatal(err)
+	}
+
+	privateKey, publicKey, err := oneCrypto.GenerateSigningKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := metadata.SetMemberKey(email, publicKey); err != nil {
+		t.Fatal(err)
+	}
+
+	return privateKey
+}
+
+// signApproval signs an approval with the private key over the current revision
+func signApproval(t *testing.T, metadata Metadata, email, decision, privateKey string) Approval {
+	t.Helper()
+
+	member, _ := metadata.findMember(email)
+	revisionHash, _ := metadata.RevisionHash()
+
+	approval, err := NewApproval(email, decision, "", revisionHash, privateKey, member.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return approval
+}
+
+func TestApprovalState(t *testing.T) {
+	metadata, err := NewMetadata("TR0001", "Desc")
+	if err != nil {
+		t.Error(err)
+	}
+
+	johnKey := addKeyedMember(t, &metadata, "John", "john@tramonto.one")
+	maryKey := addKeyedMember(t, &metadata, "Mary", "mary@tramonto.one")
+
+	if err := metadata.SetRequiredApprovers([]string{"john@tramonto.one", "mary@tramonto.one"}); err != nil {
+		t.Error(err)
+	}
+
+	if err := metadata.AddApproval(signApproval(t, metadata, "john@tramonto.one", ApprovalApproved, johnKey)); err != nil {
+		t.Error(err)
+	}
+
+	if state := metadata.ApprovalState(); state.Approved || len(state.Pending) != 1 {
+		t.Error("test should wait for mary", state)
+	}
+
+	if err := metadata.AddApproval(signApproval(t, metadata, "mary@tramonto.one", ApprovalApproved, maryKey)); err != nil {
+		t.Error(err)
+	}
+
+	if state := metadata.ApprovalState(); !state.Approved {
+		t.Error("test should be approved", state)
+	}
+
+	// Changing the content invalidates the approvals
+	metadata.AddArtifact("log", "", "QmArtifact", nil)
+
+	if state := metadata.ApprovalState(); state.Approved || len(state.Pending) != 2 {
+		t.Error("approvals should be over the previous revision", state)
+	}
+}
+
+func TestAddApprovalRejectsForgedSignature(t *testing.T) {
+	metadata, err := NewMetadata("TR0001", "Desc")
+	if err != nil {
+		t.Error(err)
+	}
+
+	johnKey := addKeyedMember(t, &metadata, "John", "john@tramonto.one")
+
+	approval := signApproval(t, metadata, "john@tramonto.one", ApprovalRejected, johnKey)
+	approval.Decision = ApprovalApproved
+
+	if err := metadata.AddApproval(approval); err == nil {
+		t.Error("forged approval should be rejected")
+	}
+}
+
+func TestAddApprovalVerifiesTheMemberKey(t *testing.T) {
+	metadata, err := NewMetadata("TR0001", "Desc")
+	if err != nil {
+		t.Error(err)
+	}
+
+	johnKey := addKeyedMember(t, &metadata, "John", "john@tramonto.one")
+	addKeyedMember(t, &metadata, "Mary", "mary@tramonto.one")
+
+	unkeyed, _ := NewMember("Ana", "ana@tramonto.one", "qa")
+	metadata.AddMember(unkeyed)
+
+	// John cannot sign for Mary, even with a valid signature of his own key
+	john, _ := metadata.findMember("john@tramonto.one")
+	revisionHash, _ := metadata.RevisionHash()
+
+	approval, _ := NewApproval("mary@tramonto.one", ApprovalApproved, "", revisionHash, johnKey, john.PublicKey)
+	if err := metadata.AddApproval(approval); err == nil {
+		t.Error("an approval signed with the key of another member should be rejected")
+	}
+
+	// Any key would be pinned to a member without a registered key
+	privateKey, publicKey, _ := oneCrypto.GenerateSigningKey()
+
+	approval, _ = NewApproval("ana@tramonto.one", ApprovalApproved, "", revisionHash, privateKey, publicKey)
+	if err := metadata.AddApproval(approval); err == nil {
+		t.Error("an approval of a member without a registered key should be rejected")
+	}
+
+	if err := metadata.SetRequiredApprovers([]string{"john@tramonto.one"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := metadata.AddApproval(signApproval(t, metadata, "john@tramonto.one", ApprovalApproved, johnKey)); err != nil {
+		t.Fatal(err)
+	}
+
+	if state := metadata.ApprovalState(); !state.Approved {
+		t.Error("test should be approved", state)
+	}
+
+	// Approvals signed with a replaced key do not count anymore
+	if err := metadata.SetMemberKey("john@tramonto.one", publicKey); err != nil {
+		t.Fatal(err)
+	}
+
+	if state := metadata.ApprovalState(); state.Approved || len(state.Pending) != 1 {
+		t.Error("approvals of a replaced key should not count", state)
+	}
+}
+
+func TestSetMemberKey(t *testing.T) {
+	metadata, err := NewMetadata("TR0001", "Desc")
+	if err != nil {
+		t.Error(err)
+	}
+
+	john, _ := NewMember("John", "john@tramonto.one", "lead")
+	mary, _ := NewMember("Mary", "mary@tramonto.one", "qa")
+	metadata.AddMember(john)
+	metadata.AddMember(mary)
+
+	base := metadata
+	revisionHash, _ := metadata.RevisionHash()
+
+	_, publicKey, _ := oneCrypto.GenerateSigningKey()
+
+	if err := metadata.SetMemberKey("john@tramonto.one", "not a key"); err == nil {
+		t.Error("an invalid key should be rejected")
+	}
+
+	if err := metadata.SetMemberKey("ana@tramonto.one", publicKey); err == nil {
+		t.Error("the key of someone not a member should be rejected")
+	}
+
+	if err := metadata.SetMemberKey("John@tramonto.one", publicKey); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := metadata.SetMemberKey("mary@tramonto.one", publicKey); err == nil {
+		t.Error("a key should be registered to a single member")
+	}
+
+	if member, exists := metadata.MemberWithKey(publicKey); !exists || member.Email != "john@tramonto.one" {
+		t.Errorf("expected john to have the key, got %+v", member)
+	}
+
+	if base.Members[0].PublicKey != "" {
+		t.Error("the base revision should not change")
+	}
+
+	// Registering keys does not invalidate the approvals
+	if newHash, _ := metadata.RevisionHash(); newHash != revisionHash {
+		t.Error("member keys should not change the revision")
+	}
+}
+
+func TestApprovalSurvivesSchemaUpgrade(t *testing.T) {
+	metadata, err := NewMetadata("TR0001", "Desc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	johnKey := addKeyedMember(t, &metadata, "John", "john@tramonto.one")
+
+	if err := metadata.SetRequiredApprovers([]string{"john@tramonto.one"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := metadata.AddApproval(signApproval(t, metadata, "john@tramonto.one", ApprovalApproved, johnKey)); err != nil {
+		t.Fatal(err)
+	}
+
+	// The approval was given by a client writing schema version 8, before the run history
+	metadataJSON, err := json.Marshal(metadata)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var document map[string]interface{}
+	if err := json.Unmarshal(metadataJSON, &document); err != nil {
+		t.Fatal(err)
+	}
+
+	delete(document, "runs")
+	document["schemaVersion"] = 8
+
+	oldJSON, _ := json.Marshal(document)
+
+	upgraded, err := MetadataFromJSON(oldJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if state := upgraded.ApprovalState(); !state.Approved {
+		t.Error("the approval should survive the schema upgrade", state)
+	}
+
+	// Runs are not part of the approved content
+	run := Run{ID: "run", StartedAt: now(), Verdicts: []CaseVerdict{}, Artifacts: []string{}}
+	if err := upgraded.AddRun(run); err != nil {
+		t.Fatal(err)
+	}
+
+	if state := upgraded.ApprovalState(); !state.Approved {
+		t.Error("the approval should survive a new run", state)
+	}
+}
+
+func TestRevisionHashIsStable(t *testing.T) {
+	metadata := Metadata{
+		SchemaVersion: CurrentSchemaVersion,
+		ID:            "id",
+		Name:          "TR0001",
+		Description:   "Desc",
+		TestCases:     []TestCase{{ID: "login", Title: "Login"}},
+		CustomFields:  map[string]string{"platform": "android", "build": "42"},
+		Artifacts:     []Artifact{{Name: "log", Hash: "QmArtifact", Headers: map[string][]string{"Content-Type": {"text/plain"}}}},
+		Members:       []Member{{Name: "John", Email: "John@tramonto.one", Role: "qa", PublicKey: "key"}},
+	}
+
+	revisionHash, err := metadata.RevisionHash()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Changing the hashed content must bump revisionHashVersion
+	if revisionHash != "f32740fe999e19400e94d3673061c7995d63ced86d559c8b71646067ac8e2bd8" {
+		t.Error("the revision hash changed", revisionHash)
+	}
+}
@@ -0,0 +1,13 @@
+package entities
+
+import "time"
+
+// BackupVersion is the version of the backup archive format
+const BackupVersion = 1
+
+// BackupManifest describes the contents of a backup archive
+type BackupManifest struct {
+	Version        int       `json:"version"`
+	CreatedAt      time.Time `json:"createdAt"`
+	IncludesBlocks bool      `json:"includesBlocks"`
+}
@@ -0,0 +1,258 @@
+package entities
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	oneCrypto "gitlab.com/tramonto-one/go-tramonto/crypto"
+)
+
+// Comment represents a comment on a test or on one of its artifacts
+type Comment struct {
+	ID           string    `json:"id"`
+	ParentID     string    `json:"parentId,omitempty"`
+	ArtifactHash string    `json:"artifactHash,omitempty"`
+	Author       string    `json:"author"`
+	Body         string    `json:"body"`
+	CreatedAt    time.Time `json:"createdAt"`
+	PublicKey    string    `json:"publicKey"`
+	Signature    string    `json:"signature"`
+}
+
+// CommentLink links a comment stored in IPFS from the metadata
+type CommentLink struct {
+	ID           string    `json:"id"`
+	ParentID     string    `json:"parentId,omitempty"`
+	ArtifactHash string    `json:"artifactHash,omitempty"`
+	Author       string    `json:"author"`
+	Hash         string    `json:"hash"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+// CommentThread represents a comment and its replies
+type CommentThread struct {
+	Comment
+	Replies []CommentThread `json:"replies"`
+}
+
+// NewComment creates a new comment, signed with the private key of the author
+// An empty artifactHash comments on the test and parentID replies to another comment
+func NewComment(artifactHash, parentID, author, body, privateKey, publicKey string) (Comment, error) {
+	if strings.TrimSpace(body) == "" {
+		return Comment{}, errors.New("Comment body is required")
+	}
+
+	id, err := newID()
+	if err != nil {
+		return Comment{}, err
+	}
+
+	comment := Comment{
+		ID:           id,
+		ParentID:     parentID,
+		ArtifactHash: artifactHash,
+		Author:       author,
+		Body:         body,
+		CreatedAt:    now(),
+		PublicKey:    publicKey,
+	}
+
+	signature, err := oneCrypto.Sign(privateKey, comment.signedContent())
+	if err != nil {
+		return Comment{}, errors.New("Error signing comment: " + err.Error())
+	}
+
+	comment.Signature = signature
+
+	return comment, nil
+}
+
+// signedContent returns the content covered by the signature
+func (c Comment) signedContent() []byte {
+	return []byte(strings.Join([]string{
+		c.ID,
+		c.ParentID,
+		c.ArtifactHash,
+		strings.ToLower(c.Author),
+		c.Body,
+		c.CreatedAt.UTC().Format(time.RFC3339Nano),
+	}, "\n"))
+}
+
+// Verify returns if the signature of the comment is valid
+func (c Comment) Verify() bool {
+	return oneCrypto.Verify(c.PublicKey, c.signedContent(), c.Signature)
+}
+
+// ValidateComment verifies that the comment can be added to the test
+// The author must be a member allowed to comment, the comment signed with
+// the key registered to the author and the commented artifact and parent
+// comment must exist
+func (m *Metadata) ValidateComment(comment Comment) error {
+	author, exists := m.findMember(comment.Author)
+	if !exists {
+		return errors.New("Author is not a member of this test")
+	}
+
+	if !author.CanComment() {
+		return errors.New("Member is not allowed to comment")
+	}
+
+	if author.PublicKey == "" {
+		return errors.New("Author has no registered key")
+	}
+
+	if comment.PublicKey != author.PublicKey {
+		return errors.New("Comment is not signed with the key of the author")
+	}
+
+	if !comment.Verify() {
+		return errors.New("Comment signature is invalid")
+	}
+
+	if comment.ArtifactHash != "" && !m.hasArtifact(comment.ArtifactHash) {
+		return errors.New("Artifact not found in this test")
+	}
+
+	if comment.ParentID != "" {
+		parent, exists := m.findComment(comment.ParentID)
+		if !exists {
+			return errors.New("Parent comment not found")
+		}
+
+		if parent.ArtifactHash != comment.ArtifactHash {
+			return errors.New("Reply must be in the same thread of its parent")
+		}
+	}
+
+	return nil
+}
+
+// VerifyComment returns if a comment read from IPFS is the linked one, signed
+// with the key registered to its author
+func (m *Metadata) VerifyComment(link CommentLink, comment Comment) bool {
+	if comment.ID != link.ID || comment.ParentID != link.ParentID || comment.ArtifactHash != link.ArtifactHash ||
+		!strings.EqualFold(comment.Author, link.Author) || !comment.CreatedAt.Equal(link.CreatedAt) {
+		return false
+	}
+
+	author, exists := m.findMember(comment.Author)
+
+	return exists && author.PublicKey != "" && author.PublicKey == comment.PublicKey && comment.Verify()
+}
+
+// AddComment links a comment stored in IPFS to the test
+func (m *Metadata) AddComment(comment Comment, hash string) error {
+	if err := m.ValidateComment(comment); err != nil {
+		return err
+	}
+
+	m.Comments = append(m.Comments, CommentLink{
+		ID:           comment.ID,
+		ParentID:     comment.ParentID,
+		ArtifactHash: comment.ArtifactHash,
+		Author:       comment.Author,
+		Hash:         hash,
+		CreatedAt:    comment.CreatedAt,
+	})
+	m.UpdatedAt = now()
+
+	return nil
+}
+
+// CommentsOf returns the links of the comments on an artifact
+// An empty artifactHash returns the comments on the test
+func (m *Metadata) CommentsOf(artifactHash string) []CommentLink {
+	links := []CommentLink{}
+	for _, link := range m.Comments {
+		if link.ArtifactHash != artifactHash {
+			continue
+		}
+
+		links = append(links, link)
+	}
+
+	return links
+}
+
+// findMember returns the member with the given email
+func (m *Metadata) findMember(email string) (Member, bool) {
+	for _, member := range m.Members {
+		if strings.EqualFold(member.Email, email) {
+			return member, true
+		}
+	}
+
+	return Member{}, false
+}
+
+// hasArtifact returns if the test has an artifact with the given hash
+func (m *Metadata) hasArtifact(hash string) bool {
+	for _, artifact := range m.Artifacts {
+		if artifact.Hash == hash {
+			return true
+		}
+	}
+
+	return false
+}
+
+// findComment returns the link of the comment with the given ID
+func (m *Metadata) findComment(id string) (CommentLink, bool) {
+	for _, link := range m.Comments {
+		if link.ID == id {
+			return link, true
+		}
+	}
+
+	return CommentLink{}, false
+}
+
+// BuildCommentThreads organizes the comments in threads of replies
+// Threads and replies are sorted by creation date
+func BuildCommentThreads(comments []Comment) []CommentThread {
+	sorted := append([]Comment{}, comments...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].CreatedAt.Before(sorted[j].CreatedAt)
+	})
+
+	children := map[string][]Comment{}
+	exists := map[string]bool{}
+	for _, comment := range sorted {
+		exists[comment.ID] = true
+	}
+
+	roots := []Comment{}
+	for _, comment := range sorted {
+		// Replies to missing comments are shown as threads
+		if comment.ParentID == "" || !exists[comment.ParentID] {
+			roots = append(roots, comment)
+			continue
+		}
+
+		children[comment.ParentID] = append(children[comment.ParentID], comment)
+	}
+
+	var build func(comment Comment) CommentThread
+	build = func(comment Comment) CommentThread {
+		thread := CommentThread{
+			Comment: comment,
+			Replies: []CommentThread{},
+		}
+
+		for _, reply := range children[comment.ID] {
+			thread.Replies = append(thread.Replies, build(reply))
+		}
+
+		return thread
+	}
+
+	threads := []CommentThread{}
+	for _, comment := range roots {
+		threads = append(threads, build(comment))
+	}
+
+	return threads
+}
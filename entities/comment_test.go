@@ -0,0 +1,104 @@
+package entities
+
+import (
+	"testing"
+
+	oneCrypto "gitlab.com/tramonto-one/go-tramonto/crypto"
+)
+
+func TestAddCommentPermissions(t *testing.T) {
+	metadata, err := NewMetadata("TR0001", "Desc")
+	if err != nil {
+		t.Error(err)
+	}
+
+	johnKey := addKeyedMember(t, &metadata, "John", "john@tramonto.one")
+
+	viewer, _ := NewMember("Mary", "mary@tramonto.one", RoleViewer)
+	metadata.AddMember(viewer)
+
+	maryPrivateKey, maryPublicKey, _ := oneCrypto.GenerateSigningKey()
+	metadata.SetMemberKey("mary@tramonto.one", maryPublicKey)
+
+	metadata.AddArtifact("log", "", "QmArtifact", nil)
+
+	john, _ := metadata.findMember("john@tramonto.one")
+
+	comment, _ := NewComment("QmArtifact", "", "john@tramonto.one", "Crashes on start", johnKey, john.PublicKey)
+	if err := metadata.AddComment(comment, "QmComment"); err != nil {
+		t.Error(err)
+	}
+
+	viewerComment, _ := NewComment("", "", "mary@tramonto.one", "Looks good", maryPrivateKey, maryPublicKey)
+	if err := metadata.AddComment(viewerComment, "QmViewer"); err == nil {
+		t.Error("viewer should not be allowed to comment")
+	}
+
+	strangerComment, _ := NewComment("", "", "bob@tramonto.one", "Hi", johnKey, john.PublicKey)
+	if err := metadata.AddComment(strangerComment, "QmStranger"); err == nil {
+		t.Error("non members should not be allowed to comment")
+	}
+
+	wrongThread, _ := NewComment("", comment.ID, "john@tramonto.one", "Reply", johnKey, john.PublicKey)
+	if err := metadata.AddComment(wrongThread, "QmReply"); err == nil {
+		t.Error("reply should be in the thread of its parent")
+	}
+
+	if links := metadata.CommentsOf("QmArtifact"); len(links) != 1 {
+		t.Error("artifact comments are wrong", links)
+	}
+}
+
+func TestAddCommentVerifiesTheAuthor(t *testing.T) {
+	metadata, err := NewMetadata("TR0001", "Desc")
+	if err != nil {
+		t.Error(err)
+	}
+
+	johnKey := addKeyedMember(t, &metadata, "John", "john@tramonto.one")
+	addKeyedMember(t, &metadata, "Mary", "mary@tramonto.one")
+
+	unkeyed, _ := NewMember("Ana", "ana@tramonto.one", "qa")
+	metadata.AddMember(unkeyed)
+
+	john, _ := metadata.findMember("john@tramonto.one")
+
+	// John cannot write as Mary
+	impersonated, _ := NewComment("", "", "mary@tramonto.one", "Approved by me", johnKey, john.PublicKey)
+	if err := metadata.AddComment(impersonated, "QmImpersonated"); err == nil {
+		t.Error("a comment signed with the key of another member should be rejected")
+	}
+
+	privateKey, publicKey, _ := oneCrypto.GenerateSigningKey()
+
+	unregistered, _ := NewComment("", "", "ana@tramonto.one", "Hi", privateKey, publicKey)
+	if err := metadata.AddComment(unregistered, "QmUnregistered"); err == nil {
+		t.Error("a comment of a member without a registered key should be rejected")
+	}
+
+	forged, _ := NewComment("", "", "john@tramonto.one", "Crashes on start", johnKey, john.PublicKey)
+	forged.Body = "Works for me"
+	if err := metadata.AddComment(forged, "QmForged"); err == nil {
+		t.Error("a comment changed after signed should be rejected")
+	}
+}
+
+func TestBuildCommentThreads(t *testing.T) {
+	privateKey, publicKey, _ := oneCrypto.GenerateSigningKey()
+
+	first, _ := NewComment("", "", "john@tramonto.one", "First", privateKey, publicKey)
+	reply, _ := NewComment("", first.ID, "mary@tramonto.one", "Reply", privateKey, publicKey)
+	second, _ := NewComment("", "", "john@tramonto.one", "Second", privateKey, publicKey)
+
+	threads := BuildCommentThreads([]Comment{reply, second, first})
+
+	if len(threads) != 2 {
+		t.Error("threads are wrong", threads)
+	}
+
+	for _, thread := range threads {
+		if thread.ID == first.ID && len(thread.Replies) != 1 {
+			t.Error("replies are wrong", thread.Replies)
+		}
+	}
+}
@@ -0,0 +1,16 @@
+package entities
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// newID generates a new random identifier
+func newID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,52 @@
+package entities
+
+import "time"
+
+// Types of the background jobs
+const (
+	// JobTypeOutbox publishes the operations of the outbox
+	JobTypeOutbox = "outbox"
+
+	// JobTypeSync refreshes the tests changed by other devices
+	JobTypeSync = "sync"
+
+	// JobTypeGC removes the unpinned blocks of the IPFS repo
+	JobTypeGC = "gc"
+
+	// JobTypeIndex indexes the artifact contents and comments of the tests
+	JobTypeIndex = "index"
+)
+
+// Results of the last run of a job
+const (
+	JobResultSuccess = "success"
+	JobResultFailure = "failure"
+
+	// JobResultSkipped means the job had nothing to do, as when the node is offline
+	JobResultSkipped = "skipped"
+)
+
+// Job represents a chore run periodically in background
+type Job struct {
+	Type            string     `json:"type"`
+	IntervalSeconds int        `json:"intervalSeconds"`
+	NextRunAt       time.Time  `json:"nextRunAt"`
+	LastRunAt       *time.Time `json:"lastRunAt"`
+	LastResult      string     `json:"lastResult"`
+	LastError       string     `json:"lastError"`
+}
+
+// Interval returns the time between the runs of the job
+func (j Job) Interval() time.Duration {
+	return time.Duration(j.IntervalSeconds) * time.Second
+}
+
+// IsDue returns if the job should run at the time
+func (j Job) IsDue(now time.Time) bool {
+	return !j.NextRunAt.After(now)
+}
+
+// NextRun returns when the job runs again after finishing at the time
+func (j Job) NextRun(finishedAt time.Time) time.Time {
+	return finishedAt.Add(j.Interval())
+}
@@ -0,0 +1,36 @@
+package entities
+
+import (
+	"testing"
+	"time"
+)
+
+func TestJobIsDue(t *testing.T) {
+	now := time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		nextRunAt time.Time
+		due       bool
+	}{
+		{now.Add(-time.Minute), true},
+		{now, true},
+		{now.Add(time.Second), false},
+	}
+
+	for _, c := range cases {
+		job := Job{Type: JobTypeOutbox, IntervalSeconds: 30, NextRunAt: c.nextRunAt}
+
+		if due := job.IsDue(now); due != c.due {
+			t.Errorf("IsDue with next run at %v = %v, want %v", c.nextRunAt, due, c.due)
+		}
+	}
+}
+
+func TestJobNextRun(t *testing.T) {
+	finishedAt := time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC)
+	job := Job{Type: JobTypeGC, IntervalSeconds: 86400}
+
+	if next := job.NextRun(finishedAt); !next.Equal(finishedAt.Add(24 * time.Hour)) {
+		t.Errorf("NextRun = %v, want a day later", next)
+	}
+}
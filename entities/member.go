@@ -1,17 +1,65 @@
 package entities
 
-import "time"
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	oneCrypto "gitlab.com/tramonto-one/go-tramonto/crypto"
+)
+
+// RoleViewer is the role of members that can only read the test
+const RoleViewer = "viewer"
 
 // Member represents a member of a test
+// PublicKey is the signing key of the member, approvals and comments of the
+// member are verified with it
 type Member struct {
 	Name      string    `json:"name"`
 	Email     string    `json:"email"`
 	Role      string    `json:"role"`
+	PublicKey string    `json:"publicKey,omitempty"`
 	CreatedAt time.Time `json:"createAt"`
 }
 
+// UnmarshalJSON reads a member, its creation date is written as "createAt"
+// and also accepted as "createdAt"
+func (m *Member) UnmarshalJSON(data []byte) error {
+	type member Member
+
+	decoded := struct {
+		member
+		CreatedAt *time.Time `json:"createdAt"`
+	}{}
+
+	// Members are decoded as strictly as the document they are in
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(&decoded); err != nil {
+		return err
+	}
+
+	*m = Member(decoded.member)
+	if m.CreatedAt.IsZero() && decoded.CreatedAt != nil {
+		m.CreatedAt = *decoded.CreatedAt
+	}
+
+	return nil
+}
+
 // NewMember creates a new member
 func NewMember(name, email, role string) (Member, error) {
+	if strings.TrimSpace(name) == "" {
+		return Member{}, errors.New("Member name is required")
+	}
+
+	if !strings.Contains(email, "@") {
+		return Member{}, errors.New("Member email is invalid")
+	}
+
 	return Member{
 		Name:      name,
 		Email:     email,
@@ -19,3 +67,53 @@ func NewMember(name, email, role string) (Member, error) {
 		CreatedAt: now(),
 	}, nil
 }
+
+// CanComment returns if the member is allowed to comment
+func (m Member) CanComment() bool {
+	return !strings.EqualFold(m.Role, RoleViewer)
+}
+
+// SetMemberKey registers the signing key of a member
+// A key identifies a single member of the test
+func (m *Metadata) SetMemberKey(email, publicKey string) error {
+	if !oneCrypto.ValidPublicKey(publicKey) {
+		return errors.New("Member key is invalid")
+	}
+
+	// The members are copied, so the base revision of an edit is not changed
+	members := append([]Member{}, m.Members...)
+
+	index := -1
+	for i, member := range members {
+		if strings.EqualFold(member.Email, email) {
+			index = i
+		} else if member.PublicKey == publicKey {
+			return errors.New("Key is registered to another member")
+		}
+	}
+
+	if index < 0 {
+		return errors.New(email + " is not a member of this test")
+	}
+
+	members[index].PublicKey = publicKey
+	m.Members = members
+	m.UpdatedAt = now()
+
+	return nil
+}
+
+// MemberWithKey returns the member with the signing key
+func (m Metadata) MemberWithKey(publicKey string) (Member, bool) {
+	if publicKey == "" {
+		return Member{}, false
+	}
+
+	for _, member := range m.Members {
+		if member.PublicKey == publicKey {
+			return member, true
+		}
+	}
+
+	return Member{}, false
+}
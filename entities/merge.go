@@ -0,0 +1,295 @@
+package entities
+
+import (
+	"sort"
+	"strings"
+)
+
+// MergeMetadata merges two concurrent edits made from the same base revision
+// Artifacts and members are merged as sets, an item is kept when any side added it
+// and dropped when a side removed it from the base. Scalar fields are registers
+// where the last writer, by UpdatedAt, wins. Local activities are appended to
+// the remote activity log
+func MergeMetadata(base, local, remote Metadata) Metadata {
+	merged := local
+	merged.SchemaVersion = CurrentSchemaVersion
+
+	// Scalar fields
+	merged.Name = mergeRegister(base.Name, local.Name, remote.Name, local, remote)
+	merged.Description = mergeRegister(base.Description, local.Description, remote.Description, local, remote)
+
+	if remote.Revision > merged.Revision {
+		merged.Revision = remote.Revision
+	}
+
+	if remote.UpdatedAt.After(merged.UpdatedAt) {
+		merged.UpdatedAt = remote.UpdatedAt
+	}
+
+	// Sets
+	merged.Artifacts = mergeArtifacts(base.Artifacts, local.Artifacts, remote.Artifacts)
+	merged.Members = mergeMembers(base.Members, local.Members, remote.Members)
+	mergeMemberKeys(merged.Members, base, local, remote)
+	merged.TestCases = mergeTestCases(base.TestCases, local.TestCases, remote.TestCases)
+	merged.CustomFields = mergeCustomFields(base, local, remote)
+	merged.Comments = mergeComments(base.Comments, local.Comments, remote.Comments)
+	merged.RequiredApprovers = mergeRequiredApprovers(base, local, remote)
+	merged.Approvals = mergeApprovals(local.Approvals, remote.Approvals)
+	merged.Runs = mergeRuns(base.Runs, local.Runs, remote.Runs)
+	merged.Activities = mergeActivities(base.Activities, local.Activities, remote.Activities)
+
+	return merged
+}
+
+// mergeRegister merges a last-writer-wins register
+func mergeRegister(base, local, remote string, localMetadata, remoteMetadata Metadata) string {
+	switch {
+	case local == remote:
+		return local
+	case local == base:
+		return remote
+	case remote == base:
+		return local
+	case remoteMetadata.UpdatedAt.After(localMetadata.UpdatedAt):
+		return remote
+	default:
+		return local
+	}
+}
+
+// mergeArtifacts merges the artifacts of two concurrent edits
+// Artifacts are identified by their hash
+func mergeArtifacts(base, local, remote []Artifact) []Artifact {
+	keyed := func(artifacts []Artifact) keyedItems {
+		return keyedItems{len(artifacts), func(index int) string { return artifacts[index].Hash }}
+	}
+
+	items := append(append([]Artifact{}, local...), remote...)
+
+	merged := []Artifact{}
+	for _, index := range mergeByKey(keyed(base), keyed(local), keyed(remote)) {
+		merged = append(merged, items[index])
+	}
+
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].CreatedAt.Before(merged[j].CreatedAt)
+	})
+
+	return merged
+}
+
+// mergeMembers merges the members of two concurrent edits
+// Members are identified by their email
+func mergeMembers(base, local, remote []Member) []Member {
+	keyed := func(members []Member) keyedItems {
+		return keyedItems{len(members), func(index int) string { return strings.ToLower(members[index].Email) }}
+	}
+
+	items := append(append([]Member{}, local...), remote...)
+
+	merged := []Member{}
+	for _, index := range mergeByKey(keyed(base), keyed(local), keyed(remote)) {
+		merged = append(merged, items[index])
+	}
+
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].CreatedAt.Before(merged[j].CreatedAt)
+	})
+
+	return merged
+}
+
+// mergeMemberKeys merges the key of each merged member as a last-writer-wins register
+func mergeMemberKeys(members []Member, base, local, remote Metadata) {
+	for index, member := range members {
+		baseMember, _ := base.findMember(member.Email)
+		localMember, _ := local.findMember(member.Email)
+		remoteMember, _ := remote.findMember(member.Email)
+
+		members[index].PublicKey = mergeRegister(baseMember.PublicKey, localMember.PublicKey, remoteMember.PublicKey, local, remote)
+	}
+}
+
+// mergeTestCases merges the test cases of two concurrent edits
+// Test cases are identified by their ID and keep the local order
+func mergeTestCases(base, local, remote []TestCase) []TestCase {
+	keyed := func(testCases []TestCase) keyedItems {
+		return keyedItems{len(testCases), func(index int) string { return testCases[index].ID }}
+	}
+
+	items := append(append([]TestCase{}, local...), remote...)
+
+	merged := []TestCase{}
+	for _, index := range mergeByKey(keyed(base), keyed(local), keyed(remote)) {
+		merged = append(merged, items[index])
+	}
+
+	return merged
+}
+
+// mergeCustomFields merges the custom fields of two concurrent edits
+// Each field is a last-writer-wins register, an empty value is a removed field
+func mergeCustomFields(base, local, remote Metadata) map[string]string {
+	merged := map[string]string{}
+
+	keys := map[string]bool{}
+	for key := range local.CustomFields {
+		keys[key] = true
+	}
+	for key := range remote.CustomFields {
+		keys[key] = true
+	}
+
+	for key := range keys {
+		value := mergeRegister(base.CustomFields[key], local.CustomFields[key], remote.CustomFields[key], local, remote)
+		if value == "" {
+			continue
+		}
+
+		merged[key] = value
+	}
+
+	return merged
+}
+
+// mergeComments merges the comments of two concurrent edits
+// Comments are identified by their ID
+func mergeComments(base, local, remote []CommentLink) []CommentLink {
+	keyed := func(comments []CommentLink) keyedItems {
+		return keyedItems{len(comments), func(index int) string { return comments[index].ID }}
+	}
+
+	items := append(append([]CommentLink{}, local...), remote...)
+
+	merged := []CommentLink{}
+	for _, index := range mergeByKey(keyed(base), keyed(local), keyed(remote)) {
+		merged = append(merged, items[index])
+	}
+
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].CreatedAt.Before(merged[j].CreatedAt)
+	})
+
+	return merged
+}
+
+// mergeRequiredApprovers merges the required approvers as a last-writer-wins register
+func mergeRequiredApprovers(base, local, remote Metadata) []string {
+	baseApprovers := strings.Join(base.RequiredApprovers, ",")
+	localApprovers := strings.Join(local.RequiredApprovers, ",")
+	remoteApprovers := strings.Join(remote.RequiredApprovers, ",")
+
+	if mergeRegister(baseApprovers, localApprovers, remoteApprovers, local, remote) == localApprovers {
+		return local.RequiredApprovers
+	}
+
+	return remote.RequiredApprovers
+}
+
+// mergeApprovals merges the approvals of two concurrent edits
+// Approvals are never removed and are identified by their signature
+func mergeApprovals(local, remote []Approval) []Approval {
+	merged := []Approval{}
+	seen := map[string]bool{}
+
+	for _, approval := range append(append([]Approval{}, local...), remote...) {
+		if seen[approval.Signature] {
+			continue
+		}
+
+		seen[approval.Signature] = true
+		merged = append(merged, approval)
+	}
+
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].CreatedAt.Before(merged[j].CreatedAt)
+	})
+
+	return merged
+}
+
+// mergeRuns merges the run history of two concurrent edits
+// Runs are identified by their ID, a run finished by any side is kept finished
+func mergeRuns(base, local, remote []Run) []Run {
+	keyed := func(runs []Run) keyedItems {
+		return keyedItems{len(runs), func(index int) string { return runs[index].ID }}
+	}
+
+	items := append(append([]Run{}, local...), remote...)
+
+	finished := map[string]Run{}
+	for _, run := range remote {
+		if run.Finished() {
+			finished[run.ID] = run
+		}
+	}
+
+	merged := []Run{}
+	for _, index := range mergeByKey(keyed(base), keyed(local), keyed(remote)) {
+		run := items[index]
+		if remoteRun, exists := finished[run.ID]; exists && !run.Finished() {
+			run = remoteRun
+		}
+
+		merged = append(merged, run)
+	}
+
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].StartedAt.Before(merged[j].StartedAt)
+	})
+
+	return merged
+}
+
+// keyedItems is a list of items merged as a set, by the key of each item
+type keyedItems struct {
+	count int
+	key   func(index int) string
+}
+
+// mergeByKey merges the items of two concurrent edits made from the same base
+// Returns the positions of the kept items in the local items followed by the
+// remote ones, an item present in both edits is taken from the local one
+func mergeByKey(base, local, remote keyedItems) []int {
+	keys := func(items keyedItems) map[string]bool {
+		present := map[string]bool{}
+		for index := 0; index < items.count; index++ {
+			present[items.key(index)] = true
+		}
+
+		return present
+	}
+
+	inBase, inLocal, inRemote := keys(base), keys(local), keys(remote)
+
+	kept := []int{}
+	seen := map[string]bool{}
+
+	for index := 0; index < local.count+remote.count; index++ {
+		var key string
+		if index < local.count {
+			key = local.key(index)
+		} else {
+			key = remote.key(index - local.count)
+		}
+
+		if seen[key] || !keepMergedItem(inBase[key], inLocal[key], inRemote[key]) {
+			continue
+		}
+
+		seen[key] = true
+		kept = append(kept, index)
+	}
+
+	return kept
+}
+
+// keepMergedItem returns if an item survives the merge
+// Items of the base are removed when any side removed them, new items are always kept
+func keepMergedItem(inBase, inLocal, inRemote bool) bool {
+	if inBase {
+		return inLocal && inRemote
+	}
+
+	return inLocal || inRemote
+}
@@ -0,0 +1,142 @@
+package entities
+
+import (
+	"testing"
+	"time"
+
+	oneCrypto "gitlab.com/tramonto-one/go-tramonto/crypto"
+)
+
+func TestMergeMetadataKeepsConcurrentAdditions(t *testing.T) {
+	currentTime := time.Now()
+	now = func() time.Time {
+		return currentTime
+	}
+
+	base, err := NewMetadata("TR0001", "Desc")
+	if err != nil {
+		t.Error(err)
+	}
+
+	base.AddArtifact("base", "", "QmBase", nil)
+
+	local := base
+	local.AddArtifact("local", "", "QmLocal", nil)
+
+	remote := base
+	remote.AddArtifact("remote", "", "QmRemote", nil)
+
+	member, err := NewMember("John", "john@tramonto.one", "tester")
+	if err != nil {
+		t.Error(err)
+	}
+
+	remote.AddMember(member)
+
+	merged := MergeMetadata(base, local, remote)
+
+	if len(merged.Artifacts) != 3 {
+		t.Error("merged artifacts are wrong", merged.Artifacts)
+	}
+
+	if len(merged.Members) != 1 {
+		t.Error("merged members are wrong", merged.Members)
+	}
+}
+
+func TestMergeMetadataDropsRemovedItems(t *testing.T) {
+	base, err := NewMetadata("TR0001", "Desc")
+	if err != nil {
+		t.Error(err)
+	}
+
+	base.AddArtifact("first", "", "QmFirst", nil)
+	base.AddArtifact("second", "", "QmSecond", nil)
+
+	local := base
+	local.Artifacts = base.Artifacts[:1]
+
+	remote := base
+
+	merged := MergeMetadata(base, local, remote)
+
+	if len(merged.Artifacts) != 1 || merged.Artifacts[0].Hash != "QmFirst" {
+		t.Error("removed artifact should not be merged back", merged.Artifacts)
+	}
+}
+
+func TestMergeMetadataLastWriterWins(t *testing.T) {
+	base, err := NewMetadata("TR0001", "Desc")
+	if err != nil {
+		t.Error(err)
+	}
+
+	local := base
+	local.Description = "Local"
+	local.UpdatedAt = base.UpdatedAt.Add(time.Minute)
+
+	remote := base
+	remote.Description = "Remote"
+	remote.UpdatedAt = base.UpdatedAt.Add(time.Hour)
+
+	if merged := MergeMetadata(base, local, remote); merged.Description != "Remote" {
+		t.Error("last writer should win", merged.Description)
+	}
+
+	remote.Description = base.Description
+
+	if merged := MergeMetadata(base, local, remote); merged.Description != "Local" {
+		t.Error("single writer should win", merged.Description)
+	}
+}
+
+func TestMergeMetadataKeepsMemberKeys(t *testing.T) {
+	base, err := NewMetadata("TR0001", "Desc")
+	if err != nil {
+		t.Error(err)
+	}
+
+	john, _ := NewMember("John", "john@tramonto.one", "lead")
+	mary, _ := NewMember("Mary", "mary@tramonto.one", "qa")
+	base.AddMember(john)
+	base.AddMember(mary)
+
+	_, johnKey, _ := oneCrypto.GenerateSigningKey()
+	_, maryKey, _ := oneCrypto.GenerateSigningKey()
+
+	local := base
+	local.SetMemberKey("john@tramonto.one", johnKey)
+
+	remote := base
+	remote.SetMemberKey("mary@tramonto.one", maryKey)
+
+	merged := MergeMetadata(base, local, remote)
+
+	if member, exists := merged.MemberWithKey(johnKey); !exists || member.Email != "john@tramonto.one" {
+		t.Error("local key should be kept", merged.Members)
+	}
+
+	if member, exists := merged.MemberWithKey(maryKey); !exists || member.Email != "mary@tramonto.one" {
+		t.Error("remote key should be kept", merged.Members)
+	}
+}
+
+func TestMergeByKey(t *testing.T) {
+	keyed := func(keys ...string) keyedItems {
+		return keyedItems{len(keys), func(index int) string { return keys[index] }}
+	}
+
+	// b is removed locally, c is added on both sides and d only remotely
+	kept := mergeByKey(keyed("a", "b"), keyed("a", "c"), keyed("a", "b", "c", "d"))
+
+	expected := []int{0, 1, 5}
+	if len(kept) != len(expected) {
+		t.Fatalf("expected positions %v, got %v", expected, kept)
+	}
+
+	for index := range expected {
+		if kept[index] != expected[index] {
+			t.Fatalf("expected positions %v, got %v", expected, kept)
+		}
+	}
+}
@@ -1,46 +1,191 @@
 package entities
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"strings"
 	"time"
 )
 
 // Metadata represents the metadata file of a test
 type Metadata struct {
-	Name        string     `json:"name"`
-	Description string     `json:"description"`
-	Revision    int        `json:"revision,omitempty"`
-	CreatedAt   time.Time  `json:"createdAt,omitempty"`
-	Artifacts   []Artifact `json:"artifacts"`
-	Members     []Member   `json:"members"`
+	SchemaVersion     int               `json:"schemaVersion"`
+	ID                string            `json:"id"`
+	Name              string            `json:"name"`
+	Description       string            `json:"description"`
+	Revision          int               `json:"revision,omitempty"`
+	CreatedAt         time.Time         `json:"createdAt,omitempty"`
+	UpdatedAt         time.Time         `json:"updatedAt"`
+	Artifacts         []Artifact        `json:"artifacts"`
+	Members           []Member          `json:"members"`
+	TestCases         []TestCase        `json:"testCases"`
+	CustomFields      map[string]string `json:"customFields"`
+	Activities        []Activity        `json:"activities"`
+	Comments          []CommentLink     `json:"comments"`
+	RequiredApprovers []string          `json:"requiredApprovers"`
+	Approvals         []Approval        `json:"approvals"`
+	Runs              []Run             `json:"runs"`
 }
 
 // NewMetadata creates a new Metadata instance
 func NewMetadata(name, description string) (Metadata, error) {
+	id, err := newID()
+	if err != nil {
+		return Metadata{}, err
+	}
+
+	createdAt := now()
+
 	return Metadata{
-		Name:        name,
-		Description: description,
-		Revision:    1,
-		CreatedAt:   now(),
-		Artifacts:   []Artifact{},
-		Members:     []Member{},
+		SchemaVersion:     CurrentSchemaVersion,
+		ID:                id,
+		Name:              name,
+		Description:       description,
+		Revision:          1,
+		CreatedAt:         createdAt,
+		UpdatedAt:         createdAt,
+		Artifacts:         []Artifact{},
+		Members:           []Member{},
+		TestCases:         []TestCase{},
+		CustomFields:      map[string]string{},
+		Activities:        []Activity{},
+		Comments:          []CommentLink{},
+		RequiredApprovers: []string{},
+		Approvals:         []Approval{},
+		Runs:              []Run{},
 	}, nil
 }
 
 // MetadataFromJSON returns a metadata from JSON
+// Documents of older schema versions are upgraded to the current one
 func MetadataFromJSON(metadataJSON []byte) (Metadata, error) {
 	var metadata Metadata
-	if err := json.Unmarshal(metadataJSON, &metadata); err != nil {
+
+	// Reads the raw document to find its schema version
+	var document map[string]interface{}
+	if err := json.Unmarshal(metadataJSON, &document); err != nil {
+		return metadata, errors.New("Invalid metadata document: " + err.Error())
+	}
+
+	if document == nil {
+		return metadata, errors.New("Invalid metadata document: document is empty")
+	}
+
+	// Lifts the document to the current schema
+	if err := upgradeMetadata(document); err != nil {
 		return metadata, err
 	}
 
+	upgradedJSON, err := json.Marshal(document)
+	if err != nil {
+		return metadata, errors.New("Invalid metadata document: " + err.Error())
+	}
+
+	// Decodes strictly, the upgraded document must match the current schema
+	// Documents of newer clients were rejected already by their schema version,
+	// so an unknown field means a malformed document
+	decoder := json.NewDecoder(bytes.NewReader(upgradedJSON))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(&metadata); err != nil {
+		return Metadata{}, errors.New("Invalid metadata document: " + err.Error())
+	}
+
+	if err := metadata.Validate(); err != nil {
+		return Metadata{}, err
+	}
+
 	return metadata, nil
 }
 
+// Validate verifies that the metadata is well formed
+func (m *Metadata) Validate() error {
+	if m.ID == "" {
+		return errors.New("Invalid metadata: id is required")
+	}
+
+	if strings.TrimSpace(m.Name) == "" {
+		return errors.New("Invalid metadata: name is required")
+	}
+
+	if m.Revision < 1 {
+		return fmt.Errorf("Invalid metadata: revision must be positive, got %d", m.Revision)
+	}
+
+	artifactHashes := map[string]bool{}
+	for index, artifact := range m.Artifacts {
+		if artifact.Hash == "" {
+			return fmt.Errorf("Invalid metadata: artifacts[%d] has no hash", index)
+		}
+
+		if artifactHashes[artifact.Hash] {
+			return fmt.Errorf("Invalid metadata: artifacts[%d] duplicates hash %s", index, artifact.Hash)
+		}
+
+		artifactHashes[artifact.Hash] = true
+	}
+
+	// Emails are only checked when members are added, documents written
+	// before the check may have members without a valid email
+	for index, member := range m.Members {
+		if strings.TrimSpace(member.Name) == "" {
+			return fmt.Errorf("Invalid metadata: members[%d] has no name", index)
+		}
+	}
+
+	testCaseIDs := map[string]bool{}
+	for index, testCase := range m.TestCases {
+		if testCase.ID == "" {
+			return fmt.Errorf("Invalid metadata: testCases[%d] has no id", index)
+		}
+
+		if strings.TrimSpace(testCase.Title) == "" {
+			return fmt.Errorf("Invalid metadata: testCases[%d] has no title", index)
+		}
+
+		if testCaseIDs[testCase.ID] {
+			return fmt.Errorf("Invalid metadata: testCases[%d] duplicates id %s", index, testCase.ID)
+		}
+
+		testCaseIDs[testCase.ID] = true
+	}
+
+	commentIDs := map[string]bool{}
+	for index, comment := range m.Comments {
+		if comment.ID == "" || comment.Hash == "" {
+			return fmt.Errorf("Invalid metadata: comments[%d] has no id or hash", index)
+		}
+
+		if commentIDs[comment.ID] {
+			return fmt.Errorf("Invalid metadata: comments[%d] duplicates id %s", index, comment.ID)
+		}
+
+		commentIDs[comment.ID] = true
+	}
+
+	runIDs := map[string]bool{}
+	for index, run := range m.Runs {
+		if run.ID == "" {
+			return fmt.Errorf("Invalid metadata: runs[%d] has no id", index)
+		}
+
+		if runIDs[run.ID] {
+			return fmt.Errorf("Invalid metadata: runs[%d] duplicates id %s", index, run.ID)
+		}
+
+		runIDs[run.ID] = true
+	}
+
+	return nil
+}
+
 // ToJSON converts the metadata file to a JSON
+// The document is always written with the current schema version
 func (m *Metadata) ToJSON() ([]byte, error) {
+	m.SchemaVersion = CurrentSchemaVersion
+
 	json, err := json.Marshal(m)
 	if err != nil {
 		return []byte{}, err
@@ -57,6 +202,7 @@ func (m *Metadata) AddArtifact(name, description, hash string, fileHeaders map[s
 	}
 
 	m.Artifacts = append(m.Artifacts, artifact)
+	m.UpdatedAt = artifact.CreatedAt
 
 	return nil
 }
@@ -75,6 +221,7 @@ func (m *Metadata) AddMember(newMember Member) error {
 	}
 
 	m.Members = append(m.Members, newMember)
+	m.UpdatedAt = now()
 
 	return nil
 }
@@ -21,6 +21,10 @@ func TestNewMetadata(t *testing.T) {
 		t.Error(err)
 	}
 
+	if metadata.ID == "" {
+		t.Error("metadata id is empty")
+	}
+
 	if metadata.Name != assertName {
 		t.Error("metadata name is wrong")
 	}
@@ -49,13 +53,13 @@ func TestConvertMetadataToJSON(t *testing.T) {
 		t.Error(err)
 	}
 
-	assert := "{\"name\":\"TR0001\",\"description\":\"My description!\",\"revision\":1,\"createdAt\":" + string(jsonTime) + "}"
-
 	metadata, err := NewMetadata("TR0001", "My description!")
 	if err != nil {
 		t.Error(err)
 	}
 
+	assert := "{\"schemaVersion\":9,\"id\":\"" + metadata.ID + "\",\"name\":\"TR0001\",\"description\":\"My description!\",\"revision\":1,\"createdAt\":" + string(jsonTime) + ",\"updatedAt\":" + string(jsonTime) + ",\"artifacts\":[],\"members\":[],\"testCases\":[],\"customFields\":{},\"activities\":[],\"comments\":[],\"requiredApprovers\":[],\"approvals\":[],\"runs\":[]}"
+
 	json, err := metadata.ToJSON()
 	if err != nil {
 		t.Error(err)
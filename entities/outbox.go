@@ -0,0 +1,59 @@
+package entities
+
+import "time"
+
+// Kinds of the operations in the outbox
+const (
+	// OperationPublishRevision publishes a revision uploaded while offline
+	OperationPublishRevision = "publishRevision"
+)
+
+// Limits of the retry backoff of the outbox
+const (
+	minOutboxBackoff = 30 * time.Second
+	maxOutboxBackoff = time.Hour
+)
+
+// MaxOutboxAttempts is how many times an operation is tried before giving up
+// Operations failing because the network cannot be reached are always retried
+const MaxOutboxAttempts = 10
+
+// OutboxOperation represents an operation waiting to be synced with the network
+// A revision edited from BaseIpfs was uploaded to EditedIpfs and still has to be
+// published to the test IPNS. An operation given up is kept until retried
+type OutboxOperation struct {
+	ID            int64      `json:"id"`
+	Kind          string     `json:"kind"`
+	TestIpns      string     `json:"testIpns"`
+	BaseIpfs      string     `json:"baseIpfs"`
+	EditedIpfs    string     `json:"editedIpfs"`
+	Attempts      int        `json:"attempts"`
+	LastError     string     `json:"lastError"`
+	NextAttemptAt time.Time  `json:"nextAttemptAt"`
+	GivenUpAt     *time.Time `json:"givenUpAt"`
+	CreatedAt     time.Time  `json:"createdAt"`
+}
+
+// SyncStatus represents the operations pending sync and the ones given up
+type SyncStatus struct {
+	Online     bool              `json:"online"`
+	Pending    int               `json:"pending"`
+	Operations []OutboxOperation `json:"operations"`
+	Failed     []OutboxOperation `json:"failed"`
+}
+
+// OutboxBackoff returns how long to wait before retrying an operation
+// The delay doubles on each failed attempt
+func OutboxBackoff(attempts int) time.Duration {
+	backoff := minOutboxBackoff
+
+	for attempt := 1; attempt < attempts; attempt++ {
+		backoff *= 2
+
+		if backoff >= maxOutboxBackoff {
+			return maxOutboxBackoff
+		}
+	}
+
+	return backoff
+}
@@ -0,0 +1,23 @@
+package entities
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOutboxBackoff(t *testing.T) {
+	expected := map[int]time.Duration{
+		0:   30 * time.Second,
+		1:   30 * time.Second,
+		2:   time.Minute,
+		3:   2 * time.Minute,
+		8:   time.Hour,
+		100: time.Hour,
+	}
+
+	for attempts, backoff := range expected {
+		if got := OutboxBackoff(attempts); got != backoff {
+			t.Errorf("backoff of %d attempts should be %s, got %s", attempts, backoff, got)
+		}
+	}
+}
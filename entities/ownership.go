@@ -0,0 +1,53 @@
+package entities
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// OwnerKeyVersion is the version of the exported owner keys
+const OwnerKeyVersion = 1
+
+// OwnerKey represents the IPNS key of a test exported to another device of the owner
+// It carries the secret of the test, so the other device can import the test too
+type OwnerKey struct {
+	Version    int    `json:"version"`
+	Ipns       string `json:"ipns"`
+	KeyName    string `json:"keyName"`
+	Secret     string `json:"secret"`
+	PrivateKey []byte `json:"privateKey"`
+}
+
+// OwnerKeyFromJSON parses and validates an exported owner key
+func OwnerKeyFromJSON(data []byte) (OwnerKey, error) {
+	var key OwnerKey
+	if err := json.Unmarshal(data, &key); err != nil {
+		return OwnerKey{}, errors.New("Invalid owner key: " + err.Error())
+	}
+
+	if key.Version != OwnerKeyVersion {
+		return OwnerKey{}, errors.New("Unsupported owner key version " + strconv.Itoa(key.Version))
+	}
+
+	if key.Ipns == "" || key.KeyName == "" || key.Secret == "" || len(key.PrivateKey) == 0 {
+		return OwnerKey{}, errors.New("Invalid owner key: missing fields")
+	}
+
+	if !validKeyName(key.KeyName) {
+		return OwnerKey{}, errors.New("Invalid owner key: invalid key name " + key.KeyName)
+	}
+
+	return key, nil
+}
+
+// validKeyName returns if the name can be used for a key of the keystore
+// The node key is named self, and names are stored as files in the keystore
+func validKeyName(name string) bool {
+	if name == "" || name == "self" || strings.HasPrefix(name, ".") {
+		return false
+	}
+
+	return !strings.ContainsAny(name, "/\\\x00")
+}
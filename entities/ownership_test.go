@@ -0,0 +1,46 @@
+package entities
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestOwnerKeyFromJSON(t *testing.T) {
+	key := OwnerKey{
+		Version:    OwnerKeyVersion,
+		Ipns:       "QmIpns",
+		KeyName:    "TR0001",
+		Secret:     "secret",
+		PrivateKey: []byte{1, 2, 3},
+	}
+
+	data, err := json.Marshal(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := OwnerKeyFromJSON(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if parsed.Ipns != key.Ipns || parsed.KeyName != key.KeyName || string(parsed.PrivateKey) != string(key.PrivateKey) {
+		t.Errorf("unexpected key %+v", parsed)
+	}
+
+	invalid := []string{
+		`not json`,
+		`{"version":2,"ipns":"QmIpns","keyName":"TR0001","secret":"secret","privateKey":"AQID"}`,
+		`{"version":1,"ipns":"QmIpns","keyName":"TR0001","secret":"secret"}`,
+		`{"version":1,"keyName":"TR0001","secret":"secret","privateKey":"AQID"}`,
+		`{"version":1,"ipns":"QmIpns","keyName":"self","secret":"secret","privateKey":"AQID"}`,
+		`{"version":1,"ipns":"QmIpns","keyName":"../TR0001","secret":"secret","privateKey":"AQID"}`,
+		`{"version":1,"ipns":"QmIpns","keyName":"keys/TR0001","secret":"secret","privateKey":"AQID"}`,
+	}
+
+	for _, data := range invalid {
+		if _, err := OwnerKeyFromJSON([]byte(data)); err == nil {
+			t.Errorf("key %s should be invalid", data)
+		}
+	}
+}
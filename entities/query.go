@@ -0,0 +1,131 @@
+package entities
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Sort keys of the test listing
+const (
+	SortByUpdatedAt = "updatedAt"
+	SortByCreatedAt = "createdAt"
+	SortByName      = "name"
+)
+
+// Ownership filters of the test listing
+const (
+	OwnershipAll    = "all"
+	OwnershipOwned  = "owned"
+	OwnershipShared = "shared"
+)
+
+// Status filters of the test listing
+const (
+	StatusActive   = "active"
+	StatusArchived = "archived"
+	StatusAll      = "all"
+)
+
+// Limits of the page size
+const (
+	DefaultQueryLimit = 50
+	MaxQueryLimit     = 200
+)
+
+// TestQuery represents a query of the test listing
+// Favorites are always listed first, then the tests are sorted by the sort key
+type TestQuery struct {
+	Limit       int        `json:"limit"`
+	Cursor      string     `json:"cursor"`
+	Sort        string     `json:"sort"`
+	Ownership   string     `json:"ownership"`
+	Favorite    bool       `json:"favorite"`
+	Status      string     `json:"status"`
+	UpdatedFrom *time.Time `json:"updatedFrom,omitempty"`
+	UpdatedTo   *time.Time `json:"updatedTo,omitempty"`
+	Text        string     `json:"text"`
+}
+
+// TestPage represents a page of the test listing
+// NextCursor is empty on the last page
+type TestPage struct {
+	Tests      []Test `json:"tests"`
+	NextCursor string `json:"nextCursor"`
+}
+
+// NewTestQuery creates a query with the default values
+func NewTestQuery() TestQuery {
+	return TestQuery{
+		Limit:     DefaultQueryLimit,
+		Sort:      SortByUpdatedAt,
+		Ownership: OwnershipAll,
+		Status:    StatusActive,
+	}
+}
+
+// TestQueryFromJSON returns a query from JSON
+// Missing values are filled with the defaults
+func TestQueryFromJSON(queryJSON []byte) (TestQuery, error) {
+	query := NewTestQuery()
+
+	if len(queryJSON) > 0 {
+		if err := json.Unmarshal(queryJSON, &query); err != nil {
+			return TestQuery{}, errors.New("Invalid query: " + err.Error())
+		}
+	}
+
+	if query.Limit == 0 {
+		query.Limit = DefaultQueryLimit
+	}
+
+	if query.Sort == "" {
+		query.Sort = SortByUpdatedAt
+	}
+
+	if query.Ownership == "" {
+		query.Ownership = OwnershipAll
+	}
+
+	if query.Status == "" {
+		query.Status = StatusActive
+	}
+
+	if err := query.Validate(); err != nil {
+		return TestQuery{}, err
+	}
+
+	return query, nil
+}
+
+// Validate verifies that the query is well formed
+func (q TestQuery) Validate() error {
+	if q.Limit < 1 || q.Limit > MaxQueryLimit {
+		return fmt.Errorf("Invalid query: limit must be between 1 and %d, got %d", MaxQueryLimit, q.Limit)
+	}
+
+	switch q.Sort {
+	case SortByUpdatedAt, SortByCreatedAt, SortByName:
+	default:
+		return fmt.Errorf("Invalid query: unknown sort %q", q.Sort)
+	}
+
+	switch q.Ownership {
+	case OwnershipAll, OwnershipOwned, OwnershipShared:
+	default:
+		return fmt.Errorf("Invalid query: unknown ownership %q", q.Ownership)
+	}
+
+	switch q.Status {
+	case StatusActive, StatusArchived, StatusAll:
+	default:
+		return fmt.Errorf("Invalid query: unknown status %q", q.Status)
+	}
+
+	if q.UpdatedFrom != nil && q.UpdatedTo != nil && q.UpdatedTo.Before(*q.UpdatedFrom) {
+		return errors.New("Invalid query: updatedTo is before updatedFrom")
+	}
+
+	return nil
+}
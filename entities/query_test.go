@@ -0,0 +1,38 @@
+package entities
+
+import "testing"
+
+func TestTestQueryFromJSON(t *testing.T) {
+	query, err := TestQueryFromJSON(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if query != NewTestQuery() {
+		t.Error("empty query should have the default values")
+	}
+
+	query, err = TestQueryFromJSON([]byte(`{"limit": 10, "sort": "name", "ownership": "shared", "favorite": true}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if query.Limit != 10 || query.Sort != SortByName || query.Ownership != OwnershipShared || !query.Favorite || query.Status != StatusActive {
+		t.Errorf("unexpected query %+v", query)
+	}
+
+	invalidQueries := []string{
+		`{"limit": 1000}`,
+		`{"sort": "size"}`,
+		`{"ownership": "mine"}`,
+		`{"status": "deleted"}`,
+		`{"updatedFrom": "2019-10-02T00:00:00Z", "updatedTo": "2019-10-01T00:00:00Z"}`,
+		`{"limit": "ten"}`,
+	}
+
+	for _, queryJSON := range invalidQueries {
+		if _, err := TestQueryFromJSON([]byte(queryJSON)); err == nil {
+			t.Errorf("query %s should be invalid", queryJSON)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package entities
+
+import "time"
+
+// JobTypeRepublish publishes again the IPNS records about to expire
+const JobTypeRepublish = "republish"
+
+// IPNSRecord represents the latest revision an owned test published to its IPNS
+type IPNSRecord struct {
+	IpnsHash    string    `json:"ipnsHash"`
+	KeyName     string    `json:"keyName"`
+	IpfsHash    string    `json:"ipfsHash"`
+	PublishedAt time.Time `json:"publishedAt"`
+	LastError   string    `json:"lastError"`
+}
+
+// NeedsRepublish returns if the record was published longer than the interval ago
+func (r IPNSRecord) NeedsRepublish(now time.Time, interval time.Duration) bool {
+	return !r.PublishedAt.Add(interval).After(now)
+}
+
+// RepublishFailure represents a record that could not be published again
+// It is sent to the callback of the republisher
+type RepublishFailure struct {
+	IpnsHash string `json:"ipns"`
+	IpfsHash string `json:"ipfs"`
+	Error    string `json:"error"`
+}
@@ -0,0 +1,28 @@
+package entities
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIPNSRecordNeedsRepublish(t *testing.T) {
+	now := time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC)
+	interval := 12 * time.Hour
+
+	cases := []struct {
+		publishedAt time.Time
+		needs       bool
+	}{
+		{now.Add(-13 * time.Hour), true},
+		{now.Add(-12 * time.Hour), true},
+		{now.Add(-time.Hour), false},
+	}
+
+	for _, c := range cases {
+		record := IPNSRecord{IpnsHash: "Qm", PublishedAt: c.publishedAt}
+
+		if needs := record.NeedsRepublish(now, interval); needs != c.needs {
+			t.Errorf("NeedsRepublish of a record published at %v = %v, want %v", c.publishedAt, needs, c.needs)
+		}
+	}
+}
@@ -0,0 +1,327 @@
+package entities
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Verdicts of a test case in a run
+const (
+	VerdictPassed  = "passed"
+	VerdictFailed  = "failed"
+	VerdictBlocked = "blocked"
+	VerdictSkipped = "skipped"
+)
+
+// Run represents an execution of a test
+type Run struct {
+	ID          string        `json:"id"`
+	Build       string        `json:"build"`
+	Device      string        `json:"device"`
+	OS          string        `json:"os"`
+	Environment string        `json:"environment"`
+	StartedAt   time.Time     `json:"startedAt"`
+	FinishedAt  time.Time     `json:"finishedAt"`
+	Verdicts    []CaseVerdict `json:"verdicts"`
+	Artifacts   []string      `json:"artifacts"`
+}
+
+// CaseVerdict represents the verdict of a test case in a run
+type CaseVerdict struct {
+	CaseID  string `json:"caseId"`
+	Verdict string `json:"verdict"`
+	Note    string `json:"note,omitempty"`
+}
+
+// RunStats represents statistics over the run history of a test
+type RunStats struct {
+	Runs                int      `json:"runs"`
+	PassRate            float64  `json:"passRate"`
+	MeanDurationSeconds float64  `json:"meanDurationSeconds"`
+	Flakiness           float64  `json:"flakiness"`
+	FlakyCases          []string `json:"flakyCases"`
+}
+
+// RunFromJSON returns a new run from JSON
+func RunFromJSON(runJSON []byte) (Run, error) {
+	var run Run
+	if err := json.Unmarshal(runJSON, &run); err != nil {
+		return Run{}, errors.New("Invalid run: " + err.Error())
+	}
+
+	id, err := newID()
+	if err != nil {
+		return Run{}, err
+	}
+
+	run.ID = id
+
+	if run.StartedAt.IsZero() {
+		return Run{}, errors.New("Invalid run: startedAt is required")
+	}
+
+	// A run still in progress has no finishedAt
+	if !run.FinishedAt.IsZero() && run.FinishedAt.Before(run.StartedAt) {
+		return Run{}, errors.New("Invalid run: finishedAt is before startedAt")
+	}
+
+	if err := validateVerdicts(run.Verdicts); err != nil {
+		return Run{}, errors.New("Invalid run: " + err.Error())
+	}
+
+	if run.Verdicts == nil {
+		run.Verdicts = []CaseVerdict{}
+	}
+
+	if run.Artifacts == nil {
+		run.Artifacts = []string{}
+	}
+
+	return run, nil
+}
+
+// VerdictsFromJSON returns the verdicts of test cases from JSON
+func VerdictsFromJSON(verdictsJSON []byte) ([]CaseVerdict, error) {
+	var verdicts []CaseVerdict
+	if err := json.Unmarshal(verdictsJSON, &verdicts); err != nil {
+		return nil, errors.New("Invalid verdicts: " + err.Error())
+	}
+
+	if err := validateVerdicts(verdicts); err != nil {
+		return nil, errors.New("Invalid verdicts: " + err.Error())
+	}
+
+	return verdicts, nil
+}
+
+// validateVerdicts verifies the verdicts are known and of different test cases
+func validateVerdicts(verdicts []CaseVerdict) error {
+	cases := map[string]bool{}
+	for index, verdict := range verdicts {
+		switch verdict.Verdict {
+		case VerdictPassed, VerdictFailed, VerdictBlocked, VerdictSkipped:
+		default:
+			return fmt.Errorf("verdicts[%d] has an unknown verdict %q", index, verdict.Verdict)
+		}
+
+		if cases[verdict.CaseID] {
+			return fmt.Errorf("verdicts[%d] repeats the test case %s", index, verdict.CaseID)
+		}
+
+		cases[verdict.CaseID] = true
+	}
+
+	return nil
+}
+
+// Passed returns if the run finished with a passed or failed test case
+// and no test case failed or was blocked
+func (r Run) Passed() bool {
+	if !r.Decided() {
+		return false
+	}
+
+	for _, verdict := range r.Verdicts {
+		if verdict.Verdict == VerdictFailed || verdict.Verdict == VerdictBlocked {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Decided returns if the run finished with at least a passed or failed test case
+// Only decided runs count in the pass rate
+func (r Run) Decided() bool {
+	if !r.Finished() {
+		return false
+	}
+
+	for _, verdict := range r.Verdicts {
+		if verdict.Verdict == VerdictPassed || verdict.Verdict == VerdictFailed {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Finished returns if the run is not in progress anymore
+func (r Run) Finished() bool {
+	return !r.FinishedAt.IsZero()
+}
+
+// Duration returns how long the run took, zero while in progress
+func (r Run) Duration() time.Duration {
+	if !r.Finished() {
+		return 0
+	}
+
+	return r.FinishedAt.Sub(r.StartedAt)
+}
+
+// AddRun records a run of the test
+// The verdicts must be of test cases of the test and the artifacts must be in the test
+func (m *Metadata) AddRun(run Run) error {
+	if err := m.verifyVerdictCases(run.Verdicts); err != nil {
+		return err
+	}
+
+	for _, artifactHash := range run.Artifacts {
+		if !m.hasArtifact(artifactHash) {
+			return errors.New("Artifact " + artifactHash + " not found in this test")
+		}
+	}
+
+	m.Runs = append(m.Runs, run)
+	m.UpdatedAt = now()
+
+	return nil
+}
+
+// FinishRun finishes a run in progress with the verdicts of its test cases
+// A verdict replaces the one of the same test case recorded while in progress
+func (m *Metadata) FinishRun(runID string, verdicts []CaseVerdict, finishedAt time.Time) (Run, error) {
+	for index := range m.Runs {
+		if m.Runs[index].ID != runID {
+			continue
+		}
+
+		// Copies the runs so the metadata this one was copied from is kept
+		m.Runs = append([]Run{}, m.Runs...)
+		run := &m.Runs[index]
+
+		if run.Finished() {
+			return Run{}, errors.New("Run " + runID + " is already finished")
+		}
+
+		if finishedAt.IsZero() || finishedAt.Before(run.StartedAt) {
+			return Run{}, errors.New("Run " + runID + " cannot finish before it started")
+		}
+
+		if err := m.verifyVerdictCases(verdicts); err != nil {
+			return Run{}, err
+		}
+
+		run.Verdicts = append([]CaseVerdict{}, run.Verdicts...)
+		for _, verdict := range verdicts {
+			replaced := false
+			for current := range run.Verdicts {
+				if run.Verdicts[current].CaseID == verdict.CaseID {
+					run.Verdicts[current] = verdict
+					replaced = true
+				}
+			}
+
+			if !replaced {
+				run.Verdicts = append(run.Verdicts, verdict)
+			}
+		}
+
+		run.FinishedAt = finishedAt
+		m.UpdatedAt = now()
+
+		return *run, nil
+	}
+
+	return Run{}, errors.New("Run " + runID + " not found in this test")
+}
+
+// verifyVerdictCases verifies the verdicts are of test cases of the test
+func (m *Metadata) verifyVerdictCases(verdicts []CaseVerdict) error {
+	cases := map[string]bool{}
+	for _, testCase := range m.TestCases {
+		cases[testCase.ID] = true
+	}
+
+	for _, verdict := range verdicts {
+		if !cases[verdict.CaseID] {
+			return errors.New("Test case " + verdict.CaseID + " not found in this test")
+		}
+	}
+
+	return nil
+}
+
+// ComputeRunStats computes the statistics of a run history
+// The pass rate and the mean duration are of the finished runs, the pass rate
+// only counts runs with at least a passed or failed test case
+// Flakiness is the ratio of consecutive runs where a test case changed
+// between passed and failed
+func ComputeRunStats(runs []Run) RunStats {
+	stats := RunStats{
+		Runs:       len(runs),
+		FlakyCases: []string{},
+	}
+
+	if len(runs) == 0 {
+		return stats
+	}
+
+	sorted := append([]Run{}, runs...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].StartedAt.Before(sorted[j].StartedAt)
+	})
+
+	passed, decided, finished := 0, 0, 0
+	var totalDuration time.Duration
+
+	lastVerdicts := map[string]string{}
+	flakyCases := map[string]bool{}
+	transitions, flips := 0, 0
+
+	for _, run := range sorted {
+		if run.Decided() {
+			decided++
+
+			if run.Passed() {
+				passed++
+			}
+		}
+
+		if run.Finished() {
+			finished++
+			totalDuration += run.Duration()
+		}
+
+		for _, verdict := range run.Verdicts {
+			if verdict.Verdict != VerdictPassed && verdict.Verdict != VerdictFailed {
+				continue
+			}
+
+			if last, exists := lastVerdicts[verdict.CaseID]; exists {
+				transitions++
+
+				if last != verdict.Verdict {
+					flips++
+					flakyCases[verdict.CaseID] = true
+				}
+			}
+
+			lastVerdicts[verdict.CaseID] = verdict.Verdict
+		}
+	}
+
+	if decided > 0 {
+		stats.PassRate = float64(passed) / float64(decided)
+	}
+
+	if finished > 0 {
+		stats.MeanDurationSeconds = totalDuration.Seconds() / float64(finished)
+	}
+
+	if transitions > 0 {
+		stats.Flakiness = float64(flips) / float64(transitions)
+	}
+
+	for caseID := range flakyCases {
+		stats.FlakyCases = append(stats.FlakyCases, caseID)
+	}
+
+	sort.Strings(stats.FlakyCases)
+
+	return stats
+}
@@ -0,0 +1,201 @@
+package entities
+
+import (
+	"testing"
+	"time"
+)
+
+func TestComputeRunStats(t *testing.T) {
+	start := time.Date(2019, 10, 1, 10, 0, 0, 0, time.UTC)
+
+	newRun := func(offset time.Duration, verdicts ...string) Run {
+		run := Run{
+			StartedAt:  start.Add(offset),
+			FinishedAt: start.Add(offset + time.Minute),
+		}
+
+		for index, verdict := range verdicts {
+			run.Verdicts = append(run.Verdicts, CaseVerdict{CaseID: []string{"login", "logout"}[index], Verdict: verdict})
+		}
+
+		return run
+	}
+
+	runs := []Run{
+		newRun(2*time.Hour, VerdictPassed, VerdictPassed),
+		newRun(0, VerdictPassed, VerdictPassed),
+		newRun(time.Hour, VerdictFailed, VerdictPassed),
+		newRun(3*time.Hour, VerdictPassed, VerdictSkipped),
+	}
+
+	stats := ComputeRunStats(runs)
+
+	if stats.Runs != 4 {
+		t.Error("runs are wrong", stats.Runs)
+	}
+
+	if stats.PassRate != 0.75 {
+		t.Error("pass rate is wrong", stats.PassRate)
+	}
+
+	if stats.MeanDurationSeconds != 60 {
+		t.Error("mean duration is wrong", stats.MeanDurationSeconds)
+	}
+
+	// login flips twice in three transitions, logout never flips in two
+	if stats.Flakiness != 0.4 {
+		t.Error("flakiness is wrong", stats.Flakiness)
+	}
+
+	if len(stats.FlakyCases) != 1 || stats.FlakyCases[0] != "login" {
+		t.Error("flaky cases are wrong", stats.FlakyCases)
+	}
+}
+
+func TestAddRunValidatesCases(t *testing.T) {
+	metadata, err := NewMetadata("TR0001", "Desc")
+	if err != nil {
+		t.Error(err)
+	}
+
+	testCase, _ := NewTestCase("Login", "", "")
+	metadata.TestCases = append(metadata.TestCases, testCase)
+
+	run, err := RunFromJSON([]byte(`{"build": "42", "startedAt": "2019-10-01T10:00:00Z", "finishedAt": "2019-10-01T10:05:00Z", "verdicts": [{"caseId": "` + testCase.ID + `", "verdict": "passed"}]}`))
+	if err != nil {
+		t.Error(err)
+	}
+
+	if err := metadata.AddRun(run); err != nil {
+		t.Error(err)
+	}
+
+	run.Verdicts[0].CaseID = "unknown"
+	if err := metadata.AddRun(run); err == nil {
+		t.Error("run with unknown case should be rejected")
+	}
+
+	if _, err := RunFromJSON([]byte(`{"startedAt": "2019-10-01T10:00:00Z", "finishedAt": "2019-10-01T10:05:00Z", "verdicts": [{"caseId": "x", "verdict": "maybe"}]}`)); err == nil {
+		t.Error("run with unknown verdict should be rejected")
+	}
+
+	if _, err := RunFromJSON([]byte(`{"startedAt": "2019-10-01T10:00:00Z", "verdicts": [{"caseId": "x", "verdict": "passed"}, {"caseId": "x", "verdict": "failed"}]}`)); err == nil {
+		t.Error("run with two verdicts of a case should be rejected")
+	}
+
+	if _, err := RunFromJSON([]byte(`{"startedAt": "2019-10-01T10:00:00Z", "finishedAt": "2019-10-01T09:55:00Z"}`)); err == nil {
+		t.Error("run finished before it started should be rejected")
+	}
+
+	// A run in progress has no finishedAt
+	inProgress, err := RunFromJSON([]byte(`{"startedAt": "2019-10-01T10:00:00Z", "verdicts": [{"caseId": "` + testCase.ID + `", "verdict": "passed"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if inProgress.Finished() || inProgress.Duration() != 0 {
+		t.Error("run should be in progress", inProgress)
+	}
+
+	if stats := ComputeRunStats([]Run{run, inProgress}); stats.MeanDurationSeconds != 300 {
+		t.Error("runs in progress should not count in the mean duration", stats.MeanDurationSeconds)
+	}
+}
+
+func TestFinishRun(t *testing.T) {
+	metadata, err := NewMetadata("TR0001", "Desc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	login, _ := NewTestCase("Login", "", "")
+	logout, _ := NewTestCase("Logout", "", "")
+	metadata.TestCases = append(metadata.TestCases, login, logout)
+
+	run, err := RunFromJSON([]byte(`{"startedAt": "2019-10-01T10:00:00Z", "verdicts": [{"caseId": "` + login.ID + `", "verdict": "failed"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := metadata.AddRun(run); err != nil {
+		t.Fatal(err)
+	}
+
+	base := metadata
+	finishedAt := run.StartedAt.Add(5 * time.Minute)
+
+	if _, err := metadata.FinishRun("unknown", nil, finishedAt); err == nil {
+		t.Error("an unknown run should not be finished")
+	}
+
+	if _, err := metadata.FinishRun(run.ID, nil, run.StartedAt.Add(-time.Minute)); err == nil {
+		t.Error("a run should not finish before it started")
+	}
+
+	if _, err := metadata.FinishRun(run.ID, []CaseVerdict{{CaseID: "unknown", Verdict: VerdictPassed}}, finishedAt); err == nil {
+		t.Error("a verdict of an unknown case should be rejected")
+	}
+
+	finished, err := metadata.FinishRun(run.ID, []CaseVerdict{
+		{CaseID: login.ID, Verdict: VerdictPassed},
+		{CaseID: logout.ID, Verdict: VerdictPassed},
+	}, finishedAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !finished.Finished() || !finished.Passed() || len(finished.Verdicts) != 2 || finished.Duration() != 5*time.Minute {
+		t.Errorf("unexpected finished run %+v", finished)
+	}
+
+	if base.Runs[0].Finished() || base.Runs[0].Verdicts[0].Verdict != VerdictFailed {
+		t.Errorf("the base metadata should keep the run in progress, got %+v", base.Runs[0])
+	}
+
+	if _, err := metadata.FinishRun(run.ID, nil, finishedAt); err == nil {
+		t.Error("a finished run should not be finished again")
+	}
+
+	// A concurrent edit that did not see the finish keeps the run finished
+	concurrent := base
+	concurrent.Runs = append([]Run{}, base.Runs...)
+	merged := MergeMetadata(base, concurrent, metadata)
+	if len(merged.Runs) != 1 || !merged.Runs[0].Finished() {
+		t.Errorf("the merged run should be finished, got %+v", merged.Runs)
+	}
+
+	if _, err := VerdictsFromJSON([]byte(`[{"caseId": "x", "verdict": "maybe"}]`)); err == nil {
+		t.Error("verdicts with an unknown verdict should be rejected")
+	}
+}
+
+func TestComputeRunStatsCountsDecidedRuns(t *testing.T) {
+	start := time.Date(2019, 10, 1, 10, 0, 0, 0, time.UTC)
+
+	runs := []Run{
+		{StartedAt: start, FinishedAt: start.Add(time.Minute), Verdicts: []CaseVerdict{{CaseID: "login", Verdict: VerdictFailed}}},
+		{StartedAt: start.Add(time.Hour), FinishedAt: start.Add(time.Hour + time.Minute), Verdicts: []CaseVerdict{{CaseID: "login", Verdict: VerdictPassed}}},
+		// In progress
+		{StartedAt: start.Add(2 * time.Hour), Verdicts: []CaseVerdict{{CaseID: "login", Verdict: VerdictPassed}}},
+		// Finished without verdicts and with only skipped cases
+		{StartedAt: start.Add(3 * time.Hour), FinishedAt: start.Add(3*time.Hour + time.Minute)},
+		{StartedAt: start.Add(4 * time.Hour), FinishedAt: start.Add(4*time.Hour + time.Minute), Verdicts: []CaseVerdict{{CaseID: "login", Verdict: VerdictSkipped}}},
+	}
+
+	if runs[2].Passed() || runs[3].Passed() || runs[4].Passed() {
+		t.Error("runs in progress or without passed or failed cases should not pass")
+	}
+
+	stats := ComputeRunStats(runs)
+	if stats.Runs != 5 {
+		t.Error("runs are wrong", stats.Runs)
+	}
+
+	if stats.PassRate != 0.5 {
+		t.Error("pass rate should only count the decided runs", stats.PassRate)
+	}
+
+	if stats := ComputeRunStats(runs[2:]); stats.PassRate != 0 {
+		t.Error("pass rate without decided runs should be zero", stats.PassRate)
+	}
+}
@@ -0,0 +1,157 @@
+package entities
+
+import (
+	"fmt"
+	"math"
+)
+
+// CurrentSchemaVersion is the version of the metadata document written by this library
+const CurrentSchemaVersion = 9
+
+// metadataUpgrade lifts a raw metadata document to the next schema version
+type metadataUpgrade func(document map[string]interface{}) error
+
+// metadataUpgrades maps each schema version to the function upgrading it to the next one
+var metadataUpgrades = map[int]metadataUpgrade{
+	1: upgradeMetadataV1,
+	2: upgradeMetadataV2,
+	3: upgradeMetadataV3,
+	4: upgradeMetadataV4,
+	5: upgradeMetadataV5,
+	6: upgradeMetadataV6,
+	7: upgradeMetadataV7,
+	8: upgradeMetadataV8,
+}
+
+// schemaVersionOf returns the schema version of a raw metadata document
+// Documents written before the versioning have no version and are treated as version 1
+func schemaVersionOf(document map[string]interface{}) (int, error) {
+	value, exists := document["schemaVersion"]
+	if !exists || value == nil {
+		return 1, nil
+	}
+
+	number, ok := value.(float64)
+	if !ok || number != math.Trunc(number) || number < 1 {
+		return 0, fmt.Errorf("Invalid schemaVersion: %v", value)
+	}
+
+	return int(number), nil
+}
+
+// upgradeMetadata lifts a raw metadata document to the current schema version
+func upgradeMetadata(document map[string]interface{}) error {
+	version, err := schemaVersionOf(document)
+	if err != nil {
+		return err
+	}
+
+	if version > CurrentSchemaVersion {
+		return fmt.Errorf("Metadata schema version %d is newer than the supported version %d", version, CurrentSchemaVersion)
+	}
+
+	for ; version < CurrentSchemaVersion; version++ {
+		upgrade, exists := metadataUpgrades[version]
+		if !exists {
+			return fmt.Errorf("No upgrade registered from metadata schema version %d", version)
+		}
+
+		if err := upgrade(document); err != nil {
+			return fmt.Errorf("Error upgrading metadata from schema version %d: %s", version, err.Error())
+		}
+
+		document["schemaVersion"] = version + 1
+	}
+
+	return nil
+}
+
+// upgradeMetadataV1 upgrades documents written before the schema was versioned
+// Their lists could be null
+func upgradeMetadataV1(document map[string]interface{}) error {
+	if document["revision"] == nil {
+		document["revision"] = 1
+	}
+
+	if document["artifacts"] == nil {
+		document["artifacts"] = []interface{}{}
+	}
+
+	if document["members"] == nil {
+		document["members"] = []interface{}{}
+	}
+
+	return nil
+}
+
+// upgradeMetadataV2 adds the last update date used to merge concurrent edits
+func upgradeMetadataV2(document map[string]interface{}) error {
+	if document["updatedAt"] == nil {
+		document["updatedAt"] = document["createdAt"]
+	}
+
+	return nil
+}
+
+// upgradeMetadataV3 adds the test cases and custom fields
+func upgradeMetadataV3(document map[string]interface{}) error {
+	if document["testCases"] == nil {
+		document["testCases"] = []interface{}{}
+	}
+
+	if document["customFields"] == nil {
+		document["customFields"] = map[string]interface{}{}
+	}
+
+	return nil
+}
+
+// upgradeMetadataV4 adds the unique ID of the test
+// Older tests named their IPNS key after the test name, so it becomes their ID
+func upgradeMetadataV4(document map[string]interface{}) error {
+	if document["id"] == nil {
+		document["id"] = document["name"]
+	}
+
+	return nil
+}
+
+// upgradeMetadataV5 adds the activity log
+func upgradeMetadataV5(document map[string]interface{}) error {
+	if document["activities"] == nil {
+		document["activities"] = []interface{}{}
+	}
+
+	return nil
+}
+
+// upgradeMetadataV6 adds the comments
+func upgradeMetadataV6(document map[string]interface{}) error {
+	if document["comments"] == nil {
+		document["comments"] = []interface{}{}
+	}
+
+	return nil
+}
+
+// upgradeMetadataV7 adds the required approvers and the approvals
+func upgradeMetadataV7(document map[string]interface{}) error {
+	if document["requiredApprovers"] == nil {
+		document["requiredApprovers"] = []interface{}{}
+	}
+
+	if document["approvals"] == nil {
+		document["approvals"] = []interface{}{}
+	}
+
+	return nil
+}
+
+// upgradeMetadataV8 adds the run history
+func upgradeMetadataV8(document map[string]interface{}) error {
+	if document["runs"] == nil {
+		document["runs"] = []interface{}{}
+	}
+
+	return nil
+}
@@ -0,0 +1,152 @@
+package entities
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestMetadataFromJSONUpgradesUnversionedDocument(t *testing.T) {
+	document := `{
+		"name": "TR0001",
+		"description": "Desc",
+		"createdAt": "2019-10-01T10:00:00Z",
+		"artifacts": null,
+		"members": [{"name": "John", "email": "john@tramonto.one", "role": "tester", "createAt": "2019-10-02T10:00:00Z"}]
+	}`
+
+	metadata, err := MetadataFromJSON([]byte(document))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if metadata.SchemaVersion != CurrentSchemaVersion {
+		t.Error("metadata schema version was not upgraded", metadata.SchemaVersion)
+	}
+
+	if metadata.Revision != 1 {
+		t.Error("metadata revision was not defaulted", metadata.Revision)
+	}
+
+	if metadata.Artifacts == nil {
+		t.Error("metadata artifacts should not be null")
+	}
+
+	if len(metadata.Members) != 1 || metadata.Members[0].CreatedAt.IsZero() {
+		t.Error("member createAt was not read", metadata.Members)
+	}
+}
+
+func TestMetadataFromJSONRejectsUnknownFields(t *testing.T) {
+	documents := map[string]string{
+		"unknown field":         `{"schemaVersion": 2, "name": "TR0001", "revision": 1, "artifacts": [], "members": [], "owner": "me"}`,
+		"unknown member field":  `{"schemaVersion": 2, "name": "TR0001", "revision": 1, "artifacts": [], "members": [{"name": "John", "email": "john@tramonto.one", "nickname": "J"}]}`,
+		"unknown current field": `{"schemaVersion": ` + strconv.Itoa(CurrentSchemaVersion) + `, "id": "id", "name": "TR0001", "revision": 1, "owner": "me"}`,
+		"newer schema version":  `{"schemaVersion": ` + strconv.Itoa(CurrentSchemaVersion+1) + `, "id": "id", "name": "TR0001", "revision": 1}`,
+	}
+
+	for name, document := range documents {
+		if _, err := MetadataFromJSON([]byte(document)); err == nil {
+			t.Errorf("document with %s should be rejected", name)
+		}
+	}
+}
+
+func TestMemberJSONKeepsCreateAt(t *testing.T) {
+	member, err := NewMember("John", "john@tramonto.one", "tester")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	memberJSON, err := json.Marshal(member)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(string(memberJSON), `"createAt"`) {
+		t.Error("member should be written with createAt", string(memberJSON))
+	}
+
+	decoded := Member{}
+	if err := json.Unmarshal([]byte(`{"name": "John", "email": "john@tramonto.one", "createdAt": "2019-10-02T10:00:00Z"}`), &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	if decoded.Name != "John" || decoded.CreatedAt.IsZero() {
+		t.Error("member createdAt should be accepted", decoded)
+	}
+}
+
+func TestMetadataFromJSONKeepsMembersWithoutEmail(t *testing.T) {
+	document := `{
+		"name": "TR0001",
+		"members": [{"name": "John", "email": "john", "role": "tester", "createAt": "2019-10-02T10:00:00Z"}]
+	}`
+
+	metadata, err := MetadataFromJSON([]byte(document))
+	if err != nil {
+		t.Fatal("documents written before the email check should load:", err)
+	}
+
+	if len(metadata.Members) != 1 || metadata.Members[0].Email != "john" {
+		t.Error("member was not kept", metadata.Members)
+	}
+}
+
+func TestMetadataFromJSONRejectsNewerSchema(t *testing.T) {
+	_, err := MetadataFromJSON([]byte(`{"schemaVersion": 999, "name": "TR0001"}`))
+	if err == nil || !strings.Contains(err.Error(), "newer") {
+		t.Error("newer schema version should be rejected", err)
+	}
+}
+
+func TestMetadataFromJSONRejectsMalformedDocuments(t *testing.T) {
+	documents := map[string]string{
+		"not an object":   `[]`,
+		"missing name":    `{"schemaVersion": 2, "revision": 1, "artifacts": [], "members": []}`,
+		"artifact hash":   `{"schemaVersion": 2, "name": "TR0001", "revision": 1, "artifacts": [{"name": "log"}], "members": []}`,
+		"member name":     `{"schemaVersion": 2, "name": "TR0001", "revision": 1, "artifacts": [], "members": [{"name": "", "email": "john@tramonto.one"}]}`,
+		"invalid version": `{"schemaVersion": "two", "name": "TR0001"}`,
+	}
+
+	for name, document := range documents {
+		if _, err := MetadataFromJSON([]byte(document)); err == nil {
+			t.Error("document should be rejected:", name)
+		}
+	}
+}
+
+func TestMetadataRoundTrip(t *testing.T) {
+	metadata, err := NewMetadata("TR0001", "Desc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	member, err := NewMember("John", "john@tramonto.one", "tester")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = metadata.AddMember(member); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = metadata.AddArtifact("log", "Crash log", "QmHash", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	metadataJSON, err := metadata.ToJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := MetadataFromJSON(metadataJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if decoded.Name != metadata.Name || len(decoded.Members) != 1 || len(decoded.Artifacts) != 1 {
+		t.Error("metadata was not decoded back", decoded)
+	}
+}
@@ -0,0 +1,64 @@
+package entities
+
+import (
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+// Kinds of the documents in the search index
+const (
+	SearchKindTest            = "test"
+	SearchKindArtifact        = "artifact"
+	SearchKindArtifactContent = "artifactContent"
+	SearchKindMember          = "member"
+	SearchKindComment         = "comment"
+)
+
+// maxIndexedContentSize limits the size of the text artifacts indexed
+const maxIndexedContentSize = 1 << 20
+
+// SearchResult represents a match of the full-text search
+type SearchResult struct {
+	TestIpns string  `json:"testIpns"`
+	TestName string  `json:"testName"`
+	Kind     string  `json:"kind"`
+	Target   string  `json:"target"`
+	Title    string  `json:"title"`
+	Snippet  string  `json:"snippet"`
+	Rank     float64 `json:"rank"`
+}
+
+// IndexableContent returns the text of an artifact to be indexed
+// Returns false when the artifact is not a text file
+func IndexableContent(headers map[string][]string, content []byte) (string, bool) {
+	if len(content) == 0 || len(content) > maxIndexedContentSize || !utf8.Valid(content) {
+		return "", false
+	}
+
+	contentType := http.DetectContentType(content)
+	if values := headers["Content-Type"]; len(values) > 0 && values[0] != "" {
+		contentType = values[0]
+	}
+
+	if !isTextContentType(contentType) {
+		return "", false
+	}
+
+	return string(content), true
+}
+
+// IsBinaryContent returns if the headers declare content that is not text
+// Its content is not indexed, so it does not need to be read
+func IsBinaryContent(headers map[string][]string) bool {
+	values := headers["Content-Type"]
+
+	return len(values) > 0 && values[0] != "" && !isTextContentType(values[0])
+}
+
+// isTextContentType returns if the content type is of text
+func isTextContentType(contentType string) bool {
+	return strings.HasPrefix(contentType, "text/") ||
+		strings.Contains(contentType, "json") ||
+		strings.Contains(contentType, "xml")
+}
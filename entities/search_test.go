@@ -0,0 +1,46 @@
+package entities
+
+import "testing"
+
+func TestIndexableContent(t *testing.T) {
+	content, ok := IndexableContent(map[string][]string{}, []byte("panic: crash on startup"))
+	if !ok || content != "panic: crash on startup" {
+		t.Error("plain text content should be indexed")
+	}
+
+	if _, ok = IndexableContent(map[string][]string{"Content-Type": {"application/json"}}, []byte(`{"level":"error"}`)); !ok {
+		t.Error("json content should be indexed")
+	}
+
+	if _, ok = IndexableContent(map[string][]string{"Content-Type": {"image/png"}}, []byte("not really an image")); ok {
+		t.Error("image content should not be indexed")
+	}
+
+	if _, ok = IndexableContent(map[string][]string{}, []byte{0xff, 0xfe, 0x00}); ok {
+		t.Error("binary content should not be indexed")
+	}
+
+	if _, ok = IndexableContent(map[string][]string{}, []byte{}); ok {
+		t.Error("empty content should not be indexed")
+	}
+}
+
+func TestIsBinaryContent(t *testing.T) {
+	cases := []struct {
+		headers map[string][]string
+		binary  bool
+	}{
+		{map[string][]string{"Content-Type": {"image/png"}}, true},
+		{map[string][]string{"Content-Type": {"video/mp4"}}, true},
+		{map[string][]string{"Content-Type": {"text/plain; charset=utf-8"}}, false},
+		{map[string][]string{"Content-Type": {"application/xml"}}, false},
+		{map[string][]string{"Content-Type": {""}}, false},
+		{map[string][]string{}, false},
+	}
+
+	for _, c := range cases {
+		if binary := IsBinaryContent(c.headers); binary != c.binary {
+			t.Errorf("IsBinaryContent(%v) = %v, want %v", c.headers, binary, c.binary)
+		}
+	}
+}
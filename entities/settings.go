@@ -0,0 +1,166 @@
+package entities
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// Keys of the settings
+const (
+	SettingCatTimeout        = "catTimeoutSeconds"
+	SettingIPNSTimeout       = "ipnsTimeoutSeconds"
+	SettingIPNSValidTime     = "ipnsValidHours"
+	SettingRepublishInterval = "republishIntervalHours"
+	SettingHTTPPort          = "httpPort"
+	SettingSyncInterval      = "syncIntervalSeconds"
+	SettingTestNamePrefix    = "testNamePrefix"
+	SettingDefaultMemberRole = "defaultMemberRole"
+)
+
+// Kinds of the values of the settings
+const (
+	SettingKindInt    = "int"
+	SettingKindString = "string"
+)
+
+// settingKinds has the kind of the value of each setting
+var settingKinds = map[string]string{
+	SettingCatTimeout:        SettingKindInt,
+	SettingIPNSTimeout:       SettingKindInt,
+	SettingIPNSValidTime:     SettingKindInt,
+	SettingRepublishInterval: SettingKindInt,
+	SettingHTTPPort:          SettingKindInt,
+	SettingSyncInterval:      SettingKindInt,
+	SettingTestNamePrefix:    SettingKindString,
+	SettingDefaultMemberRole: SettingKindString,
+}
+
+// namePrefixPattern validates the prefix of the generated test names
+var namePrefixPattern = regexp.MustCompile(`^[A-Za-z]{1,4}$`)
+
+// Settings represents the tunables of the node
+type Settings struct {
+	CatTimeoutSeconds      int    `json:"catTimeoutSeconds"`
+	IPNSTimeoutSeconds     int    `json:"ipnsTimeoutSeconds"`
+	IPNSValidHours         int    `json:"ipnsValidHours"`
+	RepublishIntervalHours int    `json:"republishIntervalHours"`
+	HTTPPort               int    `json:"httpPort"`
+	SyncIntervalSeconds    int    `json:"syncIntervalSeconds"`
+	TestNamePrefix         string `json:"testNamePrefix"`
+	DefaultMemberRole      string `json:"defaultMemberRole"`
+}
+
+// DefaultSettings returns the settings of a new node
+func DefaultSettings() Settings {
+	return Settings{
+		CatTimeoutSeconds:      60,
+		IPNSTimeoutSeconds:     180,
+		IPNSValidHours:         48,
+		RepublishIntervalHours: 12,
+		HTTPPort:               3000,
+		SyncIntervalSeconds:    30,
+		TestNamePrefix:         "TR",
+		DefaultMemberRole:      "",
+	}
+}
+
+// SettingKind returns the kind of the value of a setting
+// Returns false when the setting does not exist
+func SettingKind(key string) (string, bool) {
+	kind, ok := settingKinds[key]
+	return kind, ok
+}
+
+// SettingsFromValues returns the settings from the stored values
+// Missing settings keep their default value
+func SettingsFromValues(values map[string]string) (Settings, error) {
+	settings := DefaultSettings()
+
+	ints := map[string]*int{
+		SettingCatTimeout:        &settings.CatTimeoutSeconds,
+		SettingIPNSTimeout:       &settings.IPNSTimeoutSeconds,
+		SettingIPNSValidTime:     &settings.IPNSValidHours,
+		SettingRepublishInterval: &settings.RepublishIntervalHours,
+		SettingHTTPPort:          &settings.HTTPPort,
+		SettingSyncInterval:      &settings.SyncIntervalSeconds,
+	}
+
+	texts := map[string]*string{
+		SettingTestNamePrefix:    &settings.TestNamePrefix,
+		SettingDefaultMemberRole: &settings.DefaultMemberRole,
+	}
+
+	for key, value := range values {
+		if field, ok := ints[key]; ok {
+			intValue, err := strconv.Atoi(value)
+			if err != nil {
+				return Settings{}, fmt.Errorf("Invalid setting %s: %q is not a number", key, value)
+			}
+
+			*field = intValue
+			continue
+		}
+
+		if field, ok := texts[key]; ok {
+			*field = value
+			continue
+		}
+
+		return Settings{}, errors.New("Unknown setting " + key)
+	}
+
+	if err := settings.Validate(); err != nil {
+		return Settings{}, err
+	}
+
+	return settings, nil
+}
+
+// Values returns the settings as the values to be stored
+func (s Settings) Values() map[string]string {
+	return map[string]string{
+		SettingCatTimeout:        strconv.Itoa(s.CatTimeoutSeconds),
+		SettingIPNSTimeout:       strconv.Itoa(s.IPNSTimeoutSeconds),
+		SettingIPNSValidTime:     strconv.Itoa(s.IPNSValidHours),
+		SettingRepublishInterval: strconv.Itoa(s.RepublishIntervalHours),
+		SettingHTTPPort:          strconv.Itoa(s.HTTPPort),
+		SettingSyncInterval:      strconv.Itoa(s.SyncIntervalSeconds),
+		SettingTestNamePrefix:    s.TestNamePrefix,
+		SettingDefaultMemberRole: s.DefaultMemberRole,
+	}
+}
+
+// Validate verifies that the settings are in their ranges
+func (s Settings) Validate() error {
+	ranges := []struct {
+		key      string
+		value    int
+		min, max int
+	}{
+		{SettingCatTimeout, s.CatTimeoutSeconds, 1, 3600},
+		{SettingIPNSTimeout, s.IPNSTimeoutSeconds, 1, 3600},
+		{SettingIPNSValidTime, s.IPNSValidHours, 1, 8760},
+		{SettingRepublishInterval, s.RepublishIntervalHours, 1, 8760},
+		{SettingHTTPPort, s.HTTPPort, 1, 65535},
+		{SettingSyncInterval, s.SyncIntervalSeconds, 5, 86400},
+	}
+
+	for _, r := range ranges {
+		if r.value < r.min || r.value > r.max {
+			return fmt.Errorf("Invalid setting %s: must be between %d and %d, got %d", r.key, r.min, r.max, r.value)
+		}
+	}
+
+	// The records have to be published again before they expire
+	if s.RepublishIntervalHours >= s.IPNSValidHours {
+		return fmt.Errorf("Invalid setting %s: must be less than %s", SettingRepublishInterval, SettingIPNSValidTime)
+	}
+
+	if !namePrefixPattern.MatchString(s.TestNamePrefix) {
+		return errors.New("Invalid setting " + SettingTestNamePrefix + ": name prefix must have from 1 to 4 letters")
+	}
+
+	return nil
+}
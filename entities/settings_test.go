@@ -0,0 +1,46 @@
+package entities
+
+import "testing"
+
+func TestSettingsFromValues(t *testing.T) {
+	settings, err := SettingsFromValues(map[string]string{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if settings != DefaultSettings() {
+		t.Error("missing settings should have the default values")
+	}
+
+	settings, err = SettingsFromValues(map[string]string{
+		SettingHTTPPort:       "8080",
+		SettingTestNamePrefix: "BUG",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if settings.HTTPPort != 8080 || settings.TestNamePrefix != "BUG" || settings.CatTimeoutSeconds != 60 {
+		t.Errorf("unexpected settings %+v", settings)
+	}
+
+	parsed, err := SettingsFromValues(settings.Values())
+	if err != nil || parsed != settings {
+		t.Errorf("settings should survive a round trip, got %+v %v", parsed, err)
+	}
+
+	invalidValues := []map[string]string{
+		{SettingHTTPPort: "http"},
+		{SettingHTTPPort: "70000"},
+		{SettingCatTimeout: "0"},
+		{SettingRepublishInterval: "48"},
+		{SettingTestNamePrefix: "TOOLONG"},
+		{"unknown": "1"},
+	}
+
+	for _, values := range invalidValues {
+		if _, err := SettingsFromValues(values); err == nil {
+			t.Errorf("values %v should be invalid", values)
+		}
+	}
+}
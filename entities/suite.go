@@ -0,0 +1,132 @@
+package entities
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// Project represents a group of suites
+type Project struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// NewProject creates a new project
+func NewProject(name, description string) (Project, error) {
+	if strings.TrimSpace(name) == "" {
+		return Project{}, errors.New("Project name is required")
+	}
+
+	id, err := newID()
+	if err != nil {
+		return Project{}, err
+	}
+
+	return Project{
+		ID:          id,
+		Name:        name,
+		Description: description,
+		CreatedAt:   now(),
+	}, nil
+}
+
+// Suite represents a group of tests in a project
+type Suite struct {
+	ID          string      `json:"id"`
+	ProjectID   string      `json:"projectId,omitempty"`
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	CreatedAt   time.Time   `json:"createdAt"`
+	Tests       []SuiteTest `json:"tests"`
+
+	// Sharing informations of the suite
+	Ipfs    string `json:"ipfs,omitempty"`
+	Ipns    string `json:"ipns,omitempty"`
+	Secret  string `json:"secret,omitempty"`
+	IsOwner bool   `json:"isOwner"`
+}
+
+// SuiteTest represents a test in the shared suite document
+type SuiteTest struct {
+	Ipns   string `json:"ipns"`
+	Secret string `json:"secret"`
+	Name   string `json:"name"`
+}
+
+// NewSuite creates a new suite in a project
+func NewSuite(projectID, name, description string) (Suite, error) {
+	if strings.TrimSpace(name) == "" {
+		return Suite{}, errors.New("Suite name is required")
+	}
+
+	id, err := newID()
+	if err != nil {
+		return Suite{}, err
+	}
+
+	return Suite{
+		ID:          id,
+		ProjectID:   projectID,
+		Name:        name,
+		Description: description,
+		CreatedAt:   now(),
+		Tests:       []SuiteTest{},
+		IsOwner:     true,
+	}, nil
+}
+
+// SuiteDocument represents a suite shared in IPFS
+// The sharing informations, ownership and project are local to each device,
+// so they are not shared
+type SuiteDocument struct {
+	ID          string      `json:"id"`
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	CreatedAt   time.Time   `json:"createdAt"`
+	Tests       []SuiteTest `json:"tests"`
+}
+
+// Document returns the document of the suite shared in IPFS
+func (s Suite) Document() SuiteDocument {
+	return SuiteDocument{
+		ID:          s.ID,
+		Name:        s.Name,
+		Description: s.Description,
+		CreatedAt:   s.CreatedAt,
+		Tests:       s.Tests,
+	}
+}
+
+// ToJSON converts the document to JSON
+func (d SuiteDocument) ToJSON() ([]byte, error) {
+	return json.Marshal(d)
+}
+
+// SuiteFromDocumentJSON returns a suite from the JSON document shared in IPFS
+// The suite is not owned and not in any project of the device
+func SuiteFromDocumentJSON(documentJSON []byte) (Suite, error) {
+	var document SuiteDocument
+	if err := json.Unmarshal(documentJSON, &document); err != nil {
+		return Suite{}, errors.New("Invalid suite: " + err.Error())
+	}
+
+	if document.ID == "" || strings.TrimSpace(document.Name) == "" {
+		return Suite{}, errors.New("Invalid suite: id and name are required")
+	}
+
+	if document.Tests == nil {
+		document.Tests = []SuiteTest{}
+	}
+
+	return Suite{
+		ID:          document.ID,
+		Name:        document.Name,
+		Description: document.Description,
+		CreatedAt:   document.CreatedAt,
+		Tests:       document.Tests,
+	}, nil
+}
@@ -0,0 +1,55 @@
+package entities
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSuiteDocumentLeavesOutTheLocalInformations(t *testing.T) {
+	suite, err := NewSuite("project", "Release", "Smoke tests")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	suite.Ipfs = "QmIpfs"
+	suite.Ipns = "QmIpns"
+	suite.Secret = "secret"
+	suite.Tests = []SuiteTest{{Ipns: "QmTest", Secret: "test secret", Name: "TR0001"}}
+
+	documentJSON, err := suite.Document().ToJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(documentJSON, &fields); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, field := range []string{"projectId", "isOwner", "ipfs", "ipns", "secret"} {
+		if _, ok := fields[field]; ok {
+			t.Errorf("the document should not share %s", field)
+		}
+	}
+
+	shared, err := SuiteFromDocumentJSON(documentJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if shared.ID != suite.ID || shared.Name != suite.Name || len(shared.Tests) != 1 || shared.Tests[0].Secret != "test secret" {
+		t.Errorf("unexpected shared suite %+v", shared)
+	}
+}
+
+func TestSuiteFromDocumentJSONIgnoresTheLocalInformations(t *testing.T) {
+	// Documents were once uploaded with the whole suite
+	suite, err := SuiteFromDocumentJSON([]byte(`{"id": "1", "name": "Release", "projectId": "project", "isOwner": true, "secret": "secret"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if suite.ProjectID != "" || suite.IsOwner || suite.Secret != "" {
+		t.Errorf("the local informations should not be read, got %+v", suite)
+	}
+}
@@ -0,0 +1,19 @@
+package entities
+
+import "time"
+
+// Sources of the IPNS history entries
+const (
+	// SyncSourcePublish is a revision published by this node
+	SyncSourcePublish = "publish"
+
+	// SyncSourceResolve is a revision found resolving the IPNS
+	SyncSourceResolve = "resolve"
+)
+
+// SyncEntry represents a revision the IPNS of a test pointed to
+type SyncEntry struct {
+	IpfsHash  string    `json:"ipfsHash"`
+	Source    string    `json:"source"`
+	CreatedAt time.Time `json:"createdAt"`
+}
@@ -0,0 +1,124 @@
+package entities
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Template represents a reusable structure to create tests
+type Template struct {
+	ID           string            `json:"id"`
+	Name         string            `json:"name"`
+	Description  string            `json:"description"`
+	Members      []Member          `json:"members"`
+	TestCases    []TestCase        `json:"testCases"`
+	CustomFields map[string]string `json:"customFields"`
+	CreatedAt    time.Time         `json:"createdAt"`
+
+	// IPFS hash and secret when the template was shared
+	Ipfs   string `json:"ipfs,omitempty"`
+	Secret string `json:"secret,omitempty"`
+}
+
+// TemplateFromJSON returns a template from JSON
+// Templates without ID receive a new one and test cases without ID are identified
+// Members must be valid to be added to the tests created from the template
+func TemplateFromJSON(templateJSON []byte) (Template, error) {
+	var template Template
+	if err := json.Unmarshal(templateJSON, &template); err != nil {
+		return Template{}, errors.New("Invalid template: " + err.Error())
+	}
+
+	if strings.TrimSpace(template.Name) == "" {
+		return Template{}, errors.New("Invalid template: name is required")
+	}
+
+	if template.ID == "" {
+		id, err := newID()
+		if err != nil {
+			return Template{}, err
+		}
+
+		template.ID = id
+		template.CreatedAt = now()
+	}
+
+	if template.Members == nil {
+		template.Members = []Member{}
+	}
+
+	// Members are checked as when a test is created from the template
+	var members Metadata
+	for index, templateMember := range template.Members {
+		member, err := NewMember(templateMember.Name, templateMember.Email, templateMember.Role)
+		if err != nil {
+			return Template{}, fmt.Errorf("Invalid template: members[%d]: %s", index, err.Error())
+		}
+
+		if err := members.AddMember(member); err != nil {
+			return Template{}, fmt.Errorf("Invalid template: members[%d]: %s", index, err.Error())
+		}
+	}
+
+	if template.CustomFields == nil {
+		template.CustomFields = map[string]string{}
+	}
+
+	testCases := []TestCase{}
+	for _, testCase := range template.TestCases {
+		if testCase.ID == "" {
+			newTestCase, err := NewTestCase(testCase.Title, testCase.Description, testCase.Expected)
+			if err != nil {
+				return Template{}, errors.New("Invalid template: " + err.Error())
+			}
+
+			testCase = newTestCase
+		}
+
+		testCases = append(testCases, testCase)
+	}
+
+	template.TestCases = testCases
+
+	return template, nil
+}
+
+// ToJSON converts the template to a JSON
+func (t *Template) ToJSON() ([]byte, error) {
+	json, err := json.Marshal(t)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	return json, nil
+}
+
+// NewMetadata creates the metadata of a new test from the template
+func (t *Template) NewMetadata(name string) (Metadata, error) {
+	metadata, err := NewMetadata(name, t.Description)
+	if err != nil {
+		return Metadata{}, err
+	}
+
+	for _, templateMember := range t.Members {
+		member, err := NewMember(templateMember.Name, templateMember.Email, templateMember.Role)
+		if err != nil {
+			return Metadata{}, err
+		}
+
+		if err = metadata.AddMember(member); err != nil {
+			return Metadata{}, err
+		}
+	}
+
+	metadata.TestCases = append(metadata.TestCases, t.TestCases...)
+
+	for key, value := range t.CustomFields {
+		metadata.CustomFields[key] = value
+	}
+
+	return metadata, nil
+}
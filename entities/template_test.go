@@ -0,0 +1,53 @@
+package entities
+
+import "testing"
+
+func TestTemplateNewMetadata(t *testing.T) {
+	templateJSON := `{
+		"name": "Regression",
+		"description": "Sprint regression",
+		"members": [{"name": "John", "email": "john@tramonto.one", "role": "tester"}],
+		"testCases": [{"title": "Login"}, {"title": "Logout"}],
+		"customFields": {"platform": "android"}
+	}`
+
+	template, err := TemplateFromJSON([]byte(templateJSON))
+	if err != nil {
+		t.Error(err)
+	}
+
+	if template.ID == "" || template.TestCases[0].ID == "" {
+		t.Error("template ids were not generated")
+	}
+
+	metadata, err := template.NewMetadata("TR0001")
+	if err != nil {
+		t.Error(err)
+	}
+
+	if metadata.Name != "TR0001" || metadata.Description != "Sprint regression" {
+		t.Error("metadata fields are wrong", metadata)
+	}
+
+	if len(metadata.Members) != 1 || len(metadata.TestCases) != 2 || metadata.CustomFields["platform"] != "android" {
+		t.Error("metadata structure is wrong", metadata)
+	}
+
+	if err := metadata.Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestTemplateFromJSONValidatesMembers(t *testing.T) {
+	templates := map[string]string{
+		"member without name":  `{"name": "Regression", "members": [{"name": "", "email": "john@tramonto.one"}]}`,
+		"member invalid email": `{"name": "Regression", "members": [{"name": "John", "email": "john"}]}`,
+		"repeated member":      `{"name": "Regression", "members": [{"name": "John", "email": "john@tramonto.one"}, {"name": "john", "email": "John@tramonto.one"}]}`,
+	}
+
+	for name, templateJSON := range templates {
+		if _, err := TemplateFromJSON([]byte(templateJSON)); err == nil {
+			t.Errorf("template with %s should be rejected", name)
+		}
+	}
+}
@@ -1,5 +1,7 @@
 package entities
 
+import "time"
+
 // Test represents a test in the Tramonto One
 type Test struct {
 	// IPFS hash
@@ -14,9 +16,30 @@ type Test struct {
 	// If the current node is owner of the archieve
 	IsOwner bool `json:"isOwner"`
 
+	// If the user marked the test as favorite
+	IsFavorite bool `json:"isFavorite"`
+
 	// Secret to decrypt the files
 	Secret string `json:"secret"`
 
+	// Suite the test belongs to
+	SuiteID string `json:"suiteId,omitempty"`
+
+	// If the test has edits waiting to be published
+	PendingSync bool `json:"pendingSync"`
+
+	// When the test was last published or resolved
+	LastSyncedAt *time.Time `json:"lastSyncedAt"`
+
+	// Error of the last failed sync, empty when the last sync succeeded
+	LastSyncError string `json:"lastSyncError"`
+
+	// If the required approvers approved the current revision
+	Approved bool `json:"approved"`
+
+	// If an import found the test already in the node
+	AlreadyImported bool `json:"alreadyImported,omitempty"`
+
 	// Metadata informations
 	Metadata Metadata `json:"metadata,omitempty"`
 }
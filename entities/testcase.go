@@ -0,0 +1,33 @@
+package entities
+
+import (
+	"errors"
+	"strings"
+)
+
+// TestCase represents a case to be executed in a test
+type TestCase struct {
+	ID          string `json:"id"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Expected    string `json:"expected"`
+}
+
+// NewTestCase creates a new test case
+func NewTestCase(title, description, expected string) (TestCase, error) {
+	if strings.TrimSpace(title) == "" {
+		return TestCase{}, errors.New("Test case title is required")
+	}
+
+	id, err := newID()
+	if err != nil {
+		return TestCase{}, err
+	}
+
+	return TestCase{
+		ID:          id,
+		Title:       title,
+		Description: description,
+		Expected:    expected,
+	}, nil
+}
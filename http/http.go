@@ -1,6 +1,7 @@
 package http
 
 import (
+	"fmt"
 	"io/ioutil"
 	"net/http"
 	"sync"
@@ -10,11 +11,12 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-const httpPort = ":3000"
+const defaultHTTPPort = 3000
 
 // OneHTTP represents a HTTP server to Tramonto One
 type OneHTTP struct {
 	server *gin.Engine
+	port   int
 	mux    *sync.Mutex
 }
 
@@ -27,12 +29,21 @@ func InitializeHTTPServer() (*OneHTTP, error) {
 
 	http := &OneHTTP{
 		server: r,
+		port:   defaultHTTPPort,
 		mux:    new(sync.Mutex),
 	}
 
 	return http, nil
 }
 
+// SetPort configures the port the server listens on when started
+func (h *OneHTTP) SetPort(port int) {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+
+	h.port = port
+}
+
 // Start starts the server
 func (h *OneHTTP) Start() error {
 	h.mux.Lock()
@@ -42,7 +53,7 @@ func (h *OneHTTP) Start() error {
 		c.String(http.StatusOK, "pong")
 	})
 
-	if err := h.server.Run(httpPort); err != nil {
+	if err := h.server.Run(fmt.Sprintf(":%d", h.port)); err != nil {
 		return err
 	}
 
@@ -0,0 +1,64 @@
+package ipfs
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// Files of the repo with the identity and the IPNS keys of the node
+var repoKeyFiles = []string{"config", "version", "datastore_spec", "keystore"}
+
+// Files of the repo of a running node that are not backed up
+var repoRuntimeFiles = map[string]bool{"repo.lock": true, "api": true}
+
+// RepoPath returns the path of the IPFS repo in the Tramonto One path
+func RepoPath(path string) string {
+	return filepath.Join(path, ".ipfs")
+}
+
+// BackupFiles lists the files of the repo to back up, relative to the repo
+// The keys are always listed, the blocks and the pins only when includeBlocks
+func BackupFiles(repoPath string, includeBlocks bool) ([]string, error) {
+	roots := repoKeyFiles
+	if includeBlocks {
+		roots = []string{"."}
+	}
+
+	files := []string{}
+
+	for _, root := range roots {
+		err := filepath.Walk(filepath.Join(repoPath, root), func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			relativePath, err := filepath.Rel(repoPath, path)
+			if err != nil {
+				return err
+			}
+
+			if info.Mode().IsRegular() && !repoRuntimeFiles[relativePath] {
+				files = append(files, filepath.ToSlash(relativePath))
+			}
+
+			return nil
+		})
+
+		// Repos of older versions may miss some files
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+
+	return files, nil
+}
+
+// RestoredFiles returns the files of the repo replaced by a backup, relative to the repo
+// The whole repo is replaced when the backup has the blocks
+func RestoredFiles(includesBlocks bool) []string {
+	if includesBlocks {
+		return []string{"."}
+	}
+
+	return repoKeyFiles
+}
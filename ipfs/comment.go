@@ -0,0 +1,70 @@
+package ipfs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	ifacePath "github.com/ipfs/interface-go-ipfs-core/path"
+
+	oneCrypto "gitlab.com/tramonto-one/go-tramonto/crypto"
+	"gitlab.com/tramonto-one/go-tramonto/entities"
+)
+
+// UploadComment uploads a comment to IPFS
+// Returns the IPFS hash
+func (oneIpfs *OneIPFS) UploadComment(comment entities.Comment, secret string) (string, error) {
+	oneIpfs.mux.Lock()
+	defer oneIpfs.mux.Unlock()
+
+	// Verifies if node is running
+	if running := oneIpfs.isNodeRunning(); !running {
+		return "", errors.New("Node is not running")
+	}
+
+	jsonRepresentation, err := json.Marshal(comment)
+	if err != nil {
+		return "", errors.New("Error converting comment to json: " + err.Error())
+	}
+
+	encryptedData, err := oneCrypto.EncryptConfigFile(secret, jsonRepresentation)
+	if err != nil {
+		return "", errors.New("Error encrypting data: " + err.Error())
+	}
+
+	// Uploads json to IPFS
+	ipfsCid, err := addContent(oneIpfs.node, encryptedData, true)
+	if err != nil {
+		return "", errors.New("Error adding content: " + err.Error())
+	}
+
+	return ipfsCid.Hash().B58String(), nil
+}
+
+// ReadComment reads a comment from IPFS
+func (oneIpfs *OneIPFS) ReadComment(hash, secret string) (entities.Comment, error) {
+	oneIpfs.mux.Lock()
+	defer oneIpfs.mux.Unlock()
+
+	// Parses IPFS hash to Path
+	ipfsHashPath := fmt.Sprintf("/ipfs/%s", hash)
+	ipfsPath := ifacePath.New(ipfsHashPath)
+
+	// Reads content
+	content, err := readContent(oneIpfs.node, ipfsPath, oneIpfs.config.CatTimeout)
+	if err != nil {
+		return entities.Comment{}, errors.New("Error reading content: " + err.Error())
+	}
+
+	decryptedData, err := oneCrypto.DecryptConfigFile(secret, content)
+	if err != nil {
+		return entities.Comment{}, errors.New("Error decrypting data: " + err.Error())
+	}
+
+	var comment entities.Comment
+	if err := json.Unmarshal(decryptedData, &comment); err != nil {
+		return entities.Comment{}, errors.New("Error parsing to json: " + err.Error())
+	}
+
+	return comment, nil
+}
@@ -0,0 +1,32 @@
+package ipfs
+
+import "time"
+
+// Config represents the timeouts of the IPFS operations
+type Config struct {
+	// CatTimeout limits the time reading a content
+	CatTimeout time.Duration
+
+	// IPNSTimeout limits the time publishing and resolving a name
+	IPNSTimeout time.Duration
+
+	// IPNSValidTime is how long a published record is valid
+	IPNSValidTime time.Duration
+}
+
+// DefaultConfig returns the configuration used when none is given
+func DefaultConfig() Config {
+	return Config{
+		CatTimeout:    time.Minute,
+		IPNSTimeout:   time.Minute * 3,
+		IPNSValidTime: time.Hour * 48,
+	}
+}
+
+// Configure replaces the configuration of the operations
+func (oneIpfs *OneIPFS) Configure(config Config) {
+	oneIpfs.mux.Lock()
+	defer oneIpfs.mux.Unlock()
+
+	oneIpfs.config = config
+}
@@ -11,12 +11,15 @@ import (
 	files "github.com/ipfs/go-ipfs-files"
 	"github.com/ipfs/go-ipfs/core"
 	"github.com/ipfs/go-ipfs/core/coreapi"
+	"github.com/ipfs/go-ipfs/core/corerepo"
+	ipfsPin "github.com/ipfs/go-ipfs/pin"
 	"github.com/ipfs/interface-go-ipfs-core/options"
 	ifacePath "github.com/ipfs/interface-go-ipfs-core/path"
 	oneCrypto "gitlab.com/tramonto-one/go-tramonto/crypto"
 )
 
-const catTimeout = time.Minute
+// ErrNotPinned is returned when unpinning a content that is not pinned
+var ErrNotPinned = errors.New("Content is not pinned")
 
 // addContent adds a buffer to IPFS
 func addContent(node *core.IpfsNode, content []byte, pin bool) (cid.Cid, error) {
@@ -47,13 +50,13 @@ func addContent(node *core.IpfsNode, content []byte, pin bool) (cid.Cid, error)
 }
 
 // readContent reads the content in a hash
-func readContent(node *core.IpfsNode, path ifacePath.Path) ([]byte, error) {
+func readContent(node *core.IpfsNode, path ifacePath.Path, timeout time.Duration) ([]byte, error) {
 	api, err := coreapi.NewCoreAPI(node)
 	if err != nil {
 		return []byte{}, err
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), catTimeout)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
 	// Gets the content of the file
@@ -106,6 +109,57 @@ func pin(node *core.IpfsNode, path ifacePath.Path) error {
 	return nil
 }
 
+// unpin will remove the pin of the ipfs in the node
+func unpin(node *core.IpfsNode, path ifacePath.Path) error {
+	api, err := coreapi.NewCoreAPI(node)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	options := []options.PinRmOption{
+		options.Pin.RmRecursive(true),
+	}
+
+	// Unpins the item
+	if err = api.Pin().Rm(ctx, path, options...); err != nil {
+		if err == ipfsPin.ErrNotPinned {
+			return ErrNotPinned
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// Unpin removes the pin of an IPFS hash, allowing its blocks to be collected
+// Returns ErrNotPinned when the hash is not pinned
+func (oneIpfs *OneIPFS) Unpin(ipfsHash string) error {
+	oneIpfs.mux.Lock()
+	defer oneIpfs.mux.Unlock()
+
+	// Parses IPFS hash to Path
+	ipfsPath := ifacePath.New(fmt.Sprintf("/ipfs/%s", ipfsHash))
+
+	return unpin(oneIpfs.node, ipfsPath)
+}
+
+// CollectGarbage removes the blocks not pinned from the repo
+func (oneIpfs *OneIPFS) CollectGarbage() error {
+	oneIpfs.mux.Lock()
+	defer oneIpfs.mux.Unlock()
+
+	// Verifies if node is running
+	if running := oneIpfs.isNodeRunning(); !running {
+		return errors.New("Node is not running")
+	}
+
+	return corerepo.GarbageCollect(oneIpfs.node, context.Background())
+}
+
 // ReadArtifact will read the artifact of the specific hash
 func (oneIpfs *OneIPFS) ReadArtifact(ipfsHash, secret string) ([]byte, error) {
 	oneIpfs.mux.Lock()
@@ -116,7 +170,7 @@ func (oneIpfs *OneIPFS) ReadArtifact(ipfsHash, secret string) ([]byte, error) {
 	ipfsPath := ifacePath.New(ipfsHashPath)
 
 	// Reads the content of the IPFS hash
-	content, err := readContent(oneIpfs.node, ipfsPath)
+	content, err := readContent(oneIpfs.node, ipfsPath, oneIpfs.config.CatTimeout)
 	if err != nil {
 		return nil, err
 	}
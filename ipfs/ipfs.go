@@ -3,25 +3,27 @@ package ipfs
 import (
 	"context"
 	"errors"
-	"path/filepath"
 	"sync"
 
 	"github.com/ipfs/go-ipfs/core"
+	"github.com/ipfs/go-ipfs/core/coreapi"
 )
 
 // OneIPFS represents the IPFS repo to Tramonto One
 type OneIPFS struct {
 	repoPath string
 	node     *core.IpfsNode
+	config   Config
 	mux      *sync.Mutex
 }
 
 // InitializeOneIPFS initializes the One IPFS
 func InitializeOneIPFS(path string) (*OneIPFS, error) {
-	repoPath := filepath.Join(path, ".ipfs")
+	repoPath := RepoPath(path)
 
 	one := &OneIPFS{
 		repoPath: repoPath,
+		config:   DefaultConfig(),
 		mux:      new(sync.Mutex),
 	}
 
@@ -110,3 +112,28 @@ func (t *OneIPFS) Stop() error {
 
 	return nil
 }
+
+// IsOnline returns if the node is running and connected to other peers
+func (t *OneIPFS) IsOnline() bool {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+
+	if running := t.isNodeRunning(); !running {
+		return false
+	}
+
+	api, err := coreapi.NewCoreAPI(t.node)
+	if err != nil {
+		return false
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	peers, err := api.Swarm().Peers(ctx)
+	if err != nil {
+		return false
+	}
+
+	return len(peers) > 0
+}
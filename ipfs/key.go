@@ -2,11 +2,14 @@ package ipfs
 
 import (
 	"context"
+	"errors"
 
 	"github.com/ipfs/go-ipfs/core"
 	"github.com/ipfs/go-ipfs/core/coreapi"
 	iface "github.com/ipfs/interface-go-ipfs-core"
 	"github.com/ipfs/interface-go-ipfs-core/options"
+	"github.com/libp2p/go-libp2p-core/crypto"
+	"github.com/libp2p/go-libp2p-core/peer"
 )
 
 // genKey generates a new IPNS key (RSA-2048 default)
@@ -83,3 +86,137 @@ func (t *OneIPFS) GetKeyWithName(name string) (bool, string, error) {
 
 	return true, key.ID().Pretty(), nil
 }
+
+// ExportKey returns the private key with the given name, marshaled
+// The key is not encrypted, so it must not leave the device as is
+func (t *OneIPFS) ExportKey(name string) ([]byte, error) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+
+	// Verifies if node is running
+	if running := t.isNodeRunning(); !running {
+		return nil, errors.New("Node is not running")
+	}
+
+	privateKey, err := t.node.Repo.Keystore().Get(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return crypto.MarshalPrivateKey(privateKey)
+}
+
+// ImportKey stores a marshaled private key with the given name
+// Importing the same key again does nothing
+// Returns the IPNS hash of the key
+func (t *OneIPFS) ImportKey(name string, marshaledKey []byte) (string, error) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+
+	// Verifies if node is running
+	if running := t.isNodeRunning(); !running {
+		return "", errors.New("Node is not running")
+	}
+
+	privateKey, err := crypto.UnmarshalPrivateKey(marshaledKey)
+	if err != nil {
+		return "", errors.New("Invalid key: " + err.Error())
+	}
+
+	id, err := peer.IDFromPrivateKey(privateKey)
+	if err != nil {
+		return "", errors.New("Invalid key: " + err.Error())
+	}
+
+	keystore := t.node.Repo.Keystore()
+
+	exists, err := keystore.Has(name)
+	if err != nil {
+		return "", err
+	}
+
+	if exists {
+		existingKey, err := keystore.Get(name)
+		if err != nil {
+			return "", err
+		}
+
+		existingID, err := peer.IDFromPrivateKey(existingKey)
+		if err != nil {
+			return "", err
+		}
+
+		if existingID != id {
+			return "", errors.New("Another key named " + name + " exists")
+		}
+
+		return id.Pretty(), nil
+	}
+
+	if err = keystore.Put(name, privateKey); err != nil {
+		return "", err
+	}
+
+	return id.Pretty(), nil
+}
+
+// GetNodeID returns the peer ID of the node
+func (t *OneIPFS) GetNodeID() (string, error) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+
+	// Verifies if node is running
+	if running := t.isNodeRunning(); !running {
+		return "", errors.New("Node is not running")
+	}
+
+	api, err := coreapi.NewCoreAPI(t.node)
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	key, err := api.Key().Self(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return key.ID().Pretty(), nil
+}
+
+// RemoveKey removes the IPNS key with the given name
+// Does nothing when the key does not exist
+func (t *OneIPFS) RemoveKey(name string) error {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+
+	// Verifies if node is running
+	if running := t.isNodeRunning(); !running {
+		return errors.New("Node is not running")
+	}
+
+	key, err := keyWithName(t.node, name)
+	if err != nil {
+		return err
+	}
+
+	if key == nil {
+		return nil
+	}
+
+	api, err := coreapi.NewCoreAPI(t.node)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if _, err = api.Key().Remove(ctx, name); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,465 @@
+package ipfs
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sync"
+
+	oneCrypto "gitlab.com/tramonto-one/go-tramonto/crypto"
+	"gitlab.com/tramonto-one/go-tramonto/entities"
+)
+
+// MemoryNetwork is an in-memory IPFS network shared by memory stores
+// It keeps the contents by their hash and the IPNS names
+type MemoryNetwork struct {
+	contents map[string][]byte
+	names    map[string]string
+	mux      *sync.Mutex
+}
+
+// NewMemoryNetwork creates a new empty in-memory network
+func NewMemoryNetwork() *MemoryNetwork {
+	return &MemoryNetwork{
+		contents: map[string][]byte{},
+		names:    map[string]string{},
+		mux:      new(sync.Mutex),
+	}
+}
+
+// MemoryStore is an in-memory content store, working without a go-ipfs node
+// Stores connected to the same network see the contents and names of each other
+type MemoryStore struct {
+	network *MemoryNetwork
+	nodeID  string
+	keys    map[string]string
+	pins    map[string]bool
+	config  Config
+	running bool
+	online  bool
+	mux     *sync.Mutex
+}
+
+// MemoryStore stores the contents in memory
+var _ ContentStore = (*MemoryStore)(nil)
+
+// NewMemoryStore creates a new in-memory store connected to the network
+func NewMemoryStore(network *MemoryNetwork) (*MemoryStore, error) {
+	nodeID, err := randomMemoryID()
+	if err != nil {
+		return nil, err
+	}
+
+	return &MemoryStore{
+		network: network,
+		nodeID:  nodeID,
+		keys:    map[string]string{},
+		pins:    map[string]bool{},
+		config:  DefaultConfig(),
+		online:  true,
+		mux:     new(sync.Mutex),
+	}, nil
+}
+
+// randomMemoryID generates a random identifier of nodes and keys
+func randomMemoryID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(id), nil
+}
+
+// SetOnline connects or disconnects the store from the network
+// While offline the IPNS names cannot be published or resolved
+func (m *MemoryStore) SetOnline(online bool) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	m.online = online
+}
+
+// InitRepo does nothing, the memory store has no repo
+func (m *MemoryStore) InitRepo() error {
+	return nil
+}
+
+// Start starts the store
+func (m *MemoryStore) Start() error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	if m.running {
+		return errors.New("Node is already running")
+	}
+
+	m.running = true
+
+	return nil
+}
+
+// Stop stops the store
+func (m *MemoryStore) Stop() error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	if !m.running {
+		return errors.New("Node not running")
+	}
+
+	m.running = false
+
+	return nil
+}
+
+// Configure stores the configuration, the memory store has no timeouts
+func (m *MemoryStore) Configure(config Config) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	m.config = config
+}
+
+// IsOnline returns if the store is running and connected to the network
+func (m *MemoryStore) IsOnline() bool {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	return m.running && m.online
+}
+
+// add stores a content and pins it, returns its hash
+func (m *MemoryStore) add(content []byte) (string, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	if !m.running {
+		return "", errors.New("Node is not running")
+	}
+
+	sum := sha256.Sum256(content)
+	hash := hex.EncodeToString(sum[:])
+
+	m.network.mux.Lock()
+	m.network.contents[hash] = content
+	m.network.mux.Unlock()
+
+	m.pins[hash] = true
+
+	return hash, nil
+}
+
+// read returns the content of a hash
+func (m *MemoryStore) read(hash string) ([]byte, error) {
+	m.network.mux.Lock()
+	defer m.network.mux.Unlock()
+
+	content, ok := m.network.contents[hash]
+	if !ok {
+		return nil, errors.New("Content not found: " + hash)
+	}
+
+	return content, nil
+}
+
+// addEncrypted encrypts a config file and stores it
+func (m *MemoryStore) addEncrypted(data []byte, secret string) (string, error) {
+	encryptedData, err := oneCrypto.EncryptConfigFile(secret, data)
+	if err != nil {
+		return "", errors.New("Error encrypting data: " + err.Error())
+	}
+
+	return m.add(encryptedData)
+}
+
+// readDecrypted reads a config file and decrypts it
+func (m *MemoryStore) readDecrypted(hash, secret string) ([]byte, error) {
+	content, err := m.read(hash)
+	if err != nil {
+		return nil, errors.New("Error reading content: " + err.Error())
+	}
+
+	decryptedData, err := oneCrypto.DecryptConfigFile(secret, content)
+	if err != nil {
+		return nil, errors.New("Error decrypting data: " + err.Error())
+	}
+
+	return decryptedData, nil
+}
+
+// UploadTest uploads a test to the memory
+func (m *MemoryStore) UploadTest(metadata entities.Metadata, secret string) (string, error) {
+	jsonRepresentation, err := metadata.ToJSON()
+	if err != nil {
+		return "", errors.New("Erro converting metadata to json: " + err.Error())
+	}
+
+	return m.addEncrypted(jsonRepresentation, secret)
+}
+
+// GetTestByIPFS returns a test metadata by IPFS
+func (m *MemoryStore) GetTestByIPFS(hash, secret string) (entities.Metadata, error) {
+	decryptedData, err := m.readDecrypted(hash, secret)
+	if err != nil {
+		return entities.Metadata{}, err
+	}
+
+	metadata, err := entities.MetadataFromJSON(decryptedData)
+	if err != nil {
+		return entities.Metadata{}, errors.New("Error parsing metadata: " + err.Error())
+	}
+
+	return metadata, nil
+}
+
+// GetTestByIPNS returns a test metadata by IPNS
+func (m *MemoryStore) GetTestByIPNS(hash, secret string) (string, entities.Metadata, error) {
+	ipfsHash, err := m.ResolveIPNS(hash)
+	if err != nil {
+		return "", entities.Metadata{}, err
+	}
+
+	metadata, err := m.GetTestByIPFS(ipfsHash, secret)
+	if err != nil {
+		return "", metadata, err
+	}
+
+	return ipfsHash, metadata, nil
+}
+
+// UploadArtifact uploads an artifact to the memory
+func (m *MemoryStore) UploadArtifact(content []byte, secret string) (string, error) {
+	encryptedContent, err := oneCrypto.EncryptArtifact(secret, content)
+	if err != nil {
+		return "", errors.New("Could not encrypt artifact: " + err.Error())
+	}
+
+	return m.add(encryptedContent)
+}
+
+// ReadArtifact reads the artifact of the hash
+func (m *MemoryStore) ReadArtifact(ipfsHash, secret string) ([]byte, error) {
+	content, err := m.read(ipfsHash)
+	if err != nil {
+		return nil, err
+	}
+
+	return oneCrypto.DecryptArtifact(secret, content)
+}
+
+// UploadComment uploads a comment to the memory
+func (m *MemoryStore) UploadComment(comment entities.Comment, secret string) (string, error) {
+	jsonRepresentation, err := json.Marshal(comment)
+	if err != nil {
+		return "", errors.New("Error converting comment to json: " + err.Error())
+	}
+
+	return m.addEncrypted(jsonRepresentation, secret)
+}
+
+// ReadComment reads the comment of the hash
+func (m *MemoryStore) ReadComment(hash, secret string) (entities.Comment, error) {
+	decryptedData, err := m.readDecrypted(hash, secret)
+	if err != nil {
+		return entities.Comment{}, err
+	}
+
+	var comment entities.Comment
+	if err := json.Unmarshal(decryptedData, &comment); err != nil {
+		return entities.Comment{}, errors.New("Error parsing comment: " + err.Error())
+	}
+
+	return comment, nil
+}
+
+// UploadTemplate uploads a template to the memory
+func (m *MemoryStore) UploadTemplate(template entities.Template, secret string) (string, error) {
+	jsonRepresentation, err := template.ToJSON()
+	if err != nil {
+		return "", errors.New("Error converting template to json: " + err.Error())
+	}
+
+	return m.addEncrypted(jsonRepresentation, secret)
+}
+
+// GetTemplateByIPFS returns a template by its hash
+func (m *MemoryStore) GetTemplateByIPFS(hash, secret string) (entities.Template, error) {
+	decryptedData, err := m.readDecrypted(hash, secret)
+	if err != nil {
+		return entities.Template{}, err
+	}
+
+	template, err := entities.TemplateFromJSON(decryptedData)
+	if err != nil {
+		return entities.Template{}, errors.New("Error parsing template: " + err.Error())
+	}
+
+	return template, nil
+}
+
+// UploadSuite uploads the document of a suite to the memory
+func (m *MemoryStore) UploadSuite(document entities.SuiteDocument, secret string) (string, error) {
+	jsonRepresentation, err := document.ToJSON()
+	if err != nil {
+		return "", errors.New("Error converting suite to json: " + err.Error())
+	}
+
+	return m.addEncrypted(jsonRepresentation, secret)
+}
+
+// GetSuiteByIPNS returns the document of a suite by IPNS
+func (m *MemoryStore) GetSuiteByIPNS(hash, secret string) (string, entities.Suite, error) {
+	ipfsHash, err := m.ResolveIPNS(hash)
+	if err != nil {
+		return "", entities.Suite{}, err
+	}
+
+	decryptedData, err := m.readDecrypted(ipfsHash, secret)
+	if err != nil {
+		return "", entities.Suite{}, err
+	}
+
+	suite, err := entities.SuiteFromDocumentJSON(decryptedData)
+	if err != nil {
+		return "", entities.Suite{}, errors.New("Error parsing suite: " + err.Error())
+	}
+
+	return ipfsHash, suite, nil
+}
+
+// Unpin removes the pin of a hash
+// Contents are kept in the network, as other stores may have pinned them
+func (m *MemoryStore) Unpin(ipfsHash string) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	if !m.pins[ipfsHash] {
+		return ErrNotPinned
+	}
+
+	delete(m.pins, ipfsHash)
+
+	return nil
+}
+
+// CollectGarbage does nothing, the contents are shared by the stores of the network
+func (m *MemoryStore) CollectGarbage() error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	if !m.running {
+		return errors.New("Node not running")
+	}
+
+	return nil
+}
+
+// ResolveIPNS returns the hash an IPNS name points to
+func (m *MemoryStore) ResolveIPNS(hash string) (string, error) {
+	if !m.IsOnline() {
+		return "", errors.New("Error resolving IPNS: node is offline")
+	}
+
+	m.network.mux.Lock()
+	defer m.network.mux.Unlock()
+
+	ipfsHash, ok := m.network.names[hash]
+	if !ok {
+		return "", errors.New("Error resolving IPNS: name not found")
+	}
+
+	return ipfsHash, nil
+}
+
+// PublishToIPNS publishes a hash with the named key, generating it when needed
+func (m *MemoryStore) PublishToIPNS(ipfsHash, keyName string) (string, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	ipnsHash, keyExists := m.keys[keyName]
+	if !keyExists {
+		newKey, err := randomMemoryID()
+		if err != nil {
+			return "", errors.New("Error generating key: " + err.Error())
+		}
+
+		ipnsHash = newKey
+		m.keys[keyName] = ipnsHash
+	}
+
+	if !m.running || !m.online {
+		return ipnsHash, errors.New("Error publishing IPNS: node is offline")
+	}
+
+	m.network.mux.Lock()
+	defer m.network.mux.Unlock()
+
+	if _, ok := m.network.contents[ipfsHash]; !ok {
+		return ipnsHash, errors.New("Error publishing IPNS: content not found")
+	}
+
+	m.network.names[ipnsHash] = ipfsHash
+
+	return ipnsHash, nil
+}
+
+// GetKeyWithName returns if the named key exists and its IPNS hash
+func (m *MemoryStore) GetKeyWithName(name string) (bool, string, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	ipnsHash, ok := m.keys[name]
+
+	return ok, ipnsHash, nil
+}
+
+// RemoveKey removes the named key
+func (m *MemoryStore) RemoveKey(name string) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	delete(m.keys, name)
+
+	return nil
+}
+
+// ExportKey returns the named key, in memory it is the IPNS hash itself
+func (m *MemoryStore) ExportKey(name string) ([]byte, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	ipnsHash, ok := m.keys[name]
+	if !ok {
+		return nil, errors.New("Key not found: " + name)
+	}
+
+	return []byte(ipnsHash), nil
+}
+
+// ImportKey stores a key exported by another memory store
+// Returns the IPNS hash of the key
+func (m *MemoryStore) ImportKey(name string, marshaledKey []byte) (string, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	ipnsHash := string(marshaledKey)
+	if ipnsHash == "" {
+		return "", errors.New("Invalid key")
+	}
+
+	if existing, ok := m.keys[name]; ok && existing != ipnsHash {
+		return "", errors.New("Another key named " + name + " exists")
+	}
+
+	m.keys[name] = ipnsHash
+
+	return ipnsHash, nil
+}
+
+// GetNodeID returns the ID of the store
+func (m *MemoryStore) GetNodeID() (string, error) {
+	return m.nodeID, nil
+}
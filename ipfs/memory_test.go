@@ -0,0 +1,173 @@
+package ipfs
+
+import (
+	"testing"
+
+	"gitlab.com/tramonto-one/go-tramonto/entities"
+)
+
+// startMemoryStore starts a memory store connected to the network
+func startMemoryStore(t *testing.T, network *MemoryNetwork) *MemoryStore {
+	t.Helper()
+
+	store, err := NewMemoryStore(network)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	return store
+}
+
+func TestMemoryStoreSharesContents(t *testing.T) {
+	network := NewMemoryNetwork()
+	owner := startMemoryStore(t, network)
+	reader := startMemoryStore(t, network)
+
+	metadata, err := entities.NewMetadata("TR0001", "Login crash")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ipfsHash, err := owner.UploadTest(metadata, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ipnsHash, err := owner.PublishToIPNS(ipfsHash, metadata.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resolvedHash, resolved, err := reader.GetTestByIPNS(ipnsHash, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resolvedHash != ipfsHash || resolved.Name != "TR0001" || resolved.Description != "Login crash" {
+		t.Errorf("unexpected test %s %+v", resolvedHash, resolved)
+	}
+
+	if _, err := reader.GetTestByIPFS(ipfsHash, "wrong"); err == nil {
+		t.Error("a test should not be read with a wrong secret")
+	}
+
+	artifactHash, err := owner.UploadArtifact([]byte("NullPointerException"), "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := reader.ReadArtifact(artifactHash, "secret")
+	if err != nil || string(content) != "NullPointerException" {
+		t.Errorf("unexpected artifact %q %v", content, err)
+	}
+}
+
+func TestMemoryStorePublishesOnlyOnline(t *testing.T) {
+	network := NewMemoryNetwork()
+	store := startMemoryStore(t, network)
+
+	ipfsHash, err := store.UploadArtifact([]byte("log"), "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store.SetOnline(false)
+
+	if store.IsOnline() {
+		t.Error("a disconnected store should not be online")
+	}
+
+	// The key is generated even when the name cannot be published
+	ipnsHash, err := store.PublishToIPNS(ipfsHash, "key")
+	if err == nil {
+		t.Error("an offline store should not publish")
+	}
+
+	if exists, keyHash, _ := store.GetKeyWithName("key"); !exists || keyHash != ipnsHash {
+		t.Errorf("expected the key %s to exist, got %v %s", ipnsHash, exists, keyHash)
+	}
+
+	if _, err := store.ResolveIPNS(ipnsHash); err == nil {
+		t.Error("an offline store should not resolve")
+	}
+
+	store.SetOnline(true)
+
+	if _, err := store.PublishToIPNS(ipfsHash, "key"); err != nil {
+		t.Fatal(err)
+	}
+
+	if resolved, err := store.ResolveIPNS(ipnsHash); err != nil || resolved != ipfsHash {
+		t.Errorf("expected %s, got %s %v", ipfsHash, resolved, err)
+	}
+}
+
+func TestMemoryStoreUnpins(t *testing.T) {
+	network := NewMemoryNetwork()
+	store := startMemoryStore(t, network)
+	other := startMemoryStore(t, network)
+
+	ipfsHash, err := store.UploadArtifact([]byte("log"), "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := other.Unpin(ipfsHash); err != ErrNotPinned {
+		t.Errorf("contents of other stores are not pinned, got %v", err)
+	}
+
+	if err := store.Unpin(ipfsHash); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Unpin(ipfsHash); err != ErrNotPinned {
+		t.Errorf("expected ErrNotPinned, got %v", err)
+	}
+}
+
+func TestMemoryStoreImportsKeys(t *testing.T) {
+	network := NewMemoryNetwork()
+	owner := startMemoryStore(t, network)
+	device := startMemoryStore(t, network)
+
+	ipfsHash, err := owner.UploadArtifact([]byte("log"), "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ipnsHash, err := owner.PublishToIPNS(ipfsHash, "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := owner.ExportKey("key")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	importedHash, err := device.ImportKey("key", key)
+	if err != nil || importedHash != ipnsHash {
+		t.Fatalf("expected the key %s, got %s %v", ipnsHash, importedHash, err)
+	}
+
+	// The imported key publishes the same name
+	if published, err := device.PublishToIPNS(ipfsHash, "key"); err != nil || published != ipnsHash {
+		t.Errorf("expected %s, got %s %v", ipnsHash, published, err)
+	}
+
+	if _, err := device.ImportKey("key", []byte("other")); err == nil {
+		t.Error("another key with the same name should not be imported")
+	}
+
+	if err := device.RemoveKey("key"); err != nil {
+		t.Fatal(err)
+	}
+
+	if exists, _, _ := device.GetKeyWithName("key"); exists {
+		t.Error("a removed key should not exist")
+	}
+}
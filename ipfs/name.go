@@ -13,10 +13,8 @@ import (
 	ifacePath "github.com/ipfs/interface-go-ipfs-core/path"
 )
 
-const ipnsTimeout = time.Minute * 3
-
 // publishIPNS publishes a IPFS hash to IPNS
-func publishIPNS(node *core.IpfsNode, ipfsCid cid.Cid, keyName string) error {
+func publishIPNS(node *core.IpfsNode, ipfsCid cid.Cid, keyName string, config Config) error {
 	api, err := coreapi.NewCoreAPI(node)
 	if err != nil {
 		return err
@@ -30,10 +28,10 @@ func publishIPNS(node *core.IpfsNode, ipfsCid cid.Cid, keyName string) error {
 	ipnsPublishOpts := []options.NamePublishOption{
 		options.Name.Key(keyName),
 		options.Name.AllowOffline(true),
-		options.Name.ValidTime(time.Hour * 48),
+		options.Name.ValidTime(config.IPNSValidTime),
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), ipnsTimeout)
+	ctx, cancel := context.WithTimeout(context.Background(), config.IPNSTimeout)
 	defer cancel()
 
 	// Publishes to IPNS
@@ -45,20 +43,20 @@ func publishIPNS(node *core.IpfsNode, ipfsCid cid.Cid, keyName string) error {
 }
 
 // resolveIPNS resolves the IPNS
-func resolveIPNS(node *core.IpfsNode, hash string) (ifacePath.Path, error) {
+func resolveIPNS(node *core.IpfsNode, hash string, timeout time.Duration) (ifacePath.Path, error) {
 	api, err := coreapi.NewCoreAPI(node)
 	if err != nil {
 		return nil, err
 	}
 
 	// Generates context with timeout to resolve
-	ctx, cancel := context.WithTimeout(context.Background(), ipnsTimeout)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
 	// Options to resolve until an IPFS is found
 	nameResolveOpts := []options.NameResolveOption{
 		options.Name.ResolveOption(nsopts.Depth(nsopts.UnlimitedDepth)),
-		options.Name.ResolveOption(nsopts.DhtTimeout(ipnsTimeout)),
+		options.Name.ResolveOption(nsopts.DhtTimeout(timeout)),
 	}
 
 	// Resolves the name
@@ -0,0 +1,85 @@
+package ipfs
+
+import "gitlab.com/tramonto-one/go-tramonto/entities"
+
+// ContentStore represents the storage of the encrypted contents and the IPNS names
+type ContentStore interface {
+	// InitRepo initializes the repo
+	InitRepo() error
+
+	// Start starts the node
+	Start() error
+
+	// Stop stops the node
+	Stop() error
+
+	// Configure replaces the configuration of the operations
+	Configure(config Config)
+
+	// IsOnline returns if the node is running and connected to other peers
+	IsOnline() bool
+
+	// UploadTest uploads a test, returns the IPFS hash
+	UploadTest(metadata entities.Metadata, secret string) (string, error)
+
+	// GetTestByIPFS returns a test metadata by IPFS
+	GetTestByIPFS(hash, secret string) (entities.Metadata, error)
+
+	// GetTestByIPNS returns a test metadata by IPNS and the IPFS hash it points to
+	GetTestByIPNS(hash, secret string) (string, entities.Metadata, error)
+
+	// UploadArtifact uploads an artifact, returns the IPFS hash
+	UploadArtifact(content []byte, secret string) (string, error)
+
+	// ReadArtifact reads the artifact of the IPFS hash
+	ReadArtifact(ipfsHash, secret string) ([]byte, error)
+
+	// UploadComment uploads a comment, returns the IPFS hash
+	UploadComment(comment entities.Comment, secret string) (string, error)
+
+	// ReadComment reads the comment of the IPFS hash
+	ReadComment(hash, secret string) (entities.Comment, error)
+
+	// UploadTemplate uploads a template, returns the IPFS hash
+	UploadTemplate(template entities.Template, secret string) (string, error)
+
+	// GetTemplateByIPFS returns a template by IPFS
+	GetTemplateByIPFS(hash, secret string) (entities.Template, error)
+
+	// UploadSuite uploads the document of a suite, returns the IPFS hash
+	UploadSuite(document entities.SuiteDocument, secret string) (string, error)
+
+	// GetSuiteByIPNS returns the document of a suite by IPNS and the IPFS hash it points to
+	GetSuiteByIPNS(hash, secret string) (string, entities.Suite, error)
+
+	// Unpin removes the pin of an IPFS hash, ErrNotPinned when it is not pinned
+	Unpin(ipfsHash string) error
+
+	// CollectGarbage removes the contents not pinned
+	CollectGarbage() error
+
+	// ResolveIPNS returns the IPFS hash an IPNS currently points to
+	ResolveIPNS(hash string) (string, error)
+
+	// PublishToIPNS publishes an IPFS hash with the named key, generating it when needed
+	// Returns the IPNS hash
+	PublishToIPNS(ipfsHash, keyName string) (string, error)
+
+	// GetKeyWithName returns if the named key exists and its IPNS hash
+	GetKeyWithName(name string) (bool, string, error)
+
+	// RemoveKey removes the named key
+	RemoveKey(name string) error
+
+	// ExportKey returns the named private key, marshaled
+	ExportKey(name string) ([]byte, error)
+
+	// ImportKey stores a marshaled private key with the name, returns its IPNS hash
+	ImportKey(name string, marshaledKey []byte) (string, error)
+
+	// GetNodeID returns the peer ID of the node
+	GetNodeID() (string, error)
+}
+
+// OneIPFS stores the contents in a go-ipfs node
+var _ ContentStore = (*OneIPFS)(nil)
@@ -0,0 +1,69 @@
+package ipfs
+
+import (
+	"errors"
+
+	oneCrypto "gitlab.com/tramonto-one/go-tramonto/crypto"
+	"gitlab.com/tramonto-one/go-tramonto/entities"
+)
+
+// UploadSuite uploads the document of a suite to IPFS
+// Returns the IPFS hash
+func (oneIpfs *OneIPFS) UploadSuite(document entities.SuiteDocument, secret string) (string, error) {
+	oneIpfs.mux.Lock()
+	defer oneIpfs.mux.Unlock()
+
+	// Verifies if node is running
+	if running := oneIpfs.isNodeRunning(); !running {
+		return "", errors.New("Node is not running")
+	}
+
+	jsonRepresentation, err := document.ToJSON()
+	if err != nil {
+		return "", errors.New("Error converting suite to json: " + err.Error())
+	}
+
+	encryptedData, err := oneCrypto.EncryptConfigFile(secret, jsonRepresentation)
+	if err != nil {
+		return "", errors.New("Error encrypting data: " + err.Error())
+	}
+
+	// Uploads json to IPFS
+	ipfsCid, err := addContent(oneIpfs.node, encryptedData, true)
+	if err != nil {
+		return "", errors.New("Error adding content: " + err.Error())
+	}
+
+	return ipfsCid.Hash().B58String(), nil
+}
+
+// GetSuiteByIPNS returns the document of a suite by IPNS
+// Returns the IPFS hash the IPNS points to
+func (oneIpfs *OneIPFS) GetSuiteByIPNS(hash, secret string) (string, entities.Suite, error) {
+	oneIpfs.mux.Lock()
+	defer oneIpfs.mux.Unlock()
+
+	// Resolves IPNS to IPFS
+	ipfsPath, err := resolveIPNS(oneIpfs.node, hash, oneIpfs.config.IPNSTimeout)
+	if err != nil {
+		return "", entities.Suite{}, errors.New("Error resolving IPNS: " + err.Error())
+	}
+
+	// Reads content
+	content, err := readContent(oneIpfs.node, ipfsPath, oneIpfs.config.CatTimeout)
+	if err != nil {
+		return "", entities.Suite{}, errors.New("Error reading content: " + err.Error())
+	}
+
+	decryptedData, err := oneCrypto.DecryptConfigFile(secret, content)
+	if err != nil {
+		return "", entities.Suite{}, errors.New("Error decrypting data: " + err.Error())
+	}
+
+	suite, err := entities.SuiteFromDocumentJSON(decryptedData)
+	if err != nil {
+		return "", entities.Suite{}, errors.New("Error parsing suite: " + err.Error())
+	}
+
+	return ipfsHashFromPath(ipfsPath), suite, nil
+}
@@ -0,0 +1,73 @@
+package ipfs
+
+import (
+	"errors"
+	"fmt"
+
+	ifacePath "github.com/ipfs/interface-go-ipfs-core/path"
+
+	oneCrypto "gitlab.com/tramonto-one/go-tramonto/crypto"
+	"gitlab.com/tramonto-one/go-tramonto/entities"
+)
+
+// UploadTemplate uploads a template to IPFS
+// Returns the IPFS hash
+func (oneIpfs *OneIPFS) UploadTemplate(template entities.Template, secret string) (string, error) {
+	oneIpfs.mux.Lock()
+	defer oneIpfs.mux.Unlock()
+
+	// Verifies if node is running
+	if running := oneIpfs.isNodeRunning(); !running {
+		return "", errors.New("Node is not running")
+	}
+
+	// The sharing information is not part of the document
+	template.Ipfs = ""
+	template.Secret = ""
+
+	jsonRepresentation, err := template.ToJSON()
+	if err != nil {
+		return "", errors.New("Error converting template to json: " + err.Error())
+	}
+
+	encryptedData, err := oneCrypto.EncryptConfigFile(secret, jsonRepresentation)
+	if err != nil {
+		return "", errors.New("Error encrypting data: " + err.Error())
+	}
+
+	// Uploads json to IPFS
+	ipfsCid, err := addContent(oneIpfs.node, encryptedData, true)
+	if err != nil {
+		return "", errors.New("Error adding content: " + err.Error())
+	}
+
+	return ipfsCid.Hash().B58String(), nil
+}
+
+// GetTemplateByIPFS returns a template by IPFS
+func (oneIpfs *OneIPFS) GetTemplateByIPFS(hash, secret string) (entities.Template, error) {
+	oneIpfs.mux.Lock()
+	defer oneIpfs.mux.Unlock()
+
+	// Parses IPFS hash to Path
+	ipfsHashPath := fmt.Sprintf("/ipfs/%s", hash)
+	ipfsPath := ifacePath.New(ipfsHashPath)
+
+	// Reads content
+	content, err := readContent(oneIpfs.node, ipfsPath, oneIpfs.config.CatTimeout)
+	if err != nil {
+		return entities.Template{}, errors.New("Error reading content: " + err.Error())
+	}
+
+	decryptedData, err := oneCrypto.DecryptConfigFile(secret, content)
+	if err != nil {
+		return entities.Template{}, errors.New("Error decrypting data: " + err.Error())
+	}
+
+	template, err := entities.TemplateFromJSON(decryptedData)
+	if err != nil {
+		return entities.Template{}, errors.New("Error parsing template: " + err.Error())
+	}
+
+	return template, nil
+}
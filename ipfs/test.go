@@ -1,10 +1,10 @@
 package ipfs
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	cid "github.com/ipfs/go-cid"
 	"github.com/ipfs/go-ipfs/core"
@@ -45,9 +45,9 @@ func (oneIpfs *OneIPFS) UploadTest(metadata entities.Metadata, secret string) (s
 	return ipfsCid.Hash().B58String(), nil
 }
 
-func getTestByIPFS(node *core.IpfsNode, path ifacePath.Path, secret string) (entities.Metadata, error) {
+func getTestByIPFS(node *core.IpfsNode, path ifacePath.Path, secret string, timeout time.Duration) (entities.Metadata, error) {
 	// Reads content
-	content, err := readContent(node, path)
+	content, err := readContent(node, path, timeout)
 	if err != nil {
 		return entities.Metadata{}, errors.New("Error reading content: " + err.Error())
 	}
@@ -57,10 +57,10 @@ func getTestByIPFS(node *core.IpfsNode, path ifacePath.Path, secret string) (ent
 		return entities.Metadata{}, errors.New("Error decrypting data: " + err.Error())
 	}
 
-	// Parses from json
-	var metadata entities.Metadata
-	if err := json.Unmarshal(decryptedData, &metadata); err != nil {
-		return entities.Metadata{}, errors.New("Error parsing to json: " + err.Error())
+	// Parses from json, upgrading older schema versions
+	metadata, err := entities.MetadataFromJSON(decryptedData)
+	if err != nil {
+		return entities.Metadata{}, errors.New("Error parsing metadata: " + err.Error())
 	}
 
 	// Return
@@ -77,7 +77,7 @@ func (oneIpfs *OneIPFS) GetTestByIPFS(hash, secret string) (entities.Metadata, e
 	ipfsPath := ifacePath.New(ipfsHashPath)
 
 	// Reads and returns
-	return getTestByIPFS(oneIpfs.node, ipfsPath, secret)
+	return getTestByIPFS(oneIpfs.node, ipfsPath, secret, oneIpfs.config.CatTimeout)
 }
 
 // GetTestByIPNS returns a test Metadata by IPFS
@@ -86,20 +86,36 @@ func (oneIpfs *OneIPFS) GetTestByIPNS(hash, secret string) (string, entities.Met
 	defer oneIpfs.mux.Unlock()
 
 	// Resolves IPNS to IPFS
-	ipfsPath, err := resolveIPNS(oneIpfs.node, hash)
+	ipfsPath, err := resolveIPNS(oneIpfs.node, hash, oneIpfs.config.IPNSTimeout)
 	if err != nil {
 		return "", entities.Metadata{}, errors.New("Error resolving IPNS: " + err.Error())
 	}
 
 	// Reads and returns
-	test, err := getTestByIPFS(oneIpfs.node, ipfsPath, secret)
+	test, err := getTestByIPFS(oneIpfs.node, ipfsPath, secret, oneIpfs.config.CatTimeout)
 	if err != nil {
 		return "", test, err
 	}
 
-	ipfsHash := strings.Split(ipfsPath.String(), "/")[2]
+	return ipfsHashFromPath(ipfsPath), test, nil
+}
+
+// ResolveIPNS returns the IPFS hash an IPNS currently points to
+func (oneIpfs *OneIPFS) ResolveIPNS(hash string) (string, error) {
+	oneIpfs.mux.Lock()
+	defer oneIpfs.mux.Unlock()
+
+	ipfsPath, err := resolveIPNS(oneIpfs.node, hash, oneIpfs.config.IPNSTimeout)
+	if err != nil {
+		return "", errors.New("Error resolving IPNS: " + err.Error())
+	}
+
+	return ipfsHashFromPath(ipfsPath), nil
+}
 
-	return ipfsHash, test, nil
+// ipfsHashFromPath returns the hash of an /ipfs/ path
+func ipfsHashFromPath(ipfsPath ifacePath.Path) string {
+	return strings.Split(ipfsPath.String(), "/")[2]
 }
 
 // PublishToIPNS publishes a test with IPNS
@@ -145,7 +161,7 @@ func (oneIpfs *OneIPFS) PublishToIPNS(ipfsHash, keyName string) (string, error)
 	}
 
 	// Publish to IPNS
-	if err := publishIPNS(oneIpfs.node, ipfsCid, keyName); err != nil {
+	if err := publishIPNS(oneIpfs.node, ipfsCid, keyName, oneIpfs.config); err != nil {
 		return ipnsHash, errors.New("Error publishing IPNS: " + err.Error())
 	}
 
@@ -0,0 +1,63 @@
+package tramonto
+
+import (
+	"errors"
+
+	"gitlab.com/tramonto-one/go-tramonto/entities"
+)
+
+// appendActivity registers a mutation made by this node in the test activity log
+func (t *TramontoOne) appendActivity(metadata *entities.Metadata, action, target string) error {
+	actor, err := t.ipfs.GetNodeID()
+	if err != nil {
+		return errors.New("(IPFS) Error reading node identity: " + err.Error())
+	}
+
+	metadata.AppendActivity(actor, action, target)
+
+	return nil
+}
+
+// VerifyActivityLog verifies the activity log of a test
+// The published revision must keep every entry of the revision last seen
+// published by this device, otherwise the log was tampered or truncated
+// Edits pending sync are not published yet, so the log is verified from the
+// revision the first of them started from
+func (t *TramontoOne) VerifyActivityLog(ipnsHash string) error {
+	// Gets the test from database
+	databaseTest, err := t.tests.FindTestByIpns(ipnsHash)
+	if err != nil {
+		return errors.New("(Database) Could not find test: " + err.Error())
+	}
+
+	operations, err := t.db.FindPendingOperations()
+	if err != nil {
+		return errors.New("(Database) " + err.Error())
+	}
+
+	previousIpfsHash := databaseTest.Ipfs
+	for _, operation := range operations {
+		if operation.TestIpns == ipnsHash {
+			previousIpfsHash = operation.BaseIpfs
+			break
+		}
+	}
+
+	// Reads the revision last seen published
+	previous, err := t.readMetadata(previousIpfsHash, databaseTest.Secret)
+	if err != nil {
+		return errors.New("(IPFS) Cannot read from IPFS: " + err.Error())
+	}
+
+	// Reads the revision published
+	_, current, err := t.ipfs.GetTestByIPNS(ipnsHash, databaseTest.Secret)
+	if err != nil {
+		return errors.New("(IPNS) Cannot read from IPNS: " + err.Error())
+	}
+
+	if err := entities.VerifyActivityLogContinuity(previous, current); err != nil {
+		return errors.New("(Activity) " + err.Error())
+	}
+
+	return nil
+}
@@ -0,0 +1,52 @@
+package tramonto
+
+import (
+	"testing"
+
+	oneDb "gitlab.com/tramonto-one/go-tramonto/db"
+	oneIpfs "gitlab.com/tramonto-one/go-tramonto/ipfs"
+)
+
+func TestVerifyActivityLogWithPendingEdits(t *testing.T) {
+	store, err := oneIpfs.NewMemoryStore(oneIpfs.NewMemoryNetwork())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	one, err := NewTramontoOneWithStores("", oneDb.NewMemoryTestRepository(), store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := one.CreateTest("", "Login crash")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	created := unmarshalTest(t, data)
+
+	// The edit made offline is ahead of the published revision
+	store.SetOnline(false)
+
+	if _, err := one.AddArtifact(created.Ipns, "log.txt", "", []byte("log"), map[string][]string{}); err != nil {
+		t.Fatal(err)
+	}
+
+	store.SetOnline(true)
+
+	if err := one.VerifyActivityLog(created.Ipns); err != nil {
+		t.Errorf("a pending edit should not fail the verification, got %v", err)
+	}
+
+	if _, err := one.drainOutbox(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := one.VerifyActivityLog(created.Ipns); err != nil {
+		t.Errorf("the published edit should be verified, got %v", err)
+	}
+}
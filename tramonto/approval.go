@@ -0,0 +1,281 @@
+package tramonto
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	oneCrypto "gitlab.com/tramonto-one/go-tramonto/crypto"
+	"gitlab.com/tramonto-one/go-tramonto/entities"
+)
+
+// signingKey returns the keys the device signs approvals with
+// The keys are generated on first use. Concurrent first uses may generate
+// different keys, so the keys stored by the first one are returned
+func (t *TramontoOne) signingKey() (string, string, error) {
+	privateKey, publicKey, err := t.db.FindSigningKey()
+	if err == nil {
+		return privateKey, publicKey, nil
+	}
+
+	if err != sql.ErrNoRows {
+		return "", "", errors.New("(Database) " + err.Error())
+	}
+
+	privateKey, publicKey, err = oneCrypto.GenerateSigningKey()
+	if err != nil {
+		return "", "", errors.New("Error generating signing key: " + err.Error())
+	}
+
+	if err = t.db.SaveSigningKey(privateKey, publicKey); err != nil {
+		return "", "", errors.New("(Database) " + err.Error())
+	}
+
+	privateKey, publicKey, err = t.db.FindSigningKey()
+	if err != nil {
+		return "", "", errors.New("(Database) " + err.Error())
+	}
+
+	return privateKey, publicKey, nil
+}
+
+// SetRequiredApprovers defines the members that must approve a test
+// approversJSON is a JSON array with the emails of the members
+func (t *TramontoOne) SetRequiredApprovers(ipnsHash string, approversJSON []byte) ([]byte, error) {
+	var approvers []string
+	if err := json.Unmarshal(approversJSON, &approvers); err != nil {
+		return nil, errors.New("Invalid approvers: " + err.Error())
+	}
+
+	// Gets the test from database
+	databaseTest, err := t.tests.FindTestByIpns(ipnsHash)
+	if err != nil {
+		return nil, errors.New("(Database) Could not find test: " + err.Error())
+	}
+
+	// Verifies if the user is the owner
+	if !databaseTest.IsOwner {
+		return nil, errors.New("User is not owner of this test")
+	}
+
+	baseMetadata, err := t.readMetadata(databaseTest.Ipfs, databaseTest.Secret)
+	if err != nil {
+		return nil, errors.New("(IPFS) Cannot read from IPFS: " + err.Error())
+	}
+
+	metadata := baseMetadata
+	if err = metadata.SetRequiredApprovers(approvers); err != nil {
+		return nil, errors.New("Error setting approvers: " + err.Error())
+	}
+
+	if err = t.appendActivity(&metadata, entities.ActivitySetApprovers, string(approversJSON)); err != nil {
+		return nil, err
+	}
+
+	// Publishes the new revision
+	if _, metadata, err = t.publishMetadata(databaseTest, baseMetadata, metadata); err != nil {
+		return nil, err
+	}
+
+	jsonData, err := json.Marshal(metadata.ApprovalState())
+	if err != nil {
+		return nil, errors.New("Error parsing to json: " + err.Error())
+	}
+
+	return jsonData, nil
+}
+
+// GetMemberKey returns the public signing key of the device
+// The owner of a test registers it to a member with SetMemberKey, so the member
+// approves and comments from this device
+func (t *TramontoOne) GetMemberKey() (string, error) {
+	_, publicKey, err := t.signingKey()
+	if err != nil {
+		return "", err
+	}
+
+	return publicKey, nil
+}
+
+// SetMemberKey registers the signing key of a member of a test
+func (t *TramontoOne) SetMemberKey(ipnsHash, email, publicKey string) ([]byte, error) {
+	// Gets the test from database
+	databaseTest, err := t.tests.FindTestByIpns(ipnsHash)
+	if err != nil {
+		return nil, errors.New("(Database) Could not find test: " + err.Error())
+	}
+
+	// Verifies if the user is the owner
+	if !databaseTest.IsOwner {
+		return nil, errors.New("User is not owner of this test")
+	}
+
+	baseMetadata, err := t.readMetadata(databaseTest.Ipfs, databaseTest.Secret)
+	if err != nil {
+		return nil, errors.New("(IPFS) Cannot read from IPFS: " + err.Error())
+	}
+
+	metadata := baseMetadata
+	if err = metadata.SetMemberKey(email, publicKey); err != nil {
+		return nil, errors.New("Error setting member key: " + err.Error())
+	}
+
+	if err = t.appendActivity(&metadata, entities.ActivitySetMemberKey, email); err != nil {
+		return nil, err
+	}
+
+	// Publishes the new revision
+	if _, metadata, err = t.publishMetadata(databaseTest, baseMetadata, metadata); err != nil {
+		return nil, err
+	}
+
+	jsonData, err := json.Marshal(metadata)
+	if err != nil {
+		return nil, errors.New("Error parsing to json: " + err.Error())
+	}
+
+	return jsonData, nil
+}
+
+// deviceMember returns the member of the test whose key is the signing key of the device
+func (t *TramontoOne) deviceMember(metadata entities.Metadata) (entities.Member, string, error) {
+	privateKey, publicKey, err := t.signingKey()
+	if err != nil {
+		return entities.Member{}, "", err
+	}
+
+	member, exists := metadata.MemberWithKey(publicKey)
+	if !exists {
+		return entities.Member{}, "", errors.New("The key of this device is not registered to a member of this test")
+	}
+
+	return member, privateKey, nil
+}
+
+// signApproval signs the decision of the member of the device over the revision
+func (t *TramontoOne) signApproval(metadata entities.Metadata, decision, note string) (entities.Approval, error) {
+	member, privateKey, err := t.deviceMember(metadata)
+	if err != nil {
+		return entities.Approval{}, err
+	}
+
+	revisionHash, err := metadata.RevisionHash()
+	if err != nil {
+		return entities.Approval{}, errors.New("Error hashing revision: " + err.Error())
+	}
+
+	return entities.NewApproval(member.Email, decision, note, revisionHash, privateKey, member.PublicKey)
+}
+
+// addApproval adds a signed approval to the test and publishes it
+func (t *TramontoOne) addApproval(databaseTest entities.Test, baseMetadata entities.Metadata, approval entities.Approval) ([]byte, error) {
+	metadata := baseMetadata
+	if err := metadata.AddApproval(approval); err != nil {
+		return nil, errors.New("Error adding approval: " + err.Error())
+	}
+
+	action := entities.ActivityApprove
+	if approval.Decision == entities.ApprovalRejected {
+		action = entities.ActivityReject
+	}
+
+	if err := t.appendActivity(&metadata, action, approval.Member); err != nil {
+		return nil, err
+	}
+
+	// Publishes the new revision
+	_, metadata, err := t.publishMetadata(databaseTest, baseMetadata, metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	jsonData, err := json.Marshal(metadata.ApprovalState())
+	if err != nil {
+		return nil, errors.New("Error parsing to json: " + err.Error())
+	}
+
+	return jsonData, nil
+}
+
+// ApproveTest registers the decision of the member of the device over the
+// current revision of a test it owns
+// The approval is signed with the signing key of the device
+func (t *TramontoOne) ApproveTest(ipnsHash, decision, note string) ([]byte, error) {
+	// Gets the test from database
+	databaseTest, err := t.tests.FindTestByIpns(ipnsHash)
+	if err != nil {
+		return nil, errors.New("(Database) Could not find test: " + err.Error())
+	}
+
+	// Verifies if the user is the owner
+	if !databaseTest.IsOwner {
+		return nil, errors.New("User is not owner of this test, sign the approval with SignApproval")
+	}
+
+	baseMetadata, err := t.readMetadata(databaseTest.Ipfs, databaseTest.Secret)
+	if err != nil {
+		return nil, errors.New("(IPFS) Cannot read from IPFS: " + err.Error())
+	}
+
+	approval, err := t.signApproval(baseMetadata, decision, note)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.addApproval(databaseTest, baseMetadata, approval)
+}
+
+// SignApproval signs the decision of the member of the device over the current
+// revision of a test, without owning it
+// Returns the JSON of the approval, the owner adds it with AddSignedApproval
+func (t *TramontoOne) SignApproval(ipnsHash, decision, note string) ([]byte, error) {
+	// Gets the test from database
+	databaseTest, err := t.tests.FindTestByIpns(ipnsHash)
+	if err != nil {
+		return nil, errors.New("(Database) Could not find test: " + err.Error())
+	}
+
+	metadata, err := t.readMetadata(databaseTest.Ipfs, databaseTest.Secret)
+	if err != nil {
+		return nil, errors.New("(IPFS) Cannot read from IPFS: " + err.Error())
+	}
+
+	approval, err := t.signApproval(metadata, decision, note)
+	if err != nil {
+		return nil, err
+	}
+
+	jsonData, err := json.Marshal(approval)
+	if err != nil {
+		return nil, errors.New("Error parsing to json: " + err.Error())
+	}
+
+	return jsonData, nil
+}
+
+// AddSignedApproval adds an approval signed by a member on another device
+// The approval is verified with the key registered to the member
+func (t *TramontoOne) AddSignedApproval(ipnsHash string, approvalJSON []byte) ([]byte, error) {
+	var approval entities.Approval
+	if err := json.Unmarshal(approvalJSON, &approval); err != nil {
+		return nil, errors.New("Invalid approval: " + err.Error())
+	}
+
+	// Gets the test from database
+	databaseTest, err := t.tests.FindTestByIpns(ipnsHash)
+	if err != nil {
+		return nil, errors.New("(Database) Could not find test: " + err.Error())
+	}
+
+	// Verifies if the user is the owner
+	if !databaseTest.IsOwner {
+		return nil, errors.New("User is not owner of this test")
+	}
+
+	baseMetadata, err := t.readMetadata(databaseTest.Ipfs, databaseTest.Secret)
+	if err != nil {
+		return nil, errors.New("(IPFS) Cannot read from IPFS: " + err.Error())
+	}
+
+	return t.addApproval(databaseTest, baseMetadata, approval)
+}
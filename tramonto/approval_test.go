@@ -0,0 +1,149 @@
+package tramonto
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+
+	"gitlab.com/tramonto-one/go-tramonto/entities"
+	oneIpfs "gitlab.com/tramonto-one/go-tramonto/ipfs"
+)
+
+// registerMemberKey adds the member to the test and registers the key of its device
+func registerMemberKey(t *testing.T, owner, device *TramontoOne, ipnsHash, name, email string) {
+	t.Helper()
+
+	if _, err := owner.AddMember(ipnsHash, name, email, "qa"); err != nil {
+		t.Fatal(err)
+	}
+
+	publicKey, err := device.GetMemberKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := owner.SetMemberKey(ipnsHash, email, publicKey); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMembersApproveWithTheirKeys(t *testing.T) {
+	network := oneIpfs.NewMemoryNetwork()
+	owner := newMemoryTramonto(t, network)
+	device := newMemoryTramonto(t, network)
+
+	data, err := owner.CreateTest("", "Release 1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	test := unmarshalTest(t, data)
+
+	registerMemberKey(t, owner, owner, test.Ipns, "John", "john@tramonto.one")
+	registerMemberKey(t, owner, device, test.Ipns, "Mary", "mary@tramonto.one")
+
+	if _, err := owner.SetRequiredApprovers(test.Ipns, []byte(`["john@tramonto.one","mary@tramonto.one"]`)); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := owner.ApproveTest(test.Ipns, entities.ApprovalApproved, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := device.ImportTest(test.Ipns, test.Secret); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := device.ApproveTest(test.Ipns, entities.ApprovalApproved, ""); err == nil {
+		t.Error("a device not owner of the test should not publish approvals")
+	}
+
+	// The member signs on its device and the owner adds the approval
+	approvalJSON, err := device.SignApproval(test.Ipns, entities.ApprovalApproved, "Ship it")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	approval := entities.Approval{}
+	if err := json.Unmarshal(approvalJSON, &approval); err != nil {
+		t.Fatal(err)
+	}
+
+	if approval.Member != "mary@tramonto.one" {
+		t.Errorf("the approval should be signed by the member of the key, got %s", approval.Member)
+	}
+
+	data, err = owner.AddSignedApproval(test.Ipns, approvalJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	state := entities.ApprovalState{}
+	if err := json.Unmarshal(data, &state); err != nil {
+		t.Fatal(err)
+	}
+
+	if !state.Approved {
+		t.Errorf("test should be approved, got %+v", state)
+	}
+
+	// An approval claiming another member is rejected
+	approval.Member = "john@tramonto.one"
+	forgedJSON, _ := json.Marshal(approval)
+
+	if _, err := owner.AddSignedApproval(test.Ipns, forgedJSON); err == nil {
+		t.Error("an approval signed with the key of another member should be rejected")
+	}
+
+	stranger := newMemoryTramonto(t, network)
+	if _, err := stranger.ImportTest(test.Ipns, test.Secret); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := stranger.SignApproval(test.Ipns, entities.ApprovalApproved, ""); err == nil {
+		t.Error("a device without a member key should not sign approvals")
+	}
+}
+
+func TestSigningKeyIsGeneratedOnce(t *testing.T) {
+	one := newMemoryTramonto(t, oneIpfs.NewMemoryNetwork())
+
+	publicKeys := make(chan string, 8)
+	errs := make(chan error, 8)
+
+	var wg sync.WaitGroup
+	for index := 0; index < 8; index++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			_, publicKey, err := one.signingKey()
+			if err != nil {
+				errs <- err
+				return
+			}
+
+			publicKeys <- publicKey
+		}()
+	}
+
+	wg.Wait()
+	close(publicKeys)
+	close(errs)
+
+	for err := range errs {
+		t.Fatal(err)
+	}
+
+	_, stored, err := one.db.FindSigningKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for publicKey := range publicKeys {
+		if publicKey != stored {
+			t.Errorf("every first use should return the stored key %s, got %s", stored, publicKey)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package tramonto
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+
+	oneDb "gitlab.com/tramonto-one/go-tramonto/db"
+	"gitlab.com/tramonto-one/go-tramonto/entities"
+	oneIpfs "gitlab.com/tramonto-one/go-tramonto/ipfs"
+)
+
+// ArchiveTest archives a test, hiding it from the listings
+// The IPNS key is kept, so an archived test can be restored and published again
+func (t *TramontoOne) ArchiveTest(ipnsHash string) error {
+	if err := t.tests.ArchiveTest(ipnsHash); err != nil {
+		return errors.New("(Database) Error archiving test: " + err.Error())
+	}
+
+	return nil
+}
+
+// RestoreTest restores an archived test
+func (t *TramontoOne) RestoreTest(ipnsHash string) error {
+	if err := t.tests.RestoreTest(ipnsHash); err != nil {
+		return errors.New("(Database) Error restoring test: " + err.Error())
+	}
+
+	return nil
+}
+
+// GetArchivedTests gets the archived tests from the database
+func (t *TramontoOne) GetArchivedTests() ([]byte, error) {
+	tests, err := t.tests.FindArchivedTests()
+	if err != nil {
+		return nil, errors.New("(Database) Error finding tests: " + err.Error())
+	}
+
+	if err = t.attachCachedDetails(tests); err != nil {
+		return nil, err
+	}
+
+	jsonData, err := json.Marshal(tests)
+	if err != nil {
+		return nil, errors.New("Error parsing to json: " + err.Error())
+	}
+
+	return jsonData, nil
+}
+
+// DeleteTest permanently deletes a test from the device
+// Every revision of the test known by the node is unpinned, with the contents
+// of the current revision, so the node collects them. The IPNS key of an owned
+// test is removed after the test is deleted, so the test cannot be updated anymore
+// The test is deleted with its caches, history and pending operations at once
+// The test is deleted even when its contents cannot be released, the errors
+// releasing them are returned afterwards
+func (t *TramontoOne) DeleteTest(ipnsHash string) error {
+	test, err := t.tests.FindAnyTestByIpns(ipnsHash)
+	if err != nil {
+		return errors.New("(Database) Could not find test: " + err.Error())
+	}
+
+	// The revisions are found before their rows are deleted
+	hashes, err := t.db.FindTestRevisions(ipnsHash)
+	if err != nil {
+		return errors.New("(Database) " + err.Error())
+	}
+
+	hashes = append(hashes, test.Ipfs)
+
+	releaseErrors := []string{}
+
+	// Reads the current revision to find the contents to unpin
+	metadata, err := t.readMetadata(test.Ipfs, test.Secret)
+	if err != nil {
+		releaseErrors = append(releaseErrors, "(IPFS) Cannot read from IPFS: "+err.Error())
+	} else {
+		for _, artifact := range metadata.Artifacts {
+			hashes = append(hashes, artifact.Hash)
+		}
+		for _, comment := range metadata.Comments {
+			hashes = append(hashes, comment.Hash)
+		}
+	}
+
+	if err = t.deleteTestWithRecords(test); err != nil {
+		return err
+	}
+
+	// Revisions only resolved by the node were never pinned
+	unpinned := map[string]bool{}
+	for _, hash := range hashes {
+		if hash == "" || unpinned[hash] {
+			continue
+		}
+
+		unpinned[hash] = true
+
+		if err := t.ipfs.Unpin(hash); err != nil && err != oneIpfs.ErrNotPinned {
+			releaseErrors = append(releaseErrors, "(IPFS) Error unpinning "+hash+": "+err.Error())
+		}
+	}
+
+	// The key is named after the test ID
+	if test.IsOwner && test.Metadata.ID != "" {
+		if err := t.ipfs.RemoveKey(test.Metadata.ID); err != nil {
+			releaseErrors = append(releaseErrors, "(IPNS) Error removing key: "+err.Error())
+		}
+	}
+
+	if len(releaseErrors) > 0 {
+		return errors.New("Test deleted, but its contents were not released: " + strings.Join(releaseErrors, "; "))
+	}
+
+	return nil
+}
+
+// deleteTestWithRecords deletes a test with its caches, history and pending operations
+// Repositories implementing TestRecordsDeleter delete them at once. Tests stored
+// apart from the database are deleted after their records, so a failure leaves
+// the test to be deleted again
+func (t *TramontoOne) deleteTestWithRecords(test entities.Test) error {
+	if deleter, ok := t.tests.(oneDb.TestRecordsDeleter); ok {
+		if err := deleter.DeleteTestWithRecords(test.Ipns, test.Ipfs); err != nil {
+			return errors.New("(Database) Error deleting test: " + err.Error())
+		}
+
+		return nil
+	}
+
+	if err := t.db.DeleteTestRecords(test.Ipns, test.Ipfs); err != nil {
+		return errors.New("(Database) Error deleting test records: " + err.Error())
+	}
+
+	if err := t.tests.DeleteTest(test.Ipns); err != nil {
+		return errors.New("(Database) Error deleting test: " + err.Error())
+	}
+
+	return nil
+}
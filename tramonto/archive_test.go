@@ -0,0 +1,64 @@
+package tramonto
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	oneDb "gitlab.com/tramonto-one/go-tramonto/db"
+)
+
+// decoratedRepository wraps the database repository counting how the tests are deleted
+type decoratedRepository struct {
+	*oneDb.OneSQLite
+	plainDeletes  int
+	recordDeletes int
+}
+
+// DeleteTest counts the delete and deletes the test
+func (r *decoratedRepository) DeleteTest(ipnsHash string) error {
+	r.plainDeletes++
+
+	return r.OneSQLite.DeleteTest(ipnsHash)
+}
+
+// DeleteTestWithRecords counts the delete and deletes the test with its records
+func (r *decoratedRepository) DeleteTestWithRecords(ipnsHash, ipfsHash string) error {
+	r.recordDeletes++
+
+	return r.OneSQLite.DeleteTestWithRecords(ipnsHash, ipfsHash)
+}
+
+func TestDeleteTestUsesTheDeleteWithRecordsOfDecorators(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tramonto-delete")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	one := newDemoTramonto(t, filepath.Join(dir, "device"))
+
+	decorated := &decoratedRepository{OneSQLite: one.db}
+	one.tests = decorated
+
+	data, err := one.CreateTest("", "Login crash")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	created := unmarshalTest(t, data)
+
+	if err := one.DeleteTest(created.Ipns); err != nil {
+		t.Fatal(err)
+	}
+
+	if decorated.recordDeletes != 1 || decorated.plainDeletes != 0 {
+		t.Errorf("the test should be deleted with its records at once, got %d and %d plain deletes", decorated.recordDeletes, decorated.plainDeletes)
+	}
+
+	if _, err := one.tests.FindAnyTestByIpns(created.Ipns); err != sql.ErrNoRows {
+		t.Errorf("the test should be deleted, got %v", err)
+	}
+}
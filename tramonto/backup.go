@@ -0,0 +1,458 @@
+package tramonto
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	oneCrypto "gitlab.com/tramonto-one/go-tramonto/crypto"
+	oneDb "gitlab.com/tramonto-one/go-tramonto/db"
+	"gitlab.com/tramonto-one/go-tramonto/entities"
+	oneIpfs "gitlab.com/tramonto-one/go-tramonto/ipfs"
+)
+
+// Entries of the backup archive
+const (
+	backupManifestEntry = "manifest.json"
+	backupDatabaseEntry = "one.db"
+	backupRepoEntry     = ".ipfs"
+)
+
+// Directory of the path where the backups are exported, and their extension
+const (
+	backupsDirectory = "backups"
+	backupExtension  = ".backup"
+)
+
+// canBackup returns an error when the tests are not stored in the database file,
+// as in the memory storage, so a backup would not have them
+func (t *TramontoOne) canBackup() error {
+	if repository, ok := t.tests.(*oneDb.OneSQLite); !ok || repository != t.db || t.db.Path() == "" {
+		return errors.New("Backups need the tests stored in the database")
+	}
+
+	return nil
+}
+
+// ExportBackup exports the database and the IPNS keys to an archive encrypted with the passphrase
+// The archive is streamed to a file in the backups directory of the path, so it
+// is never held in memory, and its path is returned
+// When includeBlocks the pinned blocks are exported too, the node is stopped meanwhile
+// and an error is returned if it cannot be started again, with the path of the
+// backup when it was exported
+func (t *TramontoOne) ExportBackup(passphrase string, includeBlocks bool) (backupPath string, err error) {
+	if passphrase == "" {
+		return "", errors.New("Passphrase is required")
+	}
+
+	if err := t.canBackup(); err != nil {
+		return "", err
+	}
+
+	tempDir, err := ioutil.TempDir("", "tramonto-backup")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Copies the database while it is in use
+	databaseCopy := filepath.Join(tempDir, backupDatabaseEntry)
+	if err := t.db.BackupTo(databaseCopy); err != nil {
+		return "", errors.New("(Database) " + err.Error())
+	}
+
+	// The blocks cannot be copied while the node writes them
+	if includeBlocks && t.started {
+		t.stopScheduler()
+
+		if err := t.ipfs.Stop(); err != nil {
+			if startErr := t.startScheduler(); startErr != nil {
+				return "", errors.New("Error stopping IPFS: " + err.Error() + ", error starting scheduler: " + startErr.Error())
+			}
+
+			return "", errors.New("Error stopping IPFS: " + err.Error())
+		}
+
+		defer func() {
+			if startErr := t.restartAfterBackup(); startErr != nil {
+				err = startErr
+			}
+		}()
+	}
+
+	repoPath := oneIpfs.RepoPath(t.path)
+	repoFiles, err := oneIpfs.BackupFiles(repoPath, includeBlocks)
+	if err != nil {
+		return "", errors.New("(IPFS) Error listing repo: " + err.Error())
+	}
+
+	manifest, err := json.Marshal(entities.BackupManifest{
+		Version:        entities.BackupVersion,
+		CreatedAt:      time.Now(),
+		IncludesBlocks: includeBlocks,
+	})
+	if err != nil {
+		return "", errors.New("Error parsing to json: " + err.Error())
+	}
+
+	backupsDir := filepath.Join(t.path, backupsDirectory)
+	if err := os.MkdirAll(backupsDir, 0700); err != nil {
+		return "", err
+	}
+
+	// The file is only named as a backup when it is complete
+	file, err := ioutil.TempFile(backupsDir, ".export")
+	if err != nil {
+		return "", err
+	}
+
+	defer func() {
+		if file != nil {
+			file.Close()
+			os.Remove(file.Name())
+		}
+	}()
+
+	encryptWriter, err := oneCrypto.NewPassphraseWriter(passphrase, file)
+	if err != nil {
+		return "", errors.New("Error encrypting backup: " + err.Error())
+	}
+
+	gzipWriter := gzip.NewWriter(encryptWriter)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	if err := writeBackupEntry(tarWriter, backupManifestEntry, bytes.NewReader(manifest), int64(len(manifest))); err != nil {
+		return "", err
+	}
+
+	if err := writeBackupFile(tarWriter, backupDatabaseEntry, databaseCopy); err != nil {
+		return "", err
+	}
+
+	for _, repoFile := range repoFiles {
+		if err := writeBackupFile(tarWriter, path.Join(backupRepoEntry, repoFile), filepath.Join(repoPath, filepath.FromSlash(repoFile))); err != nil {
+			return "", err
+		}
+	}
+
+	if err := tarWriter.Close(); err != nil {
+		return "", errors.New("Error writing backup: " + err.Error())
+	}
+
+	if err := gzipWriter.Close(); err != nil {
+		return "", errors.New("Error writing backup: " + err.Error())
+	}
+
+	if err := encryptWriter.Close(); err != nil {
+		return "", errors.New("Error encrypting backup: " + err.Error())
+	}
+
+	if err := file.Close(); err != nil {
+		return "", errors.New("Error writing backup: " + err.Error())
+	}
+
+	backupPath = filepath.Join(backupsDir, "tramonto-"+time.Now().UTC().Format("20060102-150405")+backupExtension)
+	if err := os.Rename(file.Name(), backupPath); err != nil {
+		return "", errors.New("Error writing backup: " + err.Error())
+	}
+
+	file = nil
+
+	return backupPath, nil
+}
+
+// restartAfterBackup starts again the node and the scheduler stopped to back up the blocks
+// The scheduler is started even when IPFS is not, its jobs wait for the node
+func (t *TramontoOne) restartAfterBackup() error {
+	ipfsErr := t.ipfs.Start()
+	schedulerErr := t.startScheduler()
+
+	switch {
+	case ipfsErr != nil && schedulerErr != nil:
+		return errors.New("Error starting IPFS: " + ipfsErr.Error() + ", error starting scheduler: " + schedulerErr.Error())
+	case ipfsErr != nil:
+		return errors.New("Error starting IPFS: " + ipfsErr.Error())
+	case schedulerErr != nil:
+		return errors.New("Error starting scheduler: " + schedulerErr.Error())
+	}
+
+	return nil
+}
+
+// writeBackupFile writes a file to the archive
+func writeBackupFile(tarWriter *tar.Writer, name, filePath string) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return errors.New("Error reading " + name + ": " + err.Error())
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	return writeBackupEntry(tarWriter, name, file, info.Size())
+}
+
+// writeBackupEntry writes an entry to the archive
+func writeBackupEntry(tarWriter *tar.Writer, name string, content io.Reader, size int64) error {
+	if err := tarWriter.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0600,
+		Size:    size,
+		ModTime: time.Now(),
+	}); err != nil {
+		return errors.New("Error writing backup: " + err.Error())
+	}
+
+	if _, err := io.Copy(tarWriter, content); err != nil {
+		return errors.New("Error writing backup: " + err.Error())
+	}
+
+	return nil
+}
+
+// RestoreBackup restores the archive file exported by ExportBackup, replacing
+// the database and the IPNS keys of this device
+// The archive is decrypted while it is extracted, so it is never held in memory
+// The current files are moved aside until every file is restored, and put back
+// when one cannot be. It must be called before Setup
+func (t *TramontoOne) RestoreBackup(passphrase, backupPath string) error {
+	if t.started {
+		return errors.New("Backup must be restored before Setup")
+	}
+
+	if err := t.canBackup(); err != nil {
+		return err
+	}
+
+	file, err := os.Open(backupPath)
+	if err != nil {
+		return errors.New("Error reading backup: " + err.Error())
+	}
+	defer file.Close()
+
+	archive, err := oneCrypto.NewPassphraseReader(passphrase, bufio.NewReader(file))
+	if err != nil {
+		return errors.New("Error decrypting backup: " + err.Error())
+	}
+
+	// Extracts to a staging directory, so a broken archive changes nothing
+	stagingDir, err := ioutil.TempDir(t.path, ".restore")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(stagingDir)
+
+	manifest, err := extractBackup(archive, stagingDir)
+	if err != nil {
+		return err
+	}
+
+	if manifest.Version != entities.BackupVersion {
+		return errors.New("Unsupported backup version")
+	}
+
+	databasePath := t.db.Path()
+	repoPath := oneIpfs.RepoPath(t.path)
+	stagedRepo := filepath.Join(stagingDir, backupRepoEntry)
+
+	// The journal of the current database does not belong to the restored one
+	replacements := []backupReplacement{
+		{staged: filepath.Join(stagingDir, backupDatabaseEntry), target: databasePath},
+		{target: databasePath + "-wal"},
+		{target: databasePath + "-shm"},
+	}
+
+	// Replaces the keys, and the blocks when the backup has them
+	for _, file := range oneIpfs.RestoredFiles(manifest.IncludesBlocks) {
+		replacements = append(replacements, backupReplacement{
+			staged: filepath.Join(stagedRepo, file),
+			target: filepath.Join(repoPath, file),
+		})
+	}
+
+	if err := t.db.Close(); err != nil {
+		return errors.New("(Database) Error closing: " + err.Error())
+	}
+
+	replaceErr := replaceWithBackup(replacements, filepath.Join(stagingDir, ".previous"))
+
+	// Opens the restored database, or the current one when it was rolled back
+	db, err := oneDb.OpenOneSQLite(t.path)
+	if err != nil {
+		return errors.New("Error initializing OneSQLite: " + err.Error())
+	}
+
+	// canBackup made sure the tests are stored in the database
+	t.tests = db
+	t.db = db
+
+	if replaceErr != nil {
+		return errors.New("Error restoring backup: " + replaceErr.Error())
+	}
+
+	return nil
+}
+
+// backupReplacement is a file or directory of the device replaced by the one
+// restored from a backup. Without a staged path the target is only removed
+type backupReplacement struct {
+	staged string
+	target string
+}
+
+// replaceWithBackup moves the current files aside and the restored files in place
+// Every move is a rename, so when one fails the files moved are put back
+// The files moved aside are left in asideDir
+func replaceWithBackup(replacements []backupReplacement, asideDir string) error {
+	if err := os.MkdirAll(asideDir, 0700); err != nil {
+		return err
+	}
+
+	type move struct {
+		from string
+		to   string
+	}
+
+	moves := []move{}
+
+	rename := func(from, to string) error {
+		if err := os.Rename(from, to); err != nil {
+			return err
+		}
+
+		moves = append(moves, move{from, to})
+		return nil
+	}
+
+	rollback := func(cause error) error {
+		for index := len(moves) - 1; index >= 0; index-- {
+			if err := os.Rename(moves[index].to, moves[index].from); err != nil {
+				return errors.New(cause.Error() + ", could not roll back " + moves[index].from + ": " + err.Error())
+			}
+		}
+
+		return cause
+	}
+
+	for index, replacement := range replacements {
+		if _, err := os.Lstat(replacement.target); err == nil {
+			if err := rename(replacement.target, filepath.Join(asideDir, strconv.Itoa(index))); err != nil {
+				return rollback(err)
+			}
+		} else if !os.IsNotExist(err) {
+			return rollback(err)
+		}
+
+		if replacement.staged == "" {
+			continue
+		}
+
+		// Backups of older repos may miss some files
+		if _, err := os.Lstat(replacement.staged); os.IsNotExist(err) {
+			continue
+		}
+
+		if err := os.MkdirAll(filepath.Dir(replacement.target), 0700); err != nil {
+			return rollback(err)
+		}
+
+		if err := rename(replacement.staged, replacement.target); err != nil {
+			return rollback(err)
+		}
+	}
+
+	return nil
+}
+
+// extractBackup extracts the archive to the directory
+// Returns the manifest of the backup
+// A wrong passphrase or a tampered archive fails while it is read
+func extractBackup(archive io.Reader, directory string) (entities.BackupManifest, error) {
+	var manifest entities.BackupManifest
+
+	gzipReader, err := gzip.NewReader(archive)
+	if err != nil {
+		return manifest, errors.New("Invalid backup: " + err.Error())
+	}
+
+	tarReader := tar.NewReader(gzipReader)
+	hasManifest, hasDatabase := false, false
+
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return manifest, errors.New("Invalid backup: " + err.Error())
+		}
+
+		// Entries must stay inside the directory
+		name := path.Clean(header.Name)
+		if header.Typeflag != tar.TypeReg || path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return manifest, errors.New("Invalid backup: unexpected entry " + header.Name)
+		}
+
+		if name == backupManifestEntry {
+			if err := json.NewDecoder(tarReader).Decode(&manifest); err != nil {
+				return manifest, errors.New("Invalid backup manifest: " + err.Error())
+			}
+
+			hasManifest = true
+			continue
+		}
+
+		if name != backupDatabaseEntry && !strings.HasPrefix(name, backupRepoEntry+"/") {
+			return manifest, errors.New("Invalid backup: unexpected entry " + header.Name)
+		}
+
+		hasDatabase = hasDatabase || name == backupDatabaseEntry
+
+		destination := filepath.Join(directory, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(destination), 0700); err != nil {
+			return manifest, err
+		}
+
+		file, err := os.OpenFile(destination, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+		if err != nil {
+			return manifest, err
+		}
+
+		_, err = io.Copy(file, tarReader)
+		file.Close()
+
+		if err != nil {
+			return manifest, errors.New("Invalid backup: " + err.Error())
+		}
+	}
+
+	if !hasManifest || !hasDatabase {
+		return manifest, errors.New("Invalid backup: manifest or database is missing")
+	}
+
+	// Reads the archive to its end, so a truncated or tampered end is detected
+	if _, err := io.Copy(ioutil.Discard, gzipReader); err != nil {
+		return manifest, errors.New("Invalid backup: " + err.Error())
+	}
+
+	if _, err := io.Copy(ioutil.Discard, archive); err != nil {
+		return manifest, errors.New("Invalid backup: " + err.Error())
+	}
+
+	return manifest, nil
+}
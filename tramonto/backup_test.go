@@ -0,0 +1,200 @@
+package tramonto
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	oneIpfs "gitlab.com/tramonto-one/go-tramonto/ipfs"
+)
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readTestFile(t *testing.T, path string) string {
+	t.Helper()
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(content)
+}
+
+func TestReplaceWithBackup(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tramonto-restore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeTestFile(t, filepath.Join(dir, "one.db"), "current database")
+	writeTestFile(t, filepath.Join(dir, "one.db-wal"), "current journal")
+	writeTestFile(t, filepath.Join(dir, ".ipfs", "keystore", "old"), "old key")
+	writeTestFile(t, filepath.Join(dir, "staging", "one.db"), "restored database")
+	writeTestFile(t, filepath.Join(dir, "staging", ".ipfs", "keystore", "new"), "new key")
+
+	err = replaceWithBackup([]backupReplacement{
+		{staged: filepath.Join(dir, "staging", "one.db"), target: filepath.Join(dir, "one.db")},
+		{target: filepath.Join(dir, "one.db-wal")},
+		{staged: filepath.Join(dir, "staging", ".ipfs", "keystore"), target: filepath.Join(dir, ".ipfs", "keystore")},
+	}, filepath.Join(dir, "staging", ".previous"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if content := readTestFile(t, filepath.Join(dir, "one.db")); content != "restored database" {
+		t.Errorf("database should be restored, got %s", content)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "one.db-wal")); !os.IsNotExist(err) {
+		t.Error("journal of the current database should be removed")
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, ".ipfs", "keystore", "old")); !os.IsNotExist(err) {
+		t.Error("current keys should be replaced")
+	}
+
+	if content := readTestFile(t, filepath.Join(dir, ".ipfs", "keystore", "new")); content != "new key" {
+		t.Errorf("keys should be restored, got %s", content)
+	}
+}
+
+func TestReplaceWithBackupRollsBack(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tramonto-restore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeTestFile(t, filepath.Join(dir, "one.db"), "current database")
+	writeTestFile(t, filepath.Join(dir, ".ipfs", "keystore", "old"), "old key")
+	writeTestFile(t, filepath.Join(dir, "staging", "one.db"), "restored database")
+	writeTestFile(t, filepath.Join(dir, "staging", "keystore", "new"), "new key")
+
+	// The parent of the last target is a file, so it cannot be replaced
+	writeTestFile(t, filepath.Join(dir, "blocked"), "file")
+
+	err = replaceWithBackup([]backupReplacement{
+		{staged: filepath.Join(dir, "staging", "one.db"), target: filepath.Join(dir, "one.db")},
+		{staged: filepath.Join(dir, "staging", "keystore"), target: filepath.Join(dir, ".ipfs", "keystore")},
+		{staged: filepath.Join(dir, "staging", "keystore"), target: filepath.Join(dir, "blocked", "keystore")},
+	}, filepath.Join(dir, "staging", ".previous"))
+	if err == nil {
+		t.Fatal("replacing under a file should fail")
+	}
+
+	if content := readTestFile(t, filepath.Join(dir, "one.db")); content != "current database" {
+		t.Errorf("database should be rolled back, got %s", content)
+	}
+
+	if content := readTestFile(t, filepath.Join(dir, ".ipfs", "keystore", "old")); content != "old key" {
+		t.Errorf("keys should be rolled back, got %s", content)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, ".ipfs", "keystore", "new")); !os.IsNotExist(err) {
+		t.Error("restored keys should be rolled back")
+	}
+}
+
+// newDemoTramonto returns an instance storing the tests in the database of the
+// path and the contents in memory, with the store started but without Setup
+func newDemoTramonto(t *testing.T, path string) *TramontoOne {
+	t.Helper()
+
+	if err := os.MkdirAll(path, 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	one, err := NewTramontoOneWithStorage(path, StorageDemo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := one.db.MigrateTables(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := one.ipfs.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	return one
+}
+
+func TestExportAndRestoreBackup(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tramonto-backup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	profile := filepath.Join(dir, "profile")
+	device := filepath.Join(dir, "device")
+
+	one := newDemoTramonto(t, profile)
+
+	data, err := one.CreateTest("", "Login crash")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	created := unmarshalTest(t, data)
+
+	// The keys of the repo are files of the IPFS repo
+	writeTestFile(t, filepath.Join(oneIpfs.RepoPath(profile), "keystore", created.Metadata.ID), "owner key")
+
+	backupPath, err := one.ExportBackup("passphrase", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if filepath.Dir(backupPath) != filepath.Join(profile, backupsDirectory) || !strings.HasSuffix(backupPath, backupExtension) {
+		t.Errorf("the backup should be exported to the backups directory, got %s", backupPath)
+	}
+
+	restored := newDemoTramonto(t, device)
+	writeTestFile(t, filepath.Join(oneIpfs.RepoPath(device), "keystore", "self"), "device key")
+
+	// A wrong passphrase changes nothing
+	if err := restored.RestoreBackup("wrong", backupPath); err == nil {
+		t.Fatal("a wrong passphrase should not restore the backup")
+	}
+
+	if tests := findMemoryTests(t, restored); len(tests) != 0 {
+		t.Fatalf("a failed restore should not change the tests, got %+v", tests)
+	}
+
+	if err := restored.RestoreBackup("passphrase", backupPath); err != nil {
+		t.Fatal(err)
+	}
+
+	test, err := restored.tests.FindTestByIpns(created.Ipns)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if test.Ipfs != created.Ipfs || test.Secret != created.Secret || !test.IsOwner {
+		t.Errorf("expected the test of the backup, got %+v", test)
+	}
+
+	if content := readTestFile(t, filepath.Join(oneIpfs.RepoPath(device), "keystore", created.Metadata.ID)); content != "owner key" {
+		t.Errorf("the keys should be restored, got %s", content)
+	}
+
+	if _, err := os.Stat(filepath.Join(oneIpfs.RepoPath(device), "keystore", "self")); !os.IsNotExist(err) {
+		t.Error("the keys of the device should be replaced")
+	}
+}
@@ -0,0 +1,55 @@
+package tramonto
+
+import (
+	"errors"
+
+	"gitlab.com/tramonto-one/go-tramonto/entities"
+)
+
+// readMetadata reads the metadata of an IPFS hash
+// IPFS contents are immutable, so the cached metadata is used when available
+// The cache is keyed by the secret too, so a wrong secret does not read it
+func (t *TramontoOne) readMetadata(ipfsHash, secret string) (entities.Metadata, error) {
+	metadata, cached, err := t.db.FindCachedMetadata(ipfsHash, secret)
+	if err != nil {
+		return entities.Metadata{}, errors.New("(Database) " + err.Error())
+	}
+
+	if cached {
+		return metadata, nil
+	}
+
+	metadata, err = t.ipfs.GetTestByIPFS(ipfsHash, secret)
+	if err != nil {
+		return entities.Metadata{}, err
+	}
+
+	if err = t.db.CacheMetadata("", ipfsHash, secret, metadata); err != nil {
+		return entities.Metadata{}, errors.New("(Database) " + err.Error())
+	}
+
+	return metadata, nil
+}
+
+// cacheTest caches the metadata of the current revision of a test
+// with its artifacts and members
+// The artifact contents and comments not indexed yet are indexed in background
+func (t *TramontoOne) cacheTest(ipnsHash, ipfsHash, secret string, metadata entities.Metadata) error {
+	if err := t.db.CacheMetadata(ipnsHash, ipfsHash, secret, metadata); err != nil {
+		return errors.New("(Database) Error caching test: " + err.Error())
+	}
+
+	t.requestJob(entities.JobTypeIndex)
+
+	return nil
+}
+
+// attachCachedDetails fills the listed tests with their cached artifacts and members
+// The test repository only stores the tests, the caches are in the database
+func (t *TramontoOne) attachCachedDetails(tests []entities.Test) error {
+	if err := t.db.AttachCachedDetails(tests); err != nil {
+		return errors.New("(Database) Error finding test details: " + err.Error())
+	}
+
+	return nil
+}
@@ -0,0 +1,207 @@
+package tramonto
+
+import (
+	"encoding/json"
+	"errors"
+
+	"gitlab.com/tramonto-one/go-tramonto/entities"
+)
+
+// signComment creates a comment of the member of the device, signed with its key
+func (t *TramontoOne) signComment(metadata entities.Metadata, artifactHash, parentID, body string) (entities.Comment, error) {
+	author, privateKey, err := t.deviceMember(metadata)
+	if err != nil {
+		return entities.Comment{}, err
+	}
+
+	comment, err := entities.NewComment(artifactHash, parentID, author.Email, body, privateKey, author.PublicKey)
+	if err != nil {
+		return entities.Comment{}, errors.New("Error creating comment: " + err.Error())
+	}
+
+	// Validates the comment before uploading it
+	if err = metadata.ValidateComment(comment); err != nil {
+		return entities.Comment{}, errors.New("Error adding comment: " + err.Error())
+	}
+
+	return comment, nil
+}
+
+// linkComment links a comment uploaded to IPFS to the test and publishes it
+func (t *TramontoOne) linkComment(databaseTest entities.Test, baseMetadata entities.Metadata, comment entities.Comment, commentHash string) ([]byte, error) {
+	metadata := baseMetadata
+	if err := metadata.AddComment(comment, commentHash); err != nil {
+		return nil, errors.New("Error adding comment: " + err.Error())
+	}
+
+	if err := t.appendActivity(&metadata, entities.ActivityAddComment, comment.ID); err != nil {
+		return nil, err
+	}
+
+	// Publishes the new revision
+	if _, _, err := t.publishMetadata(databaseTest, baseMetadata, metadata); err != nil {
+		return nil, err
+	}
+
+	if err := t.db.IndexComment(databaseTest.Ipns, comment); err != nil {
+		return nil, errors.New("(Database) Error indexing comment: " + err.Error())
+	}
+
+	jsonData, err := json.Marshal(comment)
+	if err != nil {
+		return nil, errors.New("Error parsing to json: " + err.Error())
+	}
+
+	return jsonData, nil
+}
+
+// AddComment adds a comment of the member of the device to a test it owns
+// An empty artifactHash comments on the test and parentID replies to another comment
+// The author is the member whose key is the signing key of the device
+func (t *TramontoOne) AddComment(ipnsHash, artifactHash, parentID, body string) ([]byte, error) {
+	// Gets the test from database
+	databaseTest, err := t.tests.FindTestByIpns(ipnsHash)
+	if err != nil {
+		return nil, errors.New("(Database) Could not find test: " + err.Error())
+	}
+
+	// Verifies if the user is the owner
+	if !databaseTest.IsOwner {
+		return nil, errors.New("User is not owner of this test, sign the comment with SignComment")
+	}
+
+	// Get Metadata from IPFS
+	baseMetadata, err := t.readMetadata(databaseTest.Ipfs, databaseTest.Secret)
+	if err != nil {
+		return nil, errors.New("(IPFS) Cannot read from IPFS: " + err.Error())
+	}
+
+	comment, err := t.signComment(baseMetadata, artifactHash, parentID, body)
+	if err != nil {
+		return nil, err
+	}
+
+	// Uploads the encrypted comment
+	commentHash, err := t.ipfs.UploadComment(comment, databaseTest.Secret)
+	if err != nil {
+		return nil, errors.New("(IPFS) Could not upload comment: " + err.Error())
+	}
+
+	return t.linkComment(databaseTest, baseMetadata, comment, commentHash)
+}
+
+// SignComment writes a comment of the member of the device to a test, without owning it
+// The signed comment is uploaded encrypted to IPFS, the owner links it with AddSignedComment
+// Returns the JSON of the link of the comment, with its IPFS hash
+func (t *TramontoOne) SignComment(ipnsHash, artifactHash, parentID, body string) ([]byte, error) {
+	// Gets the test from database
+	databaseTest, err := t.tests.FindTestByIpns(ipnsHash)
+	if err != nil {
+		return nil, errors.New("(Database) Could not find test: " + err.Error())
+	}
+
+	// Get Metadata from IPFS
+	metadata, err := t.readMetadata(databaseTest.Ipfs, databaseTest.Secret)
+	if err != nil {
+		return nil, errors.New("(IPFS) Cannot read from IPFS: " + err.Error())
+	}
+
+	comment, err := t.signComment(metadata, artifactHash, parentID, body)
+	if err != nil {
+		return nil, err
+	}
+
+	// Uploads the encrypted comment
+	commentHash, err := t.ipfs.UploadComment(comment, databaseTest.Secret)
+	if err != nil {
+		return nil, errors.New("(IPFS) Could not upload comment: " + err.Error())
+	}
+
+	jsonData, err := json.Marshal(entities.CommentLink{
+		ID:           comment.ID,
+		ParentID:     comment.ParentID,
+		ArtifactHash: comment.ArtifactHash,
+		Author:       comment.Author,
+		Hash:         commentHash,
+		CreatedAt:    comment.CreatedAt,
+	})
+	if err != nil {
+		return nil, errors.New("Error parsing to json: " + err.Error())
+	}
+
+	return jsonData, nil
+}
+
+// AddSignedComment links a comment signed by a member on another device to a test
+// The comment is read from IPFS and verified with the key registered to its author
+func (t *TramontoOne) AddSignedComment(ipnsHash, commentHash string) ([]byte, error) {
+	// Gets the test from database
+	databaseTest, err := t.tests.FindTestByIpns(ipnsHash)
+	if err != nil {
+		return nil, errors.New("(Database) Could not find test: " + err.Error())
+	}
+
+	// Verifies if the user is the owner
+	if !databaseTest.IsOwner {
+		return nil, errors.New("User is not owner of this test")
+	}
+
+	// Get Metadata from IPFS
+	baseMetadata, err := t.readMetadata(databaseTest.Ipfs, databaseTest.Secret)
+	if err != nil {
+		return nil, errors.New("(IPFS) Cannot read from IPFS: " + err.Error())
+	}
+
+	comment, err := t.ipfs.ReadComment(commentHash, databaseTest.Secret)
+	if err != nil {
+		return nil, errors.New("(IPFS) Could not read comment: " + err.Error())
+	}
+
+	return t.linkComment(databaseTest, baseMetadata, comment, commentHash)
+}
+
+// ListComments lists the comment threads of a test
+// An empty artifactHash lists the comments on the test
+// Comments not matching their link or not signed with the key registered to
+// their author are left out, as the comments that cannot be read yet
+func (t *TramontoOne) ListComments(ipnsHash, artifactHash string) ([]byte, error) {
+	// Gets the test from database
+	databaseTest, err := t.tests.FindTestByIpns(ipnsHash)
+	if err != nil {
+		return nil, errors.New("(Database) Could not find test: " + err.Error())
+	}
+
+	// Get Metadata from IPFS
+	metadata, err := t.readMetadata(databaseTest.Ipfs, databaseTest.Secret)
+	if err != nil {
+		return nil, errors.New("(IPFS) Cannot read from IPFS: " + err.Error())
+	}
+
+	// Reads each comment
+	comments := []entities.Comment{}
+	for _, link := range metadata.CommentsOf(artifactHash) {
+		// The block of the comment may not have reached the node yet
+		comment, err := t.ipfs.ReadComment(link.Hash, databaseTest.Secret)
+		if err != nil {
+			continue
+		}
+
+		if !metadata.VerifyComment(link, comment) {
+			continue
+		}
+
+		// Comments of other members are indexed when read
+		if err = t.db.IndexComment(ipnsHash, comment); err != nil {
+			return nil, errors.New("(Database) Error indexing comment: " + err.Error())
+		}
+
+		comments = append(comments, comment)
+	}
+
+	jsonData, err := json.Marshal(entities.BuildCommentThreads(comments))
+	if err != nil {
+		return nil, errors.New("Error parsing to json: " + err.Error())
+	}
+
+	return jsonData, nil
+}
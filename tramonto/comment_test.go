@@ -0,0 +1,158 @@
+package tramonto
+
+import (
+	"encoding/json"
+	"testing"
+
+	"gitlab.com/tramonto-one/go-tramonto/entities"
+	oneIpfs "gitlab.com/tramonto-one/go-tramonto/ipfs"
+)
+
+func TestMembersCommentWithTheirKeys(t *testing.T) {
+	network := oneIpfs.NewMemoryNetwork()
+	owner := newMemoryTramonto(t, network)
+	device := newMemoryTramonto(t, network)
+
+	data, err := owner.CreateTest("", "Release 1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	test := unmarshalTest(t, data)
+
+	if _, err := owner.AddComment(test.Ipns, "", "", "Starting the run"); err == nil {
+		t.Error("a device without a member key should not comment")
+	}
+
+	registerMemberKey(t, owner, owner, test.Ipns, "John", "john@tramonto.one")
+	registerMemberKey(t, owner, device, test.Ipns, "Mary", "mary@tramonto.one")
+
+	data, err = owner.AddComment(test.Ipns, "", "", "Starting the run")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first := entities.Comment{}
+	if err := json.Unmarshal(data, &first); err != nil {
+		t.Fatal(err)
+	}
+
+	if first.Author != "john@tramonto.one" {
+		t.Errorf("the author should be the member of the device key, got %s", first.Author)
+	}
+
+	if _, err := device.ImportTest(test.Ipns, test.Secret); err != nil {
+		t.Fatal(err)
+	}
+
+	// The member replies on its device and the owner links the reply
+	data, err = device.SignComment(test.Ipns, "", first.ID, "Crashes on start")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	link := entities.CommentLink{}
+	if err := json.Unmarshal(data, &link); err != nil {
+		t.Fatal(err)
+	}
+
+	if link.Author != "mary@tramonto.one" || link.Hash == "" {
+		t.Fatalf("unexpected comment link %+v", link)
+	}
+
+	if _, err := owner.AddSignedComment(test.Ipns, link.Hash); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err = owner.ListComments(test.Ipns, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	threads := []entities.CommentThread{}
+	if err := json.Unmarshal(data, &threads); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(threads) != 1 || len(threads[0].Replies) != 1 || threads[0].Replies[0].Author != "mary@tramonto.one" {
+		t.Errorf("expected the reply of mary, got %+v", threads)
+	}
+}
+
+func TestListCommentsLeavesOutForgedAndUnreadableComments(t *testing.T) {
+	network := oneIpfs.NewMemoryNetwork()
+	owner := newMemoryTramonto(t, network)
+
+	data, err := owner.CreateTest("", "Release 1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	test := unmarshalTest(t, data)
+	registerMemberKey(t, owner, owner, test.Ipns, "John", "john@tramonto.one")
+
+	data, err = owner.AddComment(test.Ipns, "", "", "Starting the run")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	comment := entities.Comment{}
+	if err := json.Unmarshal(data, &comment); err != nil {
+		t.Fatal(err)
+	}
+
+	stored, err := owner.tests.FindTestByIpns(test.Ipns)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	base, err := owner.readMetadata(stored.Ipfs, stored.Secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A comment with its body changed after signing
+	tampered := comment
+	tampered.ID = "tampered"
+	tampered.Body = "Approved by John"
+	tamperedHash, err := owner.ipfs.UploadComment(tampered, stored.Secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A link to the genuine comment under another ID
+	edited := base
+	edited.Comments = append(append([]entities.CommentLink{}, base.Comments...),
+		entities.CommentLink{ID: tampered.ID, Author: tampered.Author, Hash: tamperedHash, CreatedAt: tampered.CreatedAt},
+		entities.CommentLink{ID: "copied", Author: comment.Author, Hash: base.Comments[0].Hash, CreatedAt: comment.CreatedAt},
+		// A comment whose block cannot be read
+		entities.CommentLink{ID: "unreadable", Author: comment.Author, Hash: "QmUnreadable", CreatedAt: comment.CreatedAt},
+	)
+
+	if _, _, err := owner.publishMetadata(stored, base, edited); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err = owner.ListComments(test.Ipns, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	threads := []entities.CommentThread{}
+	if err := json.Unmarshal(data, &threads); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(threads) != 1 || threads[0].ID != comment.ID {
+		t.Errorf("expected only the genuine comment, got %+v", threads)
+	}
+
+	// The comments left out are not found either
+	if _, err := owner.indexTests(); err != nil {
+		t.Fatal(err)
+	}
+
+	if results := search(t, owner, "Approved"); len(results) != 0 {
+		t.Errorf("the forged comment should not be found, got %+v", results)
+	}
+}
@@ -0,0 +1,49 @@
+package tramonto
+
+import "errors"
+
+// ConflictError is returned when a test keeps being published by another
+// device or co-owner while this node tries to publish its own edit
+type ConflictError struct {
+	// IPNS hash of the test
+	Ipns string
+
+	// IPFS hash the edit was merged with
+	Expected string
+
+	// IPFS hash the IPNS points to
+	Current string
+}
+
+// Error returns the message of the conflict
+func (e *ConflictError) Error() string {
+	return "(Conflict) Test " + e.Ipns + " moved from " + e.Expected + " to " + e.Current + " while publishing"
+}
+
+// IsConflictError returns if the error is a publishing conflict
+func IsConflictError(err error) bool {
+	var conflict *ConflictError
+	return errors.As(err, &conflict)
+}
+
+// networkError is returned when a publish fails to reach the network
+// The edit is kept in the outbox to be published later
+type networkError struct {
+	err error
+}
+
+// Error returns the message of the underlying error
+func (e *networkError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the underlying error
+func (e *networkError) Unwrap() error {
+	return e.err
+}
+
+// isNetworkError returns if the error is a failure to reach the network
+func isNetworkError(err error) bool {
+	var network *networkError
+	return errors.As(err, &network)
+}
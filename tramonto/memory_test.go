@@ -0,0 +1,312 @@
+package tramonto
+
+import (
+	"encoding/json"
+	"testing"
+
+	oneCrypto "gitlab.com/tramonto-one/go-tramonto/crypto"
+	oneDb "gitlab.com/tramonto-one/go-tramonto/db"
+	"gitlab.com/tramonto-one/go-tramonto/entities"
+	oneIpfs "gitlab.com/tramonto-one/go-tramonto/ipfs"
+)
+
+// newMemoryTramonto returns an instance storing the tests and the contents in memory
+// connected to the network, with the store started but without Setup
+func newMemoryTramonto(t *testing.T, network *oneIpfs.MemoryNetwork) *TramontoOne {
+	t.Helper()
+
+	store, err := oneIpfs.NewMemoryStore(network)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	one, err := NewTramontoOneWithStores("", oneDb.NewMemoryTestRepository(), store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return one
+}
+
+// unmarshalTest parses a test returned by the instance
+func unmarshalTest(t *testing.T, data []byte) entities.Test {
+	t.Helper()
+
+	test := entities.Test{}
+	if err := json.Unmarshal(data, &test); err != nil {
+		t.Fatal(err)
+	}
+
+	return test
+}
+
+// findMemoryTests lists the active tests of the instance
+func findMemoryTests(t *testing.T, one *TramontoOne) []entities.Test {
+	t.Helper()
+
+	data, err := one.GetTests([]byte(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	page := entities.TestPage{}
+	if err := json.Unmarshal(data, &page); err != nil {
+		t.Fatal(err)
+	}
+
+	return page.Tests
+}
+
+func TestMemoryStoresCreateTests(t *testing.T) {
+	one := newMemoryTramonto(t, oneIpfs.NewMemoryNetwork())
+
+	data, err := one.CreateTest("", "Login crash")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	test := unmarshalTest(t, data)
+	if test.Ipns == "" || !test.IsOwner || test.Metadata.Name != "TR0001" {
+		t.Fatalf("unexpected test %+v", test)
+	}
+
+	if _, err := one.CreateTest("TR0001", ""); err == nil {
+		t.Error("a test with the same name should not be created")
+	}
+
+	data, err = one.AddArtifact(test.Ipns, "logcat.txt", "startup log", []byte("NullPointerException at main"), map[string][]string{
+		"Content-Type": {"text/plain"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	test = unmarshalTest(t, data)
+	if len(test.Metadata.Artifacts) != 1 {
+		t.Fatalf("expected the artifact in the test, got %+v", test.Metadata.Artifacts)
+	}
+
+	// The listing has the cached artifacts of the test
+	tests := findMemoryTests(t, one)
+	if len(tests) != 1 || tests[0].Ipfs != test.Ipfs || len(tests[0].Metadata.Artifacts) != 1 {
+		t.Fatalf("unexpected tests %+v", tests)
+	}
+
+	data, err = one.Search("nullpointerexception")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results := []entities.SearchResult{}
+	if err := json.Unmarshal(data, &results); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) != 1 || results[0].TestName != "TR0001" || results[0].Kind != entities.SearchKindArtifactContent {
+		t.Errorf("expected the artifact content of TR0001, got %+v", results)
+	}
+
+	if err := one.ArchiveTest(test.Ipns); err != nil {
+		t.Fatal(err)
+	}
+
+	if tests := findMemoryTests(t, one); len(tests) != 0 {
+		t.Errorf("an archived test should not be listed, got %+v", tests)
+	}
+
+	if err := one.DeleteTest(test.Ipns); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := one.tests.FindAnyTestByIpns(test.Ipns); err == nil {
+		t.Error("a deleted test should not be found")
+	}
+
+	if _, cached, err := one.db.FindCachedMetadata(test.Ipfs, test.Secret); err != nil || cached {
+		t.Errorf("the metadata of a deleted test should not be cached, got %v %v", cached, err)
+	}
+
+	if _, err := one.ExportBackup("passphrase", false); err == nil {
+		t.Error("tests in memory should not be backed up")
+	}
+}
+
+func TestMemoryStoresImportTests(t *testing.T) {
+	network := oneIpfs.NewMemoryNetwork()
+	owner := newMemoryTramonto(t, network)
+	device := newMemoryTramonto(t, network)
+
+	data, err := owner.CreateTest("", "Login crash")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	created := unmarshalTest(t, data)
+
+	data, err = device.ImportTest(created.Ipns, created.Secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	imported := unmarshalTest(t, data)
+	if imported.IsOwner || imported.AlreadyImported || imported.Ipfs != created.Ipfs {
+		t.Fatalf("unexpected imported test %+v", imported)
+	}
+
+	data, err = owner.AddArtifact(created.Ipns, "screen.png", "", []byte{0x89, 0x50}, map[string][]string{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	edited := unmarshalTest(t, data)
+
+	// Importing again refreshes the test to the published revision
+	data, err = device.ImportTest(created.Ipns, created.Secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	imported = unmarshalTest(t, data)
+	if !imported.AlreadyImported || imported.Ipfs != edited.Ipfs {
+		t.Fatalf("expected the published revision %s, got %+v", edited.Ipfs, imported)
+	}
+
+	tests := findMemoryTests(t, device)
+	if len(tests) != 1 || tests[0].Ipfs != edited.Ipfs || len(tests[0].Metadata.Artifacts) != 1 {
+		t.Fatalf("unexpected tests %+v", tests)
+	}
+
+	if _, err := device.AddArtifact(created.Ipns, "log.txt", "", []byte("log"), map[string][]string{}); err == nil {
+		t.Error("a device not owner of the test should not add artifacts")
+	}
+}
+
+// expectNoKey fails when the instance keeps the named key
+func expectNoKey(t *testing.T, one *TramontoOne, keyName string) {
+	t.Helper()
+
+	exists, _, err := one.ipfs.GetKeyWithName(keyName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if exists {
+		t.Errorf("the key %s should not be kept", keyName)
+	}
+}
+
+func TestMemoryStoresShareTestKeys(t *testing.T) {
+	network := oneIpfs.NewMemoryNetwork()
+	owner := newMemoryTramonto(t, network)
+	device := newMemoryTramonto(t, network)
+
+	data, err := owner.CreateTest("", "Login crash")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	created := unmarshalTest(t, data)
+
+	exported, err := owner.ExportTestKey(created.Ipns, "passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A wrong passphrase imports nothing
+	if _, err := device.ImportTestKey(exported, "wrong"); err == nil {
+		t.Error("the key should not be imported with a wrong passphrase")
+	}
+
+	expectNoKey(t, device, created.Metadata.ID)
+
+	if tests := findMemoryTests(t, device); len(tests) != 0 {
+		t.Fatalf("no test should be imported, got %+v", tests)
+	}
+
+	data, err = device.ImportTestKey(exported, "passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	imported := unmarshalTest(t, data)
+	if !imported.IsOwner || imported.Ipns != created.Ipns {
+		t.Fatalf("expected the test owned by the device, got %+v", imported)
+	}
+
+	// The co-owner publishes to the same IPNS
+	data, err = device.AddArtifact(created.Ipns, "log.txt", "", []byte("log"), map[string][]string{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	edited := unmarshalTest(t, data)
+
+	if published := mustResolve(t, owner.ipfs, created.Ipns); published != edited.Ipfs {
+		t.Errorf("expected the edit of the co-owner published %s, got %s", edited.Ipfs, published)
+	}
+
+	if _, err := owner.syncTests(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := findMemoryTests(t, owner)
+	if len(tests) != 1 || tests[0].Ipfs != edited.Ipfs || len(tests[0].Metadata.Artifacts) != 1 {
+		t.Fatalf("expected the owner to show the edit of the co-owner, got %+v", tests)
+	}
+}
+
+func TestMemoryStoresRejectKeysOfOtherTests(t *testing.T) {
+	network := oneIpfs.NewMemoryNetwork()
+	owner := newMemoryTramonto(t, network)
+	device := newMemoryTramonto(t, network)
+
+	data, err := owner.CreateTest("", "Login crash")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	created := unmarshalTest(t, data)
+
+	data, err = owner.CreateTest("", "Logout crash")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	other := unmarshalTest(t, data)
+
+	otherKey, err := owner.ipfs.ExportKey(other.Metadata.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The key of another test is exported as the key of the created one
+	ownerKey, err := json.Marshal(entities.OwnerKey{
+		Version:    entities.OwnerKeyVersion,
+		Ipns:       created.Ipns,
+		KeyName:    created.Metadata.ID,
+		Secret:     created.Secret,
+		PrivateKey: otherKey,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exported, err := oneCrypto.EncryptWithPassphrase("passphrase", ownerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := device.ImportTestKey(exported, "passphrase"); err == nil {
+		t.Fatal("a key not publishing the test should not be imported")
+	}
+
+	expectNoKey(t, device, created.Metadata.ID)
+
+	if tests := findMemoryTests(t, device); len(tests) != 0 {
+		t.Fatalf("no test should be imported, got %+v", tests)
+	}
+}
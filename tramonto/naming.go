@@ -0,0 +1,36 @@
+package tramonto
+
+import (
+	"errors"
+
+	"gitlab.com/tramonto-one/go-tramonto/entities"
+)
+
+// SetTestNamePrefix configures the prefix of the generated test names
+func (t *TramontoOne) SetTestNamePrefix(prefix string) error {
+	return t.SetStringSetting(entities.SettingTestNamePrefix, prefix)
+}
+
+// uniqueTestName returns a test name not used in the device
+// Generates the next sequential name when name is empty
+func (t *TramontoOne) uniqueTestName(name string) (string, error) {
+	if name == "" {
+		generatedName, err := t.tests.NextTestName(t.currentSettings().TestNamePrefix)
+		if err != nil {
+			return "", errors.New("(Database) Error generating test name: " + err.Error())
+		}
+
+		return generatedName, nil
+	}
+
+	exists, err := t.tests.ExistsTestWithName(name)
+	if err != nil {
+		return "", errors.New("(Database) Error verifying test name: " + err.Error())
+	}
+
+	if exists {
+		return "", errors.New("A test named " + name + " already exists")
+	}
+
+	return name, nil
+}
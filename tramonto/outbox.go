@@ -0,0 +1,175 @@
+package tramonto
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"gitlab.com/tramonto-one/go-tramonto/entities"
+)
+
+// drainOutbox publishes the due operations of the outbox in order
+// When an operation fails, the following operations of the same test wait for it
+// An operation failing MaxOutboxAttempts times for reasons other than the
+// network is given up, so the following operations can be published, and
+// they publish its edit too
+// The first publish of a test, made by ShareTest, is out of scope: a test that
+// could not be shared has no IPNS yet, and is shared again calling ShareTest
+func (t *TramontoOne) drainOutbox() (string, error) {
+	if !t.ipfs.IsOnline() {
+		return entities.JobResultSkipped, nil
+	}
+
+	operations, err := t.db.FindPendingOperations()
+	if err != nil {
+		return "", errors.New("(Database) " + err.Error())
+	}
+
+	blocked := map[string]bool{}
+	now := time.Now()
+	failed := 0
+	var lastErr error
+
+	for _, operation := range operations {
+		if blocked[operation.TestIpns] {
+			continue
+		}
+
+		if operation.NextAttemptAt.After(now) {
+			blocked[operation.TestIpns] = true
+			continue
+		}
+
+		if syncErr := t.syncOperation(operation); syncErr != nil {
+			if err := t.failOperation(operation, syncErr); err != nil {
+				return "", err
+			}
+
+			blocked[operation.TestIpns] = true
+			failed++
+			lastErr = syncErr
+			continue
+		}
+
+		if err := t.db.CompleteOperation(operation.ID); err != nil {
+			return "", errors.New("(Database) " + err.Error())
+		}
+	}
+
+	if failed > 0 {
+		return "", fmt.Errorf("Could not publish %d operations: %s", failed, lastErr.Error())
+	}
+
+	return entities.JobResultSuccess, nil
+}
+
+// failOperation registers a failed attempt of an operation, giving it up
+// when it ran out of attempts
+func (t *TramontoOne) failOperation(operation entities.OutboxOperation, reason error) error {
+	var err error
+	if !isNetworkError(reason) && operation.Attempts+1 >= entities.MaxOutboxAttempts {
+		err = t.db.GiveUpOperation(operation.ID, reason.Error())
+	} else {
+		err = t.db.FailOperation(operation.ID, reason.Error())
+	}
+
+	if err != nil {
+		return errors.New("(Database) " + err.Error())
+	}
+
+	return nil
+}
+
+// syncOperation publishes an operation of the outbox
+func (t *TramontoOne) syncOperation(operation entities.OutboxOperation) error {
+	if operation.Kind != entities.OperationPublishRevision {
+		return errors.New("Unknown operation " + operation.Kind)
+	}
+
+	test, err := t.tests.FindTestByIpns(operation.TestIpns)
+	if err == sql.ErrNoRows {
+		// Edits of archived tests are published too
+		test, err = t.tests.FindAnyTestByIpns(operation.TestIpns)
+	}
+
+	if err == sql.ErrNoRows {
+		// The test was deleted, there is nothing to publish
+		return nil
+	}
+
+	if err != nil {
+		return errors.New("(Database) Could not find test: " + err.Error())
+	}
+
+	// A revision that cannot be read may be unreachable for now, so the edit is not given up
+	base, err := t.readMetadata(operation.BaseIpfs, test.Secret)
+	if err != nil {
+		return &networkError{errors.New("(IPFS) Cannot read base revision: " + err.Error())}
+	}
+
+	edited, err := t.readMetadata(operation.EditedIpfs, test.Secret)
+	if err != nil {
+		return &networkError{errors.New("(IPFS) Cannot read edited revision: " + err.Error())}
+	}
+
+	// The edit is published as if it was made over the base revision
+	test.Ipfs = operation.BaseIpfs
+
+	publishedIpfsHash, metadata, err := t.publishRevision(test, base, edited)
+	if err != nil {
+		return err
+	}
+
+	// The test keeps pointing to the newer local edits until they are published
+	later, err := t.db.HasPendingOperationsAfter(operation.TestIpns, operation.ID)
+	if err != nil {
+		return errors.New("(Database) " + err.Error())
+	}
+
+	if later {
+		return nil
+	}
+
+	return t.updateLocalRevision(test, publishedIpfsHash, metadata)
+}
+
+// GetSyncStatus returns the operations pending sync with the network
+// and the ones given up
+func (t *TramontoOne) GetSyncStatus() ([]byte, error) {
+	operations, err := t.db.FindPendingOperations()
+	if err != nil {
+		return nil, errors.New("(Database) " + err.Error())
+	}
+
+	failed, err := t.db.FindFailedOperations()
+	if err != nil {
+		return nil, errors.New("(Database) " + err.Error())
+	}
+
+	status := entities.SyncStatus{
+		Online:     t.ipfs.IsOnline(),
+		Pending:    len(operations),
+		Operations: operations,
+		Failed:     failed,
+	}
+
+	jsonData, err := json.Marshal(status)
+	if err != nil {
+		return nil, errors.New("Error parsing to json: " + err.Error())
+	}
+
+	return jsonData, nil
+}
+
+// RetrySyncOperation tries again an operation given up
+func (t *TramontoOne) RetrySyncOperation(id int64) error {
+	if err := t.db.RetryOperation(id); err != nil {
+		return errors.New("(Database) " + err.Error())
+	}
+
+	t.requestJob(entities.JobTypeOutbox)
+
+	return nil
+}
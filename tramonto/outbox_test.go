@@ -0,0 +1,238 @@
+package tramonto
+
+import (
+	"errors"
+	"testing"
+
+	oneDb "gitlab.com/tramonto-one/go-tramonto/db"
+	"gitlab.com/tramonto-one/go-tramonto/entities"
+	oneIpfs "gitlab.com/tramonto-one/go-tramonto/ipfs"
+)
+
+// flakyStore is a memory store failing the IPNS publishes once the allowed ones
+// are used, a negative count allows every publish
+type flakyStore struct {
+	*oneIpfs.MemoryStore
+	publishes int
+}
+
+// PublishToIPNS publishes the hash while publishes are allowed
+func (s *flakyStore) PublishToIPNS(ipfsHash, keyName string) (string, error) {
+	if s.publishes == 0 {
+		return "", errors.New("Error publishing IPNS: timeout")
+	}
+
+	s.publishes--
+
+	return s.MemoryStore.PublishToIPNS(ipfsHash, keyName)
+}
+
+func TestDrainOutboxKeepsTheLocalEdits(t *testing.T) {
+	store, err := oneIpfs.NewMemoryStore(oneIpfs.NewMemoryNetwork())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	flaky := &flakyStore{MemoryStore: store, publishes: -1}
+
+	one, err := NewTramontoOneWithStores("", oneDb.NewMemoryTestRepository(), flaky)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := one.CreateTest("", "Login crash")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	created := unmarshalTest(t, data)
+
+	// Both edits are queued while offline
+	store.SetOnline(false)
+
+	if _, err := one.AddArtifact(created.Ipns, "first.txt", "", []byte("first"), map[string][]string{}); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err = one.AddArtifact(created.Ipns, "second.txt", "", []byte("second"), map[string][]string{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	edited := unmarshalTest(t, data)
+
+	// The first edit is published and the second fails
+	store.SetOnline(true)
+	flaky.publishes = 1
+
+	if _, err := one.drainOutbox(); err == nil {
+		t.Fatal("the second edit should fail to publish")
+	}
+
+	published, err := one.readMetadata(mustResolve(t, store, created.Ipns), created.Secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(published.Artifacts) != 1 {
+		t.Errorf("expected the first edit published, got %+v", published.Artifacts)
+	}
+
+	test, err := one.tests.FindTestByIpns(created.Ipns)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if test.Ipfs != edited.Ipfs {
+		t.Errorf("the test should point to the pending edit %s, got %s", edited.Ipfs, test.Ipfs)
+	}
+
+	data, err = one.GetTestByIPNS(created.Ipns, created.Secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if shown := unmarshalTest(t, data); len(shown.Metadata.Artifacts) != 2 {
+		t.Errorf("expected the pending edit shown, got %+v", shown.Metadata.Artifacts)
+	}
+
+	// Publishing the last edit moves the test to the published revision
+	operations, err := one.db.FindPendingOperations()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(operations) != 1 {
+		t.Fatalf("expected the second edit pending, got %+v", operations)
+	}
+
+	flaky.publishes = -1
+
+	if err := one.syncOperation(operations[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	if test, err = one.tests.FindTestByIpns(created.Ipns); err != nil {
+		t.Fatal(err)
+	}
+
+	if current := mustResolve(t, store, created.Ipns); test.Ipfs != current {
+		t.Errorf("the test should point to the published revision %s, got %s", current, test.Ipfs)
+	}
+}
+
+// mustResolve returns the IPFS hash the IPNS points to in the store
+func mustResolve(t *testing.T, store oneIpfs.ContentStore, ipns string) string {
+	t.Helper()
+
+	ipfsHash, err := store.ResolveIPNS(ipns)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return ipfsHash
+}
+
+func TestDrainOutboxPublishesTheEditsGivenUpWithTheNextOnes(t *testing.T) {
+	store := newStartedMemoryStore(t)
+
+	one, err := NewTramontoOneWithStores("", oneDb.NewMemoryTestRepository(), store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := one.CreateTest("", "Login crash")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	created := unmarshalTest(t, data)
+
+	store.SetOnline(false)
+
+	if _, err := one.AddArtifact(created.Ipns, "first.txt", "", []byte("first"), map[string][]string{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := one.AddArtifact(created.Ipns, "second.txt", "", []byte("second"), map[string][]string{}); err != nil {
+		t.Fatal(err)
+	}
+
+	store.SetOnline(true)
+
+	operations, err := one.db.FindPendingOperations()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The first edit runs out of attempts
+	first := operations[0]
+	first.Attempts = entities.MaxOutboxAttempts - 1
+
+	if err := one.failOperation(first, errors.New("invalid revision")); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := one.drainOutbox(); err != nil {
+		t.Fatal(err)
+	}
+
+	published, err := one.readMetadata(mustResolve(t, store, created.Ipns), created.Secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if names := artifactNames(published); !names["first.txt"] || !names["second.txt"] {
+		t.Errorf("expected the edit given up published with the next one, got %+v", names)
+	}
+}
+
+func TestDrainOutboxRetriesTheRevisionsNotRead(t *testing.T) {
+	store := newStartedMemoryStore(t)
+
+	one, err := NewTramontoOneWithStores("", oneDb.NewMemoryTestRepository(), store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := one.CreateTest("", "Login crash")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	created := unmarshalTest(t, data)
+
+	if err := one.db.EnqueueOperation(entities.OutboxOperation{
+		Kind:       entities.OperationPublishRevision,
+		TestIpns:   created.Ipns,
+		BaseIpfs:   created.Ipfs,
+		EditedIpfs: "QmUnreachable",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	operations, err := one.db.FindPendingOperations()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	last := operations[0]
+	last.Attempts = entities.MaxOutboxAttempts - 1
+
+	syncErr := one.syncOperation(last)
+	if syncErr == nil {
+		t.Fatal("an edit not read should not be published")
+	}
+
+	if err := one.failOperation(last, syncErr); err != nil {
+		t.Fatal(err)
+	}
+
+	if pending, err := one.db.HasPendingOperations(created.Ipns); err != nil || !pending {
+		t.Errorf("an edit not read should be retried, got %v %v", pending, err)
+	}
+}
@@ -0,0 +1,126 @@
+package tramonto
+
+import (
+	"encoding/json"
+	"errors"
+
+	oneCrypto "gitlab.com/tramonto-one/go-tramonto/crypto"
+	"gitlab.com/tramonto-one/go-tramonto/entities"
+)
+
+// ExportTestKey exports the IPNS key of an owned test encrypted with the passphrase
+// Another device importing it becomes co-owner of the test and can publish updates
+func (t *TramontoOne) ExportTestKey(ipnsHash, passphrase string) ([]byte, error) {
+	if passphrase == "" {
+		return nil, errors.New("Passphrase is required")
+	}
+
+	test, err := t.tests.FindTestByIpns(ipnsHash)
+	if err != nil {
+		return nil, errors.New("(Database) Test not found: " + err.Error())
+	}
+
+	// Verifies if the user is the owner
+	if !test.IsOwner {
+		return nil, errors.New("User is not owner of this test")
+	}
+
+	// The key is named after the test ID
+	metadata, err := t.readMetadata(test.Ipfs, test.Secret)
+	if err != nil {
+		return nil, errors.New("(IPFS) Test not found: " + err.Error())
+	}
+
+	privateKey, err := t.ipfs.ExportKey(metadata.ID)
+	if err != nil {
+		return nil, errors.New("(IPFS) Error exporting key: " + err.Error())
+	}
+
+	ownerKey, err := json.Marshal(entities.OwnerKey{
+		Version:    entities.OwnerKeyVersion,
+		Ipns:       test.Ipns,
+		KeyName:    metadata.ID,
+		Secret:     test.Secret,
+		PrivateKey: privateKey,
+	})
+	if err != nil {
+		return nil, errors.New("Error parsing to json: " + err.Error())
+	}
+
+	encrypted, err := oneCrypto.EncryptWithPassphrase(passphrase, ownerKey)
+	if err != nil {
+		return nil, errors.New("Error encrypting key: " + err.Error())
+	}
+
+	return encrypted, nil
+}
+
+// ImportTestKey imports the IPNS key exported by another device of the owner
+// The test is imported when it is not in the node yet and marked as owned
+// Returns the test
+func (t *TramontoOne) ImportTestKey(exportedKey []byte, passphrase string) ([]byte, error) {
+	decrypted, err := oneCrypto.DecryptWithPassphrase(passphrase, exportedKey)
+	if err != nil {
+		return nil, errors.New("Error decrypting key: " + err.Error())
+	}
+
+	ownerKey, err := entities.OwnerKeyFromJSON(decrypted)
+	if err != nil {
+		return nil, err
+	}
+
+	keyExisted, _, err := t.ipfs.GetKeyWithName(ownerKey.KeyName)
+	if err != nil {
+		return nil, errors.New("(IPFS) Error finding key: " + err.Error())
+	}
+
+	ipnsHash, err := t.ipfs.ImportKey(ownerKey.KeyName, ownerKey.PrivateKey)
+	if err != nil {
+		return nil, errors.New("(IPFS) Error importing key: " + err.Error())
+	}
+
+	test, err := t.importOwnedTest(ownerKey, ipnsHash)
+	if err != nil {
+		// A key imported for a test that could not be owned is removed
+		if !keyExisted {
+			t.ipfs.RemoveKey(ownerKey.KeyName)
+		}
+
+		return nil, err
+	}
+
+	jsonData, err := json.Marshal(test)
+	if err != nil {
+		return nil, errors.New("Error parsing to json: " + err.Error())
+	}
+
+	return jsonData, nil
+}
+
+// importOwnedTest imports the test published by the imported key and marks it as owned
+func (t *TramontoOne) importOwnedTest(ownerKey entities.OwnerKey, ipnsHash string) (entities.Test, error) {
+	// The key must be the one publishing the test
+	if ipnsHash != ownerKey.Ipns {
+		return entities.Test{}, errors.New("The key does not publish the test " + ownerKey.Ipns)
+	}
+
+	if _, err := t.ImportTest(ownerKey.Ipns, ownerKey.Secret); err != nil {
+		return entities.Test{}, err
+	}
+
+	if err := t.tests.SetOwner(ownerKey.Ipns, true); err != nil {
+		return entities.Test{}, errors.New("(Database) Error updating owner: " + err.Error())
+	}
+
+	test, err := t.tests.FindTestByIpns(ownerKey.Ipns)
+	if err != nil {
+		return entities.Test{}, errors.New("(Database) Could not find test: " + err.Error())
+	}
+
+	// The co-owner republishes the test too
+	if err = t.trackIPNSRecord(test.Ipns, ownerKey.KeyName, test.Ipfs); err != nil {
+		return entities.Test{}, err
+	}
+
+	return test, nil
+}
@@ -0,0 +1,160 @@
+package tramonto
+
+import (
+	"errors"
+
+	"gitlab.com/tramonto-one/go-tramonto/entities"
+)
+
+// maxPublishAttempts is how many times a publish is retried when the IPNS moves
+const maxPublishAttempts = 3
+
+// publishMetadata uploads an edited metadata and publishes it to the test IPNS
+// When the network cannot be reached, or earlier edits of the test are still
+// pending, the edit is uploaded and kept in the outbox to be published later
+// On a ConflictError the edit is dropped and the test keeps the revision it had,
+// the next edit merges the revisions published meanwhile
+// Returns the new IPFS hash and the metadata
+func (t *TramontoOne) publishMetadata(test entities.Test, base, edited entities.Metadata) (string, entities.Metadata, error) {
+	pending, err := t.db.HasPendingOperations(test.Ipns)
+	if err != nil {
+		return "", entities.Metadata{}, errors.New("(Database) " + err.Error())
+	}
+
+	if !pending {
+		newIpfsHash, metadata, err := t.publishRevision(test, base, edited)
+		if err == nil {
+			if err := t.updateLocalRevision(test, newIpfsHash, metadata); err != nil {
+				return "", entities.Metadata{}, err
+			}
+
+			return newIpfsHash, metadata, nil
+		}
+
+		if !isNetworkError(err) {
+			return "", entities.Metadata{}, err
+		}
+	}
+
+	return t.enqueueRevision(test, edited)
+}
+
+// enqueueRevision uploads an edited metadata and adds its publish to the outbox
+// The test points locally to the edit until it is published
+func (t *TramontoOne) enqueueRevision(test entities.Test, edited entities.Metadata) (string, entities.Metadata, error) {
+	editedIpfsHash, err := t.ipfs.UploadTest(edited, test.Secret)
+	if err != nil {
+		return "", entities.Metadata{}, errors.New("(IPFS) Error uploading test: " + err.Error())
+	}
+
+	if err = t.db.EnqueueOperation(entities.OutboxOperation{
+		Kind:       entities.OperationPublishRevision,
+		TestIpns:   test.Ipns,
+		BaseIpfs:   test.Ipfs,
+		EditedIpfs: editedIpfsHash,
+	}); err != nil {
+		return "", entities.Metadata{}, errors.New("(Database) " + err.Error())
+	}
+
+	if err = t.updateLocalRevision(test, editedIpfsHash, edited); err != nil {
+		return "", entities.Metadata{}, err
+	}
+
+	t.requestJob(entities.JobTypeOutbox)
+
+	return editedIpfsHash, edited, nil
+}
+
+// publishRevision uploads an edited metadata and publishes it to the test IPNS
+// The edit started from the metadata in base, if the IPNS moved since then
+// the concurrent changes are merged before publishing
+// Right before publishing the IPNS is resolved again and, if it moved, the
+// edit is merged and uploaded again. Gives up with a ConflictError, leaving
+// the test pointing to the revision it had
+// The local revision of the test is not updated, the caller moves it
+// Returns the new IPFS hash and the published metadata
+func (t *TramontoOne) publishRevision(test entities.Test, base, edited entities.Metadata) (string, entities.Metadata, error) {
+	metadata := edited
+	expectedIpfsHash := test.Ipfs
+
+	currentIpfsHash, err := t.resolveTest(test.Ipns)
+	if err != nil {
+		return "", entities.Metadata{}, err
+	}
+
+	for attempt := 0; attempt < maxPublishAttempts; attempt++ {
+		// Someone else published since the edit started
+		if currentIpfsHash != expectedIpfsHash {
+			remote, err := t.readMetadata(currentIpfsHash, test.Secret)
+			if err != nil {
+				return "", entities.Metadata{}, errors.New("(IPFS) Cannot read current revision: " + err.Error())
+			}
+
+			metadata = entities.MergeMetadata(base, edited, remote)
+			expectedIpfsHash = currentIpfsHash
+		}
+
+		// Uploads the test to IPFS
+		newIpfsHash, err := t.ipfs.UploadTest(metadata, test.Secret)
+		if err != nil {
+			return "", entities.Metadata{}, errors.New("(IPFS) Error uploading test: " + err.Error())
+		}
+
+		// Verifies the IPNS still points to the merged revision
+		currentIpfsHash, err = t.resolveTest(test.Ipns)
+		if err != nil {
+			return "", entities.Metadata{}, err
+		}
+
+		if currentIpfsHash != expectedIpfsHash {
+			continue
+		}
+
+		// Publishes the new Metadata to IPNS
+		// We should update the database just after a succeded publish to IPNS
+		if _, err := t.ipfs.PublishToIPNS(newIpfsHash, base.ID); err != nil {
+			t.recordSyncError(test.Ipns, err)
+			return "", entities.Metadata{}, &networkError{errors.New("(IPNS) Error publishing: " + err.Error())}
+		}
+
+		if err = t.recordSync(test.Ipns, newIpfsHash, entities.SyncSourcePublish); err != nil {
+			return "", entities.Metadata{}, err
+		}
+
+		if err = t.trackIPNSRecord(test.Ipns, base.ID, newIpfsHash); err != nil {
+			return "", entities.Metadata{}, err
+		}
+
+		return newIpfsHash, metadata, nil
+	}
+
+	return "", entities.Metadata{}, &ConflictError{
+		Ipns:     test.Ipns,
+		Expected: expectedIpfsHash,
+		Current:  currentIpfsHash,
+	}
+}
+
+// updateLocalRevision points the test in the node to a revision and caches its metadata
+func (t *TramontoOne) updateLocalRevision(test entities.Test, ipfsHash string, metadata entities.Metadata) error {
+	if err := t.tests.UpdateIPFSHash(test.Ipns, ipfsHash); err != nil {
+		return errors.New("(Database) Error updating data: " + err.Error())
+	}
+
+	return t.cacheTest(test.Ipns, ipfsHash, test.Secret, metadata)
+}
+
+// resolveTest returns the IPFS hash currently published in the test IPNS
+func (t *TramontoOne) resolveTest(ipns string) (string, error) {
+	ipfsHash, err := t.ipfs.ResolveIPNS(ipns)
+	if err != nil {
+		t.recordSyncError(ipns, err)
+		return "", &networkError{errors.New("(IPNS) Error resolving test: " + err.Error())}
+	}
+
+	if err = t.recordSync(ipns, ipfsHash, entities.SyncSourceResolve); err != nil {
+		return "", err
+	}
+
+	return ipfsHash, nil
+}
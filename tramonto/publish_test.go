@@ -0,0 +1,173 @@
+package tramonto
+
+import (
+	"fmt"
+	"testing"
+
+	oneDb "gitlab.com/tramonto-one/go-tramonto/db"
+	"gitlab.com/tramonto-one/go-tramonto/entities"
+	oneIpfs "gitlab.com/tramonto-one/go-tramonto/ipfs"
+)
+
+// movingStore is a memory store where another device publishes a revision of
+// the test when it is resolved, once the resolves to skip are done
+type movingStore struct {
+	*oneIpfs.MemoryStore
+	secret string
+	skip   int
+	moves  int
+}
+
+// ResolveIPNS publishes a concurrent revision of the test before resolving it
+func (s *movingStore) ResolveIPNS(hash string) (string, error) {
+	if s.skip > 0 {
+		s.skip--
+	} else if s.moves > 0 {
+		s.moves--
+
+		if err := s.publishConcurrentEdit(hash); err != nil {
+			return "", err
+		}
+	}
+
+	return s.MemoryStore.ResolveIPNS(hash)
+}
+
+// publishConcurrentEdit publishes a revision of the test with a new artifact
+func (s *movingStore) publishConcurrentEdit(hash string) error {
+	_, metadata, err := s.MemoryStore.GetTestByIPNS(hash, s.secret)
+	if err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("concurrent%d.txt", len(metadata.Artifacts))
+	if err := metadata.AddArtifact(name, "", "Qm"+name, map[string][]string{}); err != nil {
+		return err
+	}
+
+	ipfsHash, err := s.MemoryStore.UploadTest(metadata, s.secret)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.MemoryStore.PublishToIPNS(ipfsHash, metadata.ID)
+
+	return err
+}
+
+// newMovingTramonto returns an instance with a created test published concurrently
+func newMovingTramonto(t *testing.T) (*TramontoOne, *movingStore, entities.Test) {
+	t.Helper()
+
+	store, err := oneIpfs.NewMemoryStore(oneIpfs.NewMemoryNetwork())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	moving := &movingStore{MemoryStore: store}
+
+	one, err := NewTramontoOneWithStores("", oneDb.NewMemoryTestRepository(), moving)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := one.CreateTest("", "Login crash")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	test := unmarshalTest(t, data)
+	moving.secret = test.Secret
+
+	return one, moving, test
+}
+
+// artifactNames returns the names of the artifacts of the metadata
+func artifactNames(metadata entities.Metadata) map[string]bool {
+	names := map[string]bool{}
+	for _, artifact := range metadata.Artifacts {
+		names[artifact.Name] = true
+	}
+
+	return names
+}
+
+func TestPublishMergesRevisionsPublishedMeanwhile(t *testing.T) {
+	one, moving, created := newMovingTramonto(t)
+
+	// The IPNS moves between the first resolve and the publish
+	moving.skip = 1
+	moving.moves = 1
+
+	data, err := one.AddArtifact(created.Ipns, "log.txt", "", []byte("log"), map[string][]string{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	edited := unmarshalTest(t, data)
+	if names := artifactNames(edited.Metadata); len(names) != 2 || !names["log.txt"] || !names["concurrent0.txt"] {
+		t.Errorf("expected the edit merged with the concurrent one, got %v", names)
+	}
+
+	if current := mustResolve(t, moving.MemoryStore, created.Ipns); current != edited.Ipfs {
+		t.Errorf("expected the merged revision %s published, got %s", edited.Ipfs, current)
+	}
+
+	test, err := one.tests.FindTestByIpns(created.Ipns)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if test.Ipfs != edited.Ipfs {
+		t.Errorf("the test should point to the published revision %s, got %s", edited.Ipfs, test.Ipfs)
+	}
+}
+
+func TestPublishGivesUpWhenTheRevisionKeepsMoving(t *testing.T) {
+	one, moving, created := newMovingTramonto(t)
+
+	// The IPNS moves before every publish
+	moving.skip = 1
+	moving.moves = maxPublishAttempts
+
+	if _, err := one.AddArtifact(created.Ipns, "log.txt", "", []byte("log"), map[string][]string{}); !IsConflictError(err) {
+		t.Fatalf("expected a conflict, got %v", err)
+	}
+
+	published, err := one.readMetadata(mustResolve(t, moving.MemoryStore, created.Ipns), created.Secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if names := artifactNames(published); len(names) != maxPublishAttempts || names["log.txt"] {
+		t.Errorf("expected only the concurrent edits published, got %v", names)
+	}
+
+	// The test keeps the revision it had and the edit is not queued
+	test, err := one.tests.FindTestByIpns(created.Ipns)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if test.Ipfs != created.Ipfs {
+		t.Errorf("the test should keep the revision %s, got %s", created.Ipfs, test.Ipfs)
+	}
+
+	if pending, err := one.db.HasPendingOperations(created.Ipns); err != nil || pending {
+		t.Errorf("a conflicting edit should not be queued, got %v %v", pending, err)
+	}
+
+	// The next edit merges the revisions published meanwhile
+	data, err := one.AddArtifact(created.Ipns, "log.txt", "", []byte("log"), map[string][]string{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if names := artifactNames(unmarshalTest(t, data).Metadata); len(names) != maxPublishAttempts+1 {
+		t.Errorf("expected the edit merged with the concurrent ones, got %v", names)
+	}
+}
@@ -0,0 +1,166 @@
+package tramonto
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"gitlab.com/tramonto-one/go-tramonto/entities"
+)
+
+// republishCheckInterval is how often the records are checked for republishing
+const republishCheckInterval = time.Hour
+
+// SetRepublishCallback registers the callback invoked with each record that could not be published again
+func (t *TramontoOne) SetRepublishCallback(callback entities.Callback) {
+	t.jobMux.Lock()
+	defer t.jobMux.Unlock()
+
+	t.republishCallback = callback
+}
+
+// trackIPNSRecord registers a publish of an owned test, so it is published again before expiring
+func (t *TramontoOne) trackIPNSRecord(ipnsHash, keyName, ipfsHash string) error {
+	if err := t.db.SaveIPNSRecord(ipnsHash, keyName, ipfsHash); err != nil {
+		return errors.New("(Database) " + err.Error())
+	}
+
+	return nil
+}
+
+// republishRecords publishes again the records published longer than the interval ago
+func (t *TramontoOne) republishRecords() (string, error) {
+	if !t.ipfs.IsOnline() {
+		return entities.JobResultSkipped, nil
+	}
+
+	records, err := t.db.FindIPNSRecords()
+	if err != nil {
+		return "", errors.New("(Database) " + err.Error())
+	}
+
+	interval := time.Duration(t.currentSettings().RepublishIntervalHours) * time.Hour
+	now := time.Now()
+	failed := 0
+	var lastErr error
+
+	for _, record := range records {
+		if !record.NeedsRepublish(now, interval) {
+			continue
+		}
+
+		ipfsHash, err := t.republishRecord(record)
+		if err != nil {
+			t.recordSyncError(record.IpnsHash, err)
+			t.notifyRepublishFailure(record, err)
+
+			if err := t.db.RecordRepublishError(record.IpnsHash, err.Error()); err != nil {
+				return "", errors.New("(Database) " + err.Error())
+			}
+
+			failed++
+			lastErr = err
+			continue
+		}
+
+		// The revision of another owner is shown too, a failure is kept in the test sync error
+		// and the sync job tries again
+		if ipfsHash != record.IpfsHash {
+			if err := t.refreshRepublishedTest(record.IpnsHash); err != nil {
+				t.recordSyncError(record.IpnsHash, err)
+
+				failed++
+				lastErr = err
+			}
+		}
+	}
+
+	if failed > 0 {
+		return "", fmt.Errorf("Could not republish or refresh %d tests: %s", failed, lastErr.Error())
+	}
+
+	return entities.JobResultSuccess, nil
+}
+
+// republishRecord publishes again the latest revision of a record
+// Another owner may have published a newer revision, so the IPNS is resolved first
+// Returns the revision published
+func (t *TramontoOne) republishRecord(record entities.IPNSRecord) (string, error) {
+	exists, _, err := t.ipfs.GetKeyWithName(record.KeyName)
+	if err != nil {
+		return "", errors.New("(IPFS) Error finding key: " + err.Error())
+	}
+
+	// Publishing without the key would generate a new IPNS
+	if !exists {
+		return "", errors.New("(IPFS) Key " + record.KeyName + " not found")
+	}
+
+	// An expired record cannot be resolved, then the known revision is published
+	ipfsHash := record.IpfsHash
+	if currentIpfsHash, err := t.ipfs.ResolveIPNS(record.IpnsHash); err == nil {
+		ipfsHash = currentIpfsHash
+	}
+
+	if _, err := t.ipfs.PublishToIPNS(ipfsHash, record.KeyName); err != nil {
+		return "", errors.New("(IPNS) Error republishing: " + err.Error())
+	}
+
+	if err := t.recordSync(record.IpnsHash, ipfsHash, entities.SyncSourcePublish); err != nil {
+		return "", err
+	}
+
+	if err := t.trackIPNSRecord(record.IpnsHash, record.KeyName, ipfsHash); err != nil {
+		return "", err
+	}
+
+	return ipfsHash, nil
+}
+
+// refreshRepublishedTest updates an active test to the revision published by another owner
+// Tests with edits pending sync are refreshed after the outbox publishes them
+func (t *TramontoOne) refreshRepublishedTest(ipnsHash string) error {
+	pending, err := t.db.HasPendingOperations(ipnsHash)
+	if err != nil {
+		return errors.New("(Database) " + err.Error())
+	}
+
+	if pending {
+		return nil
+	}
+
+	test, err := t.tests.FindTestByIpns(ipnsHash)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+
+	if err != nil {
+		return errors.New("(Database) " + err.Error())
+	}
+
+	return t.refreshTest(test)
+}
+
+// notifyRepublishFailure sends a record that could not be published again to the callback
+func (t *TramontoOne) notifyRepublishFailure(record entities.IPNSRecord, err error) {
+	t.jobMux.Lock()
+	callback := t.republishCallback
+	t.jobMux.Unlock()
+
+	if callback == nil {
+		return
+	}
+
+	jsonData, jsonErr := json.Marshal(entities.RepublishFailure{
+		IpnsHash: record.IpnsHash,
+		IpfsHash: record.IpfsHash,
+		Error:    err.Error(),
+	})
+	if jsonErr != nil {
+		return
+	}
+
+	callback.Invoke(string(jsonData))
+}
@@ -0,0 +1,180 @@
+package tramonto
+
+import (
+	"encoding/json"
+	"testing"
+
+	oneDb "gitlab.com/tramonto-one/go-tramonto/db"
+	"gitlab.com/tramonto-one/go-tramonto/entities"
+)
+
+// recordingCallback keeps the data it is invoked with
+type recordingCallback struct {
+	invocations []string
+}
+
+// Invoke keeps the data
+func (c *recordingCallback) Invoke(data string) {
+	c.invocations = append(c.invocations, data)
+}
+
+// findIPNSRecord returns the record tracked for a test
+func findIPNSRecord(t *testing.T, one *TramontoOne, ipnsHash string) entities.IPNSRecord {
+	t.Helper()
+
+	records, err := one.db.FindIPNSRecords()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, record := range records {
+		if record.IpnsHash == ipnsHash {
+			return record
+		}
+	}
+
+	t.Fatalf("no record tracked for %s", ipnsHash)
+
+	return entities.IPNSRecord{}
+}
+
+func TestRepublishRecordsShowsTheRevisionsOfOtherOwners(t *testing.T) {
+	one, moving, created := newMovingTramonto(t)
+
+	// Another owner publishes a revision to the test IPNS
+	if err := moving.publishConcurrentEdit(created.Ipns); err != nil {
+		t.Fatal(err)
+	}
+
+	published := mustResolve(t, moving, created.Ipns)
+
+	one.settings.RepublishIntervalHours = 0
+
+	result, err := one.republishRecords()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result != entities.JobResultSuccess {
+		t.Errorf("expected the records republished, got %s", result)
+	}
+
+	if record := findIPNSRecord(t, one, created.Ipns); record.IpfsHash != published || record.LastError != "" {
+		t.Errorf("expected the revision of the other owner republished, got %+v", record)
+	}
+
+	test, err := one.tests.FindTestByIpns(created.Ipns)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if test.Ipfs != published {
+		t.Errorf("the test should point to the republished revision %s, got %s", published, test.Ipfs)
+	}
+
+	data, err := one.GetTestByIPNS(created.Ipns, created.Secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if names := artifactNames(unmarshalTest(t, data).Metadata); !names["concurrent0.txt"] {
+		t.Errorf("expected the revision of the other owner shown, got %+v", names)
+	}
+}
+
+func TestRepublishRecordsNotifiesTheFailures(t *testing.T) {
+	flaky := &flakyStore{MemoryStore: newStartedMemoryStore(t), publishes: -1}
+
+	one, err := NewTramontoOneWithStores("", oneDb.NewMemoryTestRepository(), flaky)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := one.CreateTest("", "Login crash")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	created := unmarshalTest(t, data)
+
+	callback := &recordingCallback{}
+	one.SetRepublishCallback(callback)
+	one.settings.RepublishIntervalHours = 0
+	flaky.publishes = 0
+
+	if _, err := one.republishRecords(); err == nil {
+		t.Fatal("the republish should fail")
+	}
+
+	if len(callback.invocations) != 1 {
+		t.Fatalf("expected the failure notified once, got %+v", callback.invocations)
+	}
+
+	failure := entities.RepublishFailure{}
+	if err := json.Unmarshal([]byte(callback.invocations[0]), &failure); err != nil {
+		t.Fatal(err)
+	}
+
+	if failure.IpnsHash != created.Ipns || failure.IpfsHash != created.Ipfs || failure.Error == "" {
+		t.Errorf("unexpected failure %+v", failure)
+	}
+
+	if record := findIPNSRecord(t, one, created.Ipns); record.LastError == "" {
+		t.Errorf("the failure should be recorded, got %+v", record)
+	}
+
+	// Once published again the failure is cleared
+	flaky.publishes = -1
+
+	if _, err := one.republishRecords(); err != nil {
+		t.Fatal(err)
+	}
+
+	if record := findIPNSRecord(t, one, created.Ipns); record.LastError != "" {
+		t.Errorf("the failure should be cleared, got %+v", record)
+	}
+
+	if len(callback.invocations) != 1 {
+		t.Errorf("only the failures should be notified, got %+v", callback.invocations)
+	}
+}
+
+func TestRepublishRecordsRecordsTheRevisionsNotRead(t *testing.T) {
+	one, moving, created := newMovingTramonto(t)
+
+	callback := &recordingCallback{}
+	one.SetRepublishCallback(callback)
+
+	// Another owner publishes a revision that cannot be read as a test
+	unreadable, err := moving.UploadArtifact([]byte("not a test"), created.Secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := moving.PublishToIPNS(unreadable, created.Metadata.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	one.settings.RepublishIntervalHours = 0
+
+	if _, err := one.republishRecords(); err == nil {
+		t.Fatal("the revision not read should fail the job")
+	}
+
+	if len(callback.invocations) != 0 {
+		t.Errorf("the record was republished and should not be notified, got %+v", callback.invocations)
+	}
+
+	if record := findIPNSRecord(t, one, created.Ipns); record.IpfsHash != unreadable || record.LastError != "" {
+		t.Errorf("expected the revision republished, got %+v", record)
+	}
+
+	test, err := one.tests.FindTestByIpns(created.Ipns)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if test.Ipfs != created.Ipfs || test.LastSyncError == "" {
+		t.Errorf("expected the test kept with the sync error recorded, got %+v", test)
+	}
+}
@@ -0,0 +1,129 @@
+package tramonto
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"gitlab.com/tramonto-one/go-tramonto/entities"
+)
+
+// AddRun records a run of a test
+// runJSON has the build, device, os, environment, startedAt, finishedAt,
+// the verdicts of the test cases and the hashes of the artifacts of the run
+func (t *TramontoOne) AddRun(ipnsHash string, runJSON []byte) ([]byte, error) {
+	run, err := entities.RunFromJSON(runJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	// Gets the test from database
+	databaseTest, err := t.tests.FindTestByIpns(ipnsHash)
+	if err != nil {
+		return nil, errors.New("(Database) Could not find test: " + err.Error())
+	}
+
+	// Verifies if the user is the owner
+	if !databaseTest.IsOwner {
+		return nil, errors.New("User is not owner of this test")
+	}
+
+	baseMetadata, err := t.readMetadata(databaseTest.Ipfs, databaseTest.Secret)
+	if err != nil {
+		return nil, errors.New("(IPFS) Cannot read from IPFS: " + err.Error())
+	}
+
+	metadata := baseMetadata
+	if err = metadata.AddRun(run); err != nil {
+		return nil, errors.New("Error adding run: " + err.Error())
+	}
+
+	if err = t.appendActivity(&metadata, entities.ActivityAddRun, run.ID); err != nil {
+		return nil, err
+	}
+
+	// Publishes the new revision
+	if _, _, err = t.publishMetadata(databaseTest, baseMetadata, metadata); err != nil {
+		return nil, err
+	}
+
+	jsonData, err := json.Marshal(run)
+	if err != nil {
+		return nil, errors.New("Error parsing to json: " + err.Error())
+	}
+
+	return jsonData, nil
+}
+
+// FinishRun finishes a run in progress of a test
+// verdictsJSON has the verdicts of the test cases and finishedAt is in RFC 3339
+func (t *TramontoOne) FinishRun(ipnsHash, runID string, verdictsJSON []byte, finishedAt string) ([]byte, error) {
+	verdicts, err := entities.VerdictsFromJSON(verdictsJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	finishTime, err := time.Parse(time.RFC3339, finishedAt)
+	if err != nil {
+		return nil, errors.New("Invalid finishedAt: " + err.Error())
+	}
+
+	// Gets the test from database
+	databaseTest, err := t.tests.FindTestByIpns(ipnsHash)
+	if err != nil {
+		return nil, errors.New("(Database) Could not find test: " + err.Error())
+	}
+
+	// Verifies if the user is the owner
+	if !databaseTest.IsOwner {
+		return nil, errors.New("User is not owner of this test")
+	}
+
+	baseMetadata, err := t.readMetadata(databaseTest.Ipfs, databaseTest.Secret)
+	if err != nil {
+		return nil, errors.New("(IPFS) Cannot read from IPFS: " + err.Error())
+	}
+
+	metadata := baseMetadata
+	run, err := metadata.FinishRun(runID, verdicts, finishTime)
+	if err != nil {
+		return nil, errors.New("Error finishing run: " + err.Error())
+	}
+
+	if err = t.appendActivity(&metadata, entities.ActivityFinishRun, run.ID); err != nil {
+		return nil, err
+	}
+
+	// Publishes the new revision
+	if _, _, err = t.publishMetadata(databaseTest, baseMetadata, metadata); err != nil {
+		return nil, err
+	}
+
+	jsonData, err := json.Marshal(run)
+	if err != nil {
+		return nil, errors.New("Error parsing to json: " + err.Error())
+	}
+
+	return jsonData, nil
+}
+
+// GetRunStats computes the statistics over the run history of a test
+func (t *TramontoOne) GetRunStats(ipnsHash string) ([]byte, error) {
+	// Gets the test from database
+	databaseTest, err := t.tests.FindTestByIpns(ipnsHash)
+	if err != nil {
+		return nil, errors.New("(Database) Could not find test: " + err.Error())
+	}
+
+	metadata, err := t.readMetadata(databaseTest.Ipfs, databaseTest.Secret)
+	if err != nil {
+		return nil, errors.New("(IPFS) Cannot read from IPFS: " + err.Error())
+	}
+
+	jsonData, err := json.Marshal(entities.ComputeRunStats(metadata.Runs))
+	if err != nil {
+		return nil, errors.New("Error parsing to json: " + err.Error())
+	}
+
+	return jsonData, nil
+}
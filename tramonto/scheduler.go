@@ -0,0 +1,313 @@
+package tramonto
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"gitlab.com/tramonto-one/go-tramonto/entities"
+)
+
+// Intervals of the jobs not configured by the settings
+const (
+	syncJobInterval  = 15 * time.Minute
+	gcJobInterval    = 24 * time.Hour
+	indexJobInterval = time.Hour
+)
+
+// schedulerRetryDelay is how long the scheduler waits when the jobs cannot be read
+const schedulerRetryDelay = time.Minute
+
+// scheduledJob represents a job the scheduler knows how to run
+// run returns the result of the job, or an error when it fails
+type scheduledJob struct {
+	interval time.Duration
+	run      func() (string, error)
+}
+
+// scheduledJobs returns the jobs run by the scheduler by type
+func (t *TramontoOne) scheduledJobs() map[string]scheduledJob {
+	return map[string]scheduledJob{
+		entities.JobTypeOutbox:    {time.Duration(t.currentSettings().SyncIntervalSeconds) * time.Second, t.drainOutbox},
+		entities.JobTypeSync:      {syncJobInterval, t.syncTests},
+		entities.JobTypeGC:        {gcJobInterval, t.collectGarbage},
+		entities.JobTypeRepublish: {republishCheckInterval, t.republishRecords},
+		entities.JobTypeIndex:     {indexJobInterval, t.indexTests},
+	}
+}
+
+// registerJobs stores the jobs, the new ones are due right away
+// A job whose interval was shortened runs at most one interval from now
+func (t *TramontoOne) registerJobs() error {
+	now := time.Now()
+
+	for jobType, job := range t.scheduledJobs() {
+		if err := t.db.RegisterJob(jobType, int(job.interval/time.Second), now); err != nil {
+			return errors.New("(Database) " + err.Error())
+		}
+	}
+
+	jobs, err := t.db.FindJobs()
+	if err != nil {
+		return errors.New("(Database) " + err.Error())
+	}
+
+	for _, job := range jobs {
+		if limit := job.NextRun(now); job.NextRunAt.After(limit) {
+			if err := t.db.ScheduleJob(job.Type, limit); err != nil {
+				return errors.New("(Database) " + err.Error())
+			}
+		}
+	}
+
+	return nil
+}
+
+// startScheduler starts running the jobs in background
+func (t *TramontoOne) startScheduler() error {
+	if err := t.registerJobs(); err != nil {
+		return err
+	}
+
+	t.schedulerStop = make(chan struct{})
+	t.schedulerDone = make(chan struct{})
+
+	go func() {
+		defer close(t.schedulerDone)
+
+		for {
+			next := t.runDueJobs()
+
+			timer := time.NewTimer(time.Until(next))
+
+			select {
+			case <-t.schedulerStop:
+				timer.Stop()
+				return
+			case <-timer.C:
+			case <-t.schedulerWake:
+				timer.Stop()
+			}
+		}
+	}()
+
+	return nil
+}
+
+// stopScheduler stops running the jobs and waits the current job to finish
+func (t *TramontoOne) stopScheduler() {
+	if t.schedulerStop == nil {
+		return
+	}
+
+	close(t.schedulerStop)
+	<-t.schedulerDone
+
+	t.schedulerStop = nil
+}
+
+// isSchedulerStopping returns if the scheduler was asked to stop
+func (t *TramontoOne) isSchedulerStopping() bool {
+	select {
+	case <-t.schedulerStop:
+		return true
+	default:
+		return false
+	}
+}
+
+// requestJob asks a job to run right away
+func (t *TramontoOne) requestJob(jobType string) {
+	t.jobMux.Lock()
+	t.jobRequests[jobType] = true
+	t.jobMux.Unlock()
+
+	select {
+	case t.schedulerWake <- struct{}{}:
+	default:
+	}
+}
+
+// takeJobRequests returns the jobs requested since the last call
+func (t *TramontoOne) takeJobRequests() map[string]bool {
+	t.jobMux.Lock()
+	defer t.jobMux.Unlock()
+
+	requests := t.jobRequests
+	t.jobRequests = map[string]bool{}
+
+	return requests
+}
+
+// runDueJobs runs the jobs due or requested, returns when the next job is due
+func (t *TramontoOne) runDueJobs() time.Time {
+	jobs, err := t.db.FindJobs()
+	if err != nil {
+		return time.Now().Add(schedulerRetryDelay)
+	}
+
+	scheduled := t.scheduledJobs()
+	requests := t.takeJobRequests()
+
+	var next time.Time
+
+	for _, job := range jobs {
+		definition, ok := scheduled[job.Type]
+		if !ok {
+			continue
+		}
+
+		if t.isSchedulerStopping() {
+			return time.Now()
+		}
+
+		if requests[job.Type] || job.IsDue(time.Now()) {
+			if job, err = t.runJob(job, definition); err != nil {
+				// The job would stay due, so the jobs wait before running again
+				return time.Now().Add(schedulerRetryDelay)
+			}
+		}
+
+		if next.IsZero() || job.NextRunAt.Before(next) {
+			next = job.NextRunAt
+		}
+	}
+
+	if next.IsZero() {
+		return time.Now().Add(schedulerRetryDelay)
+	}
+
+	return next
+}
+
+// runJob runs a job and stores its result
+// Returns an error when the result cannot be stored
+func (t *TramontoOne) runJob(job entities.Job, definition scheduledJob) (entities.Job, error) {
+	startedAt := time.Now()
+
+	result, err := definition.run()
+
+	job.LastRunAt = &startedAt
+	job.LastResult = result
+	job.LastError = ""
+
+	if err != nil {
+		job.LastResult = entities.JobResultFailure
+		job.LastError = err.Error()
+	}
+
+	job.NextRunAt = job.NextRun(time.Now())
+
+	if err := t.db.CompleteJob(job.Type, job.LastResult, job.LastError, startedAt, job.NextRunAt); err != nil {
+		return job, errors.New("(Database) " + err.Error())
+	}
+
+	return job, nil
+}
+
+// syncTests refreshes the tests changed by other devices
+// Tests with edits pending sync are refreshed after the outbox publishes them
+func (t *TramontoOne) syncTests() (string, error) {
+	if !t.ipfs.IsOnline() {
+		return entities.JobResultSkipped, nil
+	}
+
+	tests, err := t.tests.FindTests()
+	if err != nil {
+		return "", errors.New("(Database) " + err.Error())
+	}
+
+	failed := 0
+	var lastErr error
+
+	for _, test := range tests {
+		if test.Ipns == "" {
+			continue
+		}
+
+		pending, err := t.db.HasPendingOperations(test.Ipns)
+		if err != nil {
+			return "", errors.New("(Database) " + err.Error())
+		}
+
+		if pending {
+			continue
+		}
+
+		if err := t.refreshTest(test); err != nil {
+			failed++
+			lastErr = err
+		}
+	}
+
+	if failed > 0 {
+		return "", fmt.Errorf("Could not sync %d tests: %s", failed, lastErr.Error())
+	}
+
+	return entities.JobResultSuccess, nil
+}
+
+// refreshTest updates a test to the revision its IPNS points to
+func (t *TramontoOne) refreshTest(test entities.Test) error {
+	ipfsHash, err := t.resolveTest(test.Ipns)
+	if err != nil {
+		return err
+	}
+
+	if ipfsHash == test.Ipfs {
+		return nil
+	}
+
+	metadata, err := t.readMetadata(ipfsHash, test.Secret)
+	if err != nil {
+		return errors.New("(IPFS) Test not found: " + err.Error())
+	}
+
+	// An edit published meanwhile already moved the test past the resolved revision
+	updated, err := t.tests.UpdateIPFSHashFrom(test.Ipns, test.Ipfs, ipfsHash)
+	if err != nil {
+		return errors.New("(Database) Could not update IPFS: " + err.Error())
+	}
+
+	if !updated {
+		return nil
+	}
+
+	return t.cacheTest(test.Ipns, ipfsHash, test.Secret, metadata)
+}
+
+// collectGarbage removes the blocks no longer pinned
+func (t *TramontoOne) collectGarbage() (string, error) {
+	if err := t.ipfs.CollectGarbage(); err != nil {
+		return "", errors.New("(IPFS) Error collecting garbage: " + err.Error())
+	}
+
+	return entities.JobResultSuccess, nil
+}
+
+// GetJobs returns the background jobs with their last results
+func (t *TramontoOne) GetJobs() ([]byte, error) {
+	jobs, err := t.db.FindJobs()
+	if err != nil {
+		return nil, errors.New("(Database) " + err.Error())
+	}
+
+	jsonData, err := json.Marshal(jobs)
+	if err != nil {
+		return nil, errors.New("Error parsing to json: " + err.Error())
+	}
+
+	return jsonData, nil
+}
+
+// RunJob asks a background job to run right away
+func (t *TramontoOne) RunJob(jobType string) error {
+	if _, ok := t.scheduledJobs()[jobType]; !ok {
+		return errors.New("Unknown job " + jobType)
+	}
+
+	t.requestJob(jobType)
+
+	return nil
+}
@@ -0,0 +1,359 @@
+package tramonto
+
+import (
+	"testing"
+	"time"
+
+	oneDb "gitlab.com/tramonto-one/go-tramonto/db"
+	"gitlab.com/tramonto-one/go-tramonto/entities"
+	oneIpfs "gitlab.com/tramonto-one/go-tramonto/ipfs"
+)
+
+// blockingStore is a memory store whose garbage collection waits to be released
+type blockingStore struct {
+	*oneIpfs.MemoryStore
+	collecting chan struct{}
+	release    chan struct{}
+}
+
+// CollectGarbage signals the collection started and waits to be released
+func (s *blockingStore) CollectGarbage() error {
+	close(s.collecting)
+	<-s.release
+
+	return s.MemoryStore.CollectGarbage()
+}
+
+// racingStore is a memory store running an edit right after a test is resolved,
+// as an edit published while the test is refreshed
+type racingStore struct {
+	*oneIpfs.MemoryStore
+	edit func()
+}
+
+// ResolveIPNS resolves the test and then runs the edit once
+func (s *racingStore) ResolveIPNS(hash string) (string, error) {
+	ipfsHash, err := s.MemoryStore.ResolveIPNS(hash)
+
+	if edit := s.edit; edit != nil {
+		s.edit = nil
+		edit()
+	}
+
+	return ipfsHash, err
+}
+
+// newStartedMemoryStore returns a memory store started in a new network
+func newStartedMemoryStore(t *testing.T) *oneIpfs.MemoryStore {
+	t.Helper()
+
+	store, err := oneIpfs.NewMemoryStore(oneIpfs.NewMemoryNetwork())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	return store
+}
+
+// findJobsByType returns the registered jobs of the instance by type
+func findJobsByType(t *testing.T, one *TramontoOne) map[string]entities.Job {
+	t.Helper()
+
+	jobs, err := one.db.FindJobs()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	byType := map[string]entities.Job{}
+	for _, job := range jobs {
+		byType[job.Type] = job
+	}
+
+	return byType
+}
+
+// ranSince returns if the job ran after the previous state of it
+func ranSince(previous, current entities.Job) bool {
+	if current.LastRunAt == nil {
+		return false
+	}
+
+	return previous.LastRunAt == nil || current.LastRunAt.After(*previous.LastRunAt)
+}
+
+func TestRunDueJobsRunsAndReschedulesTheDueJobs(t *testing.T) {
+	one := newMemoryTramonto(t, oneIpfs.NewMemoryNetwork())
+
+	if err := one.registerJobs(); err != nil {
+		t.Fatal(err)
+	}
+
+	startedAt := time.Now()
+	next := one.runDueJobs()
+
+	jobs := findJobsByType(t, one)
+	if len(jobs) != len(one.scheduledJobs()) {
+		t.Fatalf("expected every job registered, got %+v", jobs)
+	}
+
+	var earliest time.Time
+	for _, job := range jobs {
+		if job.LastRunAt == nil {
+			t.Errorf("the new job %s should run right away", job.Type)
+			continue
+		}
+
+		if job.LastError != "" {
+			t.Errorf("job %s failed: %s", job.Type, job.LastError)
+		}
+
+		if job.NextRunAt.Before(startedAt.Add(job.Interval())) {
+			t.Errorf("job %s should run again one interval later, got %s", job.Type, job.NextRunAt)
+		}
+
+		if earliest.IsZero() || job.NextRunAt.Before(earliest) {
+			earliest = job.NextRunAt
+		}
+	}
+
+	if !next.Equal(earliest) {
+		t.Errorf("the scheduler should wake up at %s, got %s", earliest, next)
+	}
+
+	// Nothing is due until the intervals pass
+	one.runDueJobs()
+
+	for jobType, job := range findJobsByType(t, one) {
+		if ranSince(jobs[jobType], job) {
+			t.Errorf("job %s is not due and should not run", jobType)
+		}
+	}
+}
+
+func TestRunDueJobsRunsTheRequestedJobs(t *testing.T) {
+	one := newMemoryTramonto(t, oneIpfs.NewMemoryNetwork())
+
+	if err := one.registerJobs(); err != nil {
+		t.Fatal(err)
+	}
+
+	one.runDueJobs()
+	jobs := findJobsByType(t, one)
+
+	if err := one.RunJob("unknown"); err == nil {
+		t.Error("an unknown job should not be requested")
+	}
+
+	if err := one.RunJob(entities.JobTypeSync); err != nil {
+		t.Fatal(err)
+	}
+
+	one.runDueJobs()
+
+	for jobType, job := range findJobsByType(t, one) {
+		if ran := ranSince(jobs[jobType], job); ran != (jobType == entities.JobTypeSync) {
+			t.Errorf("job %s ran %v, only the requested job should run", jobType, ran)
+		}
+	}
+
+	// The request is taken by the run
+	jobs = findJobsByType(t, one)
+	one.runDueJobs()
+
+	if job := findJobsByType(t, one)[entities.JobTypeSync]; ranSince(jobs[entities.JobTypeSync], job) {
+		t.Error("the requested job should run once")
+	}
+}
+
+func TestStopSchedulerWaitsForTheRunningJob(t *testing.T) {
+	store := &blockingStore{
+		MemoryStore: newStartedMemoryStore(t),
+		collecting:  make(chan struct{}),
+		release:     make(chan struct{}),
+	}
+
+	one, err := NewTramontoOneWithStores("", oneDb.NewMemoryTestRepository(), store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := one.startScheduler(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The jobs run sorted by type, so the garbage collection is the first
+	select {
+	case <-store.collecting:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the garbage collection should run")
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		one.stopScheduler()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		t.Fatal("the scheduler should wait for the running job")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(store.release)
+
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the scheduler should stop once the job finishes")
+	}
+
+	for jobType, job := range findJobsByType(t, one) {
+		if ran := job.LastRunAt != nil; ran != (jobType == entities.JobTypeGC) {
+			t.Errorf("job %s ran %v, only the running job should finish", jobType, ran)
+		}
+	}
+}
+
+func TestSyncTestsKeepsTheEditsPublishedMeanwhile(t *testing.T) {
+	store := &racingStore{MemoryStore: newStartedMemoryStore(t)}
+
+	one, err := NewTramontoOneWithStores("", oneDb.NewMemoryTestRepository(), store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := one.CreateTest("", "Login crash")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	created := unmarshalTest(t, data)
+
+	// Another device publishes a revision, then this node edits while syncing it
+	other := &movingStore{MemoryStore: store.MemoryStore, secret: created.Secret}
+	if err := other.publishConcurrentEdit(created.Ipns); err != nil {
+		t.Fatal(err)
+	}
+
+	var edited entities.Test
+	store.edit = func() {
+		data, err := one.AddArtifact(created.Ipns, "logcat.txt", "", []byte("crash"), map[string][]string{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		edited = unmarshalTest(t, data)
+	}
+
+	if _, err := one.syncTests(); err != nil {
+		t.Fatal(err)
+	}
+
+	if edited.Ipfs == "" {
+		t.Fatal("the edit should run while syncing")
+	}
+
+	test, err := one.tests.FindTestByIpns(created.Ipns)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if test.Ipfs != edited.Ipfs {
+		t.Errorf("the test should keep pointing to the edit %s, got %s", edited.Ipfs, test.Ipfs)
+	}
+
+	data, err = one.GetTestByIPNS(created.Ipns, created.Secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := artifactNames(unmarshalTest(t, data).Metadata)
+	if !names["logcat.txt"] || !names["concurrent0.txt"] {
+		t.Errorf("expected both revisions shown, got %+v", names)
+	}
+}
+
+// racingRepository is a test repository running an edit right before the
+// revision of a refreshed test is stored
+type racingRepository struct {
+	oneDb.TestRepository
+	edit func()
+}
+
+// UpdateIPFSHashFrom runs the edit once and then updates the test
+func (r *racingRepository) UpdateIPFSHashFrom(ipns, expectedIpfs, newIpfs string) (bool, error) {
+	if edit := r.edit; edit != nil {
+		r.edit = nil
+		edit()
+	}
+
+	return r.TestRepository.UpdateIPFSHashFrom(ipns, expectedIpfs, newIpfs)
+}
+
+func TestRefreshTestKeepsTheEditsPublishedBeforeStoringTheRevision(t *testing.T) {
+	store := newStartedMemoryStore(t)
+	tests := &racingRepository{TestRepository: oneDb.NewMemoryTestRepository()}
+
+	one, err := NewTramontoOneWithStores("", tests, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := one.CreateTest("", "Login crash")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	created := unmarshalTest(t, data)
+
+	other := &movingStore{MemoryStore: store, secret: created.Secret}
+	if err := other.publishConcurrentEdit(created.Ipns); err != nil {
+		t.Fatal(err)
+	}
+
+	var edited entities.Test
+	tests.edit = func() {
+		data, err := one.AddArtifact(created.Ipns, "logcat.txt", "", []byte("crash"), map[string][]string{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		edited = unmarshalTest(t, data)
+	}
+
+	test, err := one.tests.FindTestByIpns(created.Ipns)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := one.refreshTest(test); err != nil {
+		t.Fatal(err)
+	}
+
+	if edited.Ipfs == "" {
+		t.Fatal("the edit should run while refreshing")
+	}
+
+	if test, err = one.tests.FindTestByIpns(created.Ipns); err != nil {
+		t.Fatal(err)
+	}
+
+	if test.Ipfs != edited.Ipfs {
+		t.Errorf("the test should keep pointing to the edit %s, got %s", edited.Ipfs, test.Ipfs)
+	}
+
+	data, err = one.GetTestByIPNS(created.Ipns, created.Secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := artifactNames(unmarshalTest(t, data).Metadata)
+	if !names["logcat.txt"] || !names["concurrent0.txt"] {
+		t.Errorf("expected both revisions shown, got %+v", names)
+	}
+}
@@ -0,0 +1,167 @@
+package tramonto
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"gitlab.com/tramonto-one/go-tramonto/entities"
+)
+
+// maxSearchResults is the number of results returned by a search
+const maxSearchResults = 50
+
+// Search finds the tests, artifacts, members and comments matching the query
+// Results are ranked by relevance and have a snippet of the matched text
+func (t *TramontoOne) Search(query string) ([]byte, error) {
+	// Only the active tests of the repository are searched
+	tests, err := t.tests.FindTests()
+	if err != nil {
+		return nil, errors.New("(Database) " + err.Error())
+	}
+
+	names := map[string]string{}
+	ipnsHashes := []string{}
+	for _, test := range tests {
+		if test.Ipns == "" {
+			continue
+		}
+
+		names[test.Ipns] = test.Metadata.Name
+		ipnsHashes = append(ipnsHashes, test.Ipns)
+	}
+
+	results, err := t.db.Search(query, ipnsHashes, maxSearchResults)
+	if err != nil {
+		return nil, errors.New("(Database) " + err.Error())
+	}
+
+	for index := range results {
+		results[index].TestName = names[results[index].TestIpns]
+	}
+
+	jsonData, err := json.Marshal(results)
+	if err != nil {
+		return nil, errors.New("Error parsing to json: " + err.Error())
+	}
+
+	return jsonData, nil
+}
+
+// indexArtifactContent indexes the content of text artifacts
+// Binary artifacts are only recorded as indexed
+func (t *TramontoOne) indexArtifactContent(ipnsHash, name, artifactHash string, content []byte, headers map[string][]string) error {
+	text, ok := entities.IndexableContent(headers, content)
+	if !ok {
+		if err := t.db.MarkContentIndexed(ipnsHash, entities.SearchKindArtifactContent, artifactHash); err != nil {
+			return errors.New("(Database) Error indexing artifact: " + err.Error())
+		}
+
+		return nil
+	}
+
+	if err := t.db.IndexArtifactContent(ipnsHash, artifactHash, name, text); err != nil {
+		return errors.New("(Database) Error indexing artifact: " + err.Error())
+	}
+
+	return nil
+}
+
+// indexTests indexes the artifact contents and comments of the tests not indexed yet
+// They are not in the metadata, so they are read when the metadata arrives from
+// other devices. Contents that cannot be read are tried again on the next run
+func (t *TramontoOne) indexTests() (string, error) {
+	tests, err := t.tests.FindTests()
+	if err != nil {
+		return "", errors.New("(Database) " + err.Error())
+	}
+
+	failed := 0
+	var lastErr error
+
+	for _, test := range tests {
+		if test.Ipns == "" {
+			continue
+		}
+
+		if t.isSchedulerStopping() {
+			break
+		}
+
+		if err := t.indexTestContents(test); err != nil {
+			failed++
+			lastErr = err
+		}
+	}
+
+	if failed > 0 {
+		return "", fmt.Errorf("Could not index %d tests: %s", failed, lastErr.Error())
+	}
+
+	return entities.JobResultSuccess, nil
+}
+
+// indexTestContents indexes the artifact contents and comments of the current
+// revision of a test not indexed yet
+// As in ListComments, comments that cannot be read or verified are left out
+func (t *TramontoOne) indexTestContents(test entities.Test) error {
+	metadata, err := t.readMetadata(test.Ipfs, test.Secret)
+	if err != nil {
+		return errors.New("(IPFS) Test not found: " + err.Error())
+	}
+
+	indexedComments, err := t.db.FindIndexedContents(test.Ipns, entities.SearchKindComment)
+	if err != nil {
+		return errors.New("(Database) " + err.Error())
+	}
+
+	for _, link := range metadata.Comments {
+		if indexedComments[link.ID] {
+			continue
+		}
+
+		comment, err := t.ipfs.ReadComment(link.Hash, test.Secret)
+		if err != nil {
+			continue
+		}
+
+		if !metadata.VerifyComment(link, comment) {
+			continue
+		}
+
+		if err = t.db.IndexComment(test.Ipns, comment); err != nil {
+			return errors.New("(Database) Error indexing comment: " + err.Error())
+		}
+	}
+
+	indexedArtifacts, err := t.db.FindIndexedContents(test.Ipns, entities.SearchKindArtifactContent)
+	if err != nil {
+		return errors.New("(Database) " + err.Error())
+	}
+
+	for _, artifact := range metadata.Artifacts {
+		if indexedArtifacts[artifact.Hash] {
+			continue
+		}
+
+		// Binary artifacts, as images and videos, are not downloaded
+		if entities.IsBinaryContent(artifact.Headers) {
+			if err := t.db.MarkContentIndexed(test.Ipns, entities.SearchKindArtifactContent, artifact.Hash); err != nil {
+				return errors.New("(Database) Error indexing artifact: " + err.Error())
+			}
+
+			continue
+		}
+
+		content, err := t.ipfs.ReadArtifact(artifact.Hash, test.Secret)
+		if err != nil {
+			return errors.New("(IPFS) Could not read artifact: " + err.Error())
+		}
+
+		if err = t.indexArtifactContent(test.Ipns, artifact.Name, artifact.Hash, content, artifact.Headers); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
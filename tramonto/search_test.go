@@ -0,0 +1,115 @@
+package tramonto
+
+import (
+	"encoding/json"
+	"testing"
+
+	oneDb "gitlab.com/tramonto-one/go-tramonto/db"
+	"gitlab.com/tramonto-one/go-tramonto/entities"
+	oneIpfs "gitlab.com/tramonto-one/go-tramonto/ipfs"
+)
+
+// countingStore is a memory store counting the artifacts read
+type countingStore struct {
+	*oneIpfs.MemoryStore
+	reads int
+}
+
+// ReadArtifact counts the read and reads the artifact
+func (s *countingStore) ReadArtifact(ipfsHash, secret string) ([]byte, error) {
+	s.reads++
+
+	return s.MemoryStore.ReadArtifact(ipfsHash, secret)
+}
+
+// search returns the results of a query of the instance
+func search(t *testing.T, one *TramontoOne, query string) []entities.SearchResult {
+	t.Helper()
+
+	data, err := one.Search(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results := []entities.SearchResult{}
+	if err := json.Unmarshal(data, &results); err != nil {
+		t.Fatal(err)
+	}
+
+	return results
+}
+
+func TestIndexTestsSkipsTheBinaryArtifacts(t *testing.T) {
+	network := oneIpfs.NewMemoryNetwork()
+	owner := newMemoryTramonto(t, network)
+
+	store, err := oneIpfs.NewMemoryStore(network)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	counting := &countingStore{MemoryStore: store}
+
+	device, err := NewTramontoOneWithStores("", oneDb.NewMemoryTestRepository(), counting)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := owner.CreateTest("", "Login crash")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	created := unmarshalTest(t, data)
+
+	if _, err := owner.AddArtifact(created.Ipns, "screen.png", "", []byte("NullPointerException"), map[string][]string{
+		"Content-Type": {"image/png"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := owner.AddArtifact(created.Ipns, "logcat.txt", "", []byte("NullPointerException at main"), map[string][]string{
+		"Content-Type": {"text/plain"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := device.ImportTest(created.Ipns, created.Secret); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := device.indexTests(); err != nil {
+		t.Fatal(err)
+	}
+
+	if counting.reads != 1 {
+		t.Errorf("only the text artifact should be read, got %d reads", counting.reads)
+	}
+
+	indexed, err := device.db.FindIndexedContents(created.Ipns, entities.SearchKindArtifactContent)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(indexed) != 2 {
+		t.Errorf("both artifacts should be recorded as indexed, got %+v", indexed)
+	}
+
+	results := search(t, device, "NullPointerException")
+	if len(results) != 1 || results[0].Kind != entities.SearchKindArtifactContent || results[0].Title != "logcat.txt" {
+		t.Errorf("expected the text artifact found, got %+v", results)
+	}
+
+	// The artifacts indexed are not read again
+	if _, err := device.indexTests(); err != nil {
+		t.Fatal(err)
+	}
+
+	if counting.reads != 1 {
+		t.Errorf("the indexed artifacts should not be read again, got %d reads", counting.reads)
+	}
+}
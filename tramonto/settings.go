@@ -0,0 +1,167 @@
+package tramonto
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"gitlab.com/tramonto-one/go-tramonto/entities"
+	oneIpfs "gitlab.com/tramonto-one/go-tramonto/ipfs"
+)
+
+// loadSettings reads the stored settings and configures the node with them
+func (t *TramontoOne) loadSettings() error {
+	values, err := t.db.FindSettings()
+	if err != nil {
+		return errors.New("(Database) " + err.Error())
+	}
+
+	settings, err := entities.SettingsFromValues(values)
+	if err != nil {
+		return errors.New("Error loading settings: " + err.Error())
+	}
+
+	return t.applySettings(settings)
+}
+
+// applySettings configures IPFS and the jobs with the settings
+// The HTTP port is only read by Setup, before the server starts
+func (t *TramontoOne) applySettings(settings entities.Settings) error {
+	t.settingsMux.Lock()
+	previous := t.settings
+	t.settings = settings
+	t.settingsMux.Unlock()
+
+	t.ipfs.Configure(oneIpfs.Config{
+		CatTimeout:    time.Duration(settings.CatTimeoutSeconds) * time.Second,
+		IPNSTimeout:   time.Duration(settings.IPNSTimeoutSeconds) * time.Second,
+		IPNSValidTime: time.Duration(settings.IPNSValidHours) * time.Hour,
+	})
+
+	// Reschedules the outbox with the new interval
+	if t.started && previous.SyncIntervalSeconds != settings.SyncIntervalSeconds {
+		if err := t.registerJobs(); err != nil {
+			return err
+		}
+
+		t.requestJob(entities.JobTypeOutbox)
+	}
+
+	return nil
+}
+
+// saveSettings validates, stores and applies the settings
+func (t *TramontoOne) saveSettings(settings entities.Settings) error {
+	if err := settings.Validate(); err != nil {
+		return err
+	}
+
+	current := t.currentSettings().Values()
+
+	changed := map[string]string{}
+	for key, value := range settings.Values() {
+		if current[key] != value {
+			changed[key] = value
+		}
+	}
+
+	if err := t.db.SaveSettings(changed); err != nil {
+		return errors.New("(Database) " + err.Error())
+	}
+
+	return t.applySettings(settings)
+}
+
+// currentSettings returns the settings applied to the node
+func (t *TramontoOne) currentSettings() entities.Settings {
+	t.settingsMux.RLock()
+	defer t.settingsMux.RUnlock()
+
+	return t.settings
+}
+
+// GetSettings returns the settings of the node
+func (t *TramontoOne) GetSettings() ([]byte, error) {
+	jsonData, err := json.Marshal(t.currentSettings())
+	if err != nil {
+		return nil, errors.New("Error parsing to json: " + err.Error())
+	}
+
+	return jsonData, nil
+}
+
+// UpdateSettings changes the settings present in the JSON, keeping the others
+// Returns the updated settings
+func (t *TramontoOne) UpdateSettings(settingsJSON []byte) ([]byte, error) {
+	settings := t.currentSettings()
+	if err := json.Unmarshal(settingsJSON, &settings); err != nil {
+		return nil, errors.New("Error parsing settings: " + err.Error())
+	}
+
+	if err := t.saveSettings(settings); err != nil {
+		return nil, err
+	}
+
+	return t.GetSettings()
+}
+
+// settingValue verifies that the setting exists with the kind and returns its value
+func (t *TramontoOne) settingValue(key, kind string) (string, error) {
+	settingKind, ok := entities.SettingKind(key)
+	if !ok {
+		return "", errors.New("Unknown setting " + key)
+	}
+
+	if settingKind != kind {
+		return "", errors.New("Setting " + key + " is not of kind " + kind)
+	}
+
+	return t.currentSettings().Values()[key], nil
+}
+
+// setSettingValue changes the value of a setting
+func (t *TramontoOne) setSettingValue(key, value string) error {
+	values := t.currentSettings().Values()
+	values[key] = value
+
+	settings, err := entities.SettingsFromValues(values)
+	if err != nil {
+		return err
+	}
+
+	return t.saveSettings(settings)
+}
+
+// GetIntSetting returns the value of a number setting
+func (t *TramontoOne) GetIntSetting(key string) (int, error) {
+	value, err := t.settingValue(key, entities.SettingKindInt)
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.Atoi(value)
+}
+
+// SetIntSetting changes the value of a number setting
+func (t *TramontoOne) SetIntSetting(key string, value int) error {
+	if _, err := t.settingValue(key, entities.SettingKindInt); err != nil {
+		return err
+	}
+
+	return t.setSettingValue(key, strconv.Itoa(value))
+}
+
+// GetStringSetting returns the value of a text setting
+func (t *TramontoOne) GetStringSetting(key string) (string, error) {
+	return t.settingValue(key, entities.SettingKindString)
+}
+
+// SetStringSetting changes the value of a text setting
+func (t *TramontoOne) SetStringSetting(key, value string) error {
+	if _, err := t.settingValue(key, entities.SettingKindString); err != nil {
+		return err
+	}
+
+	return t.setSettingValue(key, value)
+}
@@ -0,0 +1,304 @@
+package tramonto
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	oneCrypto "gitlab.com/tramonto-one/go-tramonto/crypto"
+	"gitlab.com/tramonto-one/go-tramonto/entities"
+)
+
+// suiteKeyPrefix prefixes the names of the IPNS keys of suites
+const suiteKeyPrefix = "suite-"
+
+// CreateProject creates a new project
+func (t *TramontoOne) CreateProject(name, description string) ([]byte, error) {
+	project, err := entities.NewProject(name, description)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = t.db.InsertProject(project); err != nil {
+		return nil, errors.New("(Database) Error inserting project: " + err.Error())
+	}
+
+	jsonResponse, err := json.Marshal(project)
+	if err != nil {
+		return nil, errors.New("Error parsing to json: " + err.Error())
+	}
+
+	return jsonResponse, nil
+}
+
+// GetProjects gets all the projects from the database
+func (t *TramontoOne) GetProjects() ([]byte, error) {
+	projects, err := t.db.FindProjects()
+	if err != nil {
+		return nil, errors.New("(Database) Error finding projects: " + err.Error())
+	}
+
+	jsonData, err := json.Marshal(projects)
+	if err != nil {
+		return nil, errors.New("Error parsing to json: " + err.Error())
+	}
+
+	return jsonData, nil
+}
+
+// CreateSuite creates a new suite in a project
+func (t *TramontoOne) CreateSuite(projectID, name, description string) ([]byte, error) {
+	// The foreign key is not enforced by SQLite, so the project is verified here
+	if _, err := t.db.FindProjectByID(projectID); err != nil {
+		return nil, errors.New("(Database) Could not find project: " + err.Error())
+	}
+
+	suite, err := entities.NewSuite(projectID, name, description)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = t.db.SaveSuite(suite); err != nil {
+		return nil, errors.New("(Database) Error inserting suite: " + err.Error())
+	}
+
+	jsonResponse, err := json.Marshal(suite)
+	if err != nil {
+		return nil, errors.New("Error parsing to json: " + err.Error())
+	}
+
+	return jsonResponse, nil
+}
+
+// GetSuites gets the suites of a project
+// An empty projectID gets the suites without project, as the imported ones
+func (t *TramontoOne) GetSuites(projectID string) ([]byte, error) {
+	suites, err := t.db.FindSuites(projectID)
+	if err != nil {
+		return nil, errors.New("(Database) Error finding suites: " + err.Error())
+	}
+
+	jsonData, err := json.Marshal(suites)
+	if err != nil {
+		return nil, errors.New("Error parsing to json: " + err.Error())
+	}
+
+	return jsonData, nil
+}
+
+// GetSuiteTests gets the tests of a suite
+func (t *TramontoOne) GetSuiteTests(suiteID string) ([]byte, error) {
+	tests, err := t.tests.FindTestsBySuite(suiteID)
+	if err != nil {
+		return nil, errors.New("(Database) Error finding tests: " + err.Error())
+	}
+
+	if err = t.attachCachedDetails(tests); err != nil {
+		return nil, err
+	}
+
+	jsonData, err := json.Marshal(tests)
+	if err != nil {
+		return nil, errors.New("Error parsing to json: " + err.Error())
+	}
+
+	return jsonData, nil
+}
+
+// AddTestToSuite moves a test to a suite
+// Shared suites are published again with the new test before the test is moved,
+// so a failed publish leaves the suite as it was. The shared suite the test
+// leaves is published again without it, so its members stop importing the test
+func (t *TramontoOne) AddTestToSuite(suiteID, ipnsHash string) ([]byte, error) {
+	suite, err := t.db.FindSuiteByID(suiteID)
+	if err != nil {
+		return nil, errors.New("(Database) Could not find suite: " + err.Error())
+	}
+
+	test, err := t.tests.FindTestByIpns(ipnsHash)
+	if err != nil {
+		return nil, errors.New("(Database) Could not find test: " + err.Error())
+	}
+
+	if suite.Ipns != "" && suite.IsOwner {
+		tests, err := t.tests.FindTestsBySuite(suiteID)
+		if err != nil {
+			return nil, errors.New("(Database) Error finding tests: " + err.Error())
+		}
+
+		if test.SuiteID != suiteID {
+			tests = append(tests, test)
+		}
+
+		if suite, err = t.publishSuite(suite, tests); err != nil {
+			return nil, err
+		}
+	}
+
+	if test.SuiteID != "" && test.SuiteID != suiteID {
+		if err = t.publishSuiteWithout(test.SuiteID, ipnsHash); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = t.tests.SetTestSuite(ipnsHash, suiteID); err != nil {
+		return nil, errors.New("(Database) Error adding test to suite: " + err.Error())
+	}
+
+	jsonResponse, err := json.Marshal(suite)
+	if err != nil {
+		return nil, errors.New("Error parsing to json: " + err.Error())
+	}
+
+	return jsonResponse, nil
+}
+
+// ShareSuite publishes a suite to IPNS, so all its tests are shared with one invite
+// Returns the suite with its IPNS hash and secret
+func (t *TramontoOne) ShareSuite(suiteID string) ([]byte, error) {
+	suite, err := t.db.FindSuiteByID(suiteID)
+	if err != nil {
+		return nil, errors.New("(Database) Could not find suite: " + err.Error())
+	}
+
+	if !suite.IsOwner {
+		return nil, errors.New("User is not owner of this suite")
+	}
+
+	if suite.Secret == "" {
+		secret, err := oneCrypto.GenerateSecret()
+		if err != nil {
+			return nil, errors.New("Error generating secret: " + err.Error())
+		}
+
+		suite.Secret = secret
+	}
+
+	tests, err := t.tests.FindTestsBySuite(suite.ID)
+	if err != nil {
+		return nil, errors.New("(Database) Error finding tests: " + err.Error())
+	}
+
+	if suite, err = t.publishSuite(suite, tests); err != nil {
+		return nil, err
+	}
+
+	jsonResponse, err := json.Marshal(suite)
+	if err != nil {
+		return nil, errors.New("Error parsing to json: " + err.Error())
+	}
+
+	return jsonResponse, nil
+}
+
+// publishSuiteWithout publishes a shared suite again without one of its tests
+// Suites not shared or not owned by the user are not published
+func (t *TramontoOne) publishSuiteWithout(suiteID, ipnsHash string) error {
+	suite, err := t.db.FindSuiteByID(suiteID)
+	if err != nil {
+		return errors.New("(Database) Could not find suite: " + err.Error())
+	}
+
+	if suite.Ipns == "" || !suite.IsOwner {
+		return nil
+	}
+
+	tests, err := t.tests.FindTestsBySuite(suiteID)
+	if err != nil {
+		return errors.New("(Database) Error finding tests: " + err.Error())
+	}
+
+	remaining := []entities.Test{}
+	for _, test := range tests {
+		if test.Ipns != ipnsHash {
+			remaining = append(remaining, test)
+		}
+	}
+
+	_, err = t.publishSuite(suite, remaining)
+
+	return err
+}
+
+// publishSuite uploads the suite document with the tests and publishes it
+func (t *TramontoOne) publishSuite(suite entities.Suite, tests []entities.Test) (entities.Suite, error) {
+	suite.Tests = []entities.SuiteTest{}
+	for _, test := range tests {
+		if test.Ipns == "" {
+			continue
+		}
+
+		suite.Tests = append(suite.Tests, entities.SuiteTest{
+			Ipns:   test.Ipns,
+			Secret: test.Secret,
+			Name:   test.Metadata.Name,
+		})
+	}
+
+	ipfsHash, err := t.ipfs.UploadSuite(suite.Document(), suite.Secret)
+	if err != nil {
+		return suite, errors.New("(IPFS) Error uploading suite: " + err.Error())
+	}
+
+	ipnsHash, err := t.ipfs.PublishToIPNS(ipfsHash, suiteKeyPrefix+suite.ID)
+	if err != nil {
+		return suite, errors.New("(IPNS) Error publishing suite: " + err.Error())
+	}
+
+	suite.Ipfs = ipfsHash
+	suite.Ipns = ipnsHash
+
+	if err = t.db.SaveSuite(suite); err != nil {
+		return suite, errors.New("(Database) Error saving suite: " + err.Error())
+	}
+
+	return suite, nil
+}
+
+// ImportSuite imports a shared suite and all its tests
+// A suite already in the device refreshes its tests and keeps its ownership and project
+func (t *TramontoOne) ImportSuite(ipnsHash, secret string) ([]byte, error) {
+	ipfsHash, suite, err := t.ipfs.GetSuiteByIPNS(ipnsHash, secret)
+	if err != nil {
+		return nil, errors.New("(IPNS) Could not find suite: " + err.Error())
+	}
+
+	suite.Ipfs = ipfsHash
+	suite.Ipns = ipnsHash
+	suite.Secret = secret
+	suite.IsOwner = false
+	suite.ProjectID = ""
+
+	existing, err := t.db.FindSuiteByID(suite.ID)
+	switch {
+	case err == nil:
+		suite.IsOwner = existing.IsOwner
+		suite.ProjectID = existing.ProjectID
+	case err != sql.ErrNoRows:
+		return nil, errors.New("(Database) Could not find suite: " + err.Error())
+	}
+
+	if err = t.db.SaveSuite(suite); err != nil {
+		return nil, errors.New("(Database) Error saving suite: " + err.Error())
+	}
+
+	// Imports each test of the suite
+	for _, suiteTest := range suite.Tests {
+		if _, err := t.tests.FindTestByIpns(suiteTest.Ipns); err != nil {
+			if _, err := t.ImportTest(suiteTest.Ipns, suiteTest.Secret); err != nil {
+				return nil, errors.New("Error importing test " + suiteTest.Name + ": " + err.Error())
+			}
+		}
+
+		if err := t.tests.SetTestSuite(suiteTest.Ipns, suite.ID); err != nil {
+			return nil, errors.New("(Database) Error adding test to suite: " + err.Error())
+		}
+	}
+
+	jsonResponse, err := json.Marshal(suite)
+	if err != nil {
+		return nil, errors.New("Error parsing to json: " + err.Error())
+	}
+
+	return jsonResponse, nil
+}
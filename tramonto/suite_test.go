@@ -0,0 +1,208 @@
+package tramonto
+
+import (
+	"encoding/json"
+	"testing"
+
+	oneDb "gitlab.com/tramonto-one/go-tramonto/db"
+	"gitlab.com/tramonto-one/go-tramonto/entities"
+	oneIpfs "gitlab.com/tramonto-one/go-tramonto/ipfs"
+)
+
+func TestAddTestToSharedSuite(t *testing.T) {
+	store, err := oneIpfs.NewMemoryStore(oneIpfs.NewMemoryNetwork())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	flaky := &flakyStore{MemoryStore: store, publishes: -1}
+
+	one, err := NewTramontoOneWithStores("", oneDb.NewMemoryTestRepository(), flaky)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := one.CreateSuite("missing", "Release", ""); err == nil {
+		t.Error("a suite of a missing project should not be created")
+	}
+
+	data, err := one.CreateProject("Mobile", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	project := entities.Project{}
+	if err := json.Unmarshal(data, &project); err != nil {
+		t.Fatal(err)
+	}
+
+	if data, err = one.CreateSuite(project.ID, "Release", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	suite := entities.Suite{}
+	if err := json.Unmarshal(data, &suite); err != nil {
+		t.Fatal(err)
+	}
+
+	if data, err = one.ShareSuite(suite.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := json.Unmarshal(data, &suite); err != nil {
+		t.Fatal(err)
+	}
+
+	if data, err = one.CreateTest("", "Login crash"); err != nil {
+		t.Fatal(err)
+	}
+
+	test := unmarshalTest(t, data)
+
+	// A failed publish leaves the test out of the suite
+	flaky.publishes = 0
+
+	if _, err := one.AddTestToSuite(suite.ID, test.Ipns); err == nil {
+		t.Fatal("the suite should fail to publish")
+	}
+
+	if tests, err := one.tests.FindTestsBySuite(suite.ID); err != nil || len(tests) != 0 {
+		t.Errorf("the test should not be moved to the suite, got %+v %v", tests, err)
+	}
+
+	flaky.publishes = -1
+
+	if _, err := one.AddTestToSuite(suite.ID, test.Ipns); err != nil {
+		t.Fatal(err)
+	}
+
+	expectSuiteTests(t, one, suite, test.Ipns)
+
+	// Adding the test again publishes it once
+	if _, err := one.AddTestToSuite(suite.ID, test.Ipns); err != nil {
+		t.Fatal(err)
+	}
+
+	expectSuiteTests(t, one, suite, test.Ipns)
+
+	// Moving the test to another shared suite publishes the suite it left without it
+	if data, err = one.CreateSuite(project.ID, "Hotfix", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	hotfix := entities.Suite{}
+	if err := json.Unmarshal(data, &hotfix); err != nil {
+		t.Fatal(err)
+	}
+
+	if data, err = one.ShareSuite(hotfix.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := json.Unmarshal(data, &hotfix); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := one.AddTestToSuite(hotfix.ID, test.Ipns); err != nil {
+		t.Fatal(err)
+	}
+
+	expectSuiteTests(t, one, hotfix, test.Ipns)
+	expectSuiteTests(t, one, suite)
+
+	// Importing its own suite keeps the ownership and the project
+	if data, err = one.ImportSuite(hotfix.Ipns, hotfix.Secret); err != nil {
+		t.Fatal(err)
+	}
+
+	imported := entities.Suite{}
+	if err := json.Unmarshal(data, &imported); err != nil {
+		t.Fatal(err)
+	}
+
+	if stored, err := one.db.FindSuiteByID(hotfix.ID); err != nil || !stored.IsOwner || stored.ProjectID != project.ID || !imported.IsOwner || imported.ProjectID != project.ID {
+		t.Errorf("the suite should keep its owner and project, got %+v %+v %v", imported, stored, err)
+	}
+
+	expectSuiteTests(t, one, hotfix, test.Ipns)
+}
+
+// expectSuiteTests fails when the tests are not the ones in the suite and in its published document
+func expectSuiteTests(t *testing.T, one *TramontoOne, suite entities.Suite, ipnsHashes ...string) {
+	t.Helper()
+
+	tests, err := one.tests.FindTestsBySuite(suite.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, published, err := one.ipfs.GetSuiteByIPNS(suite.Ipns, suite.Secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tests) != len(ipnsHashes) || len(published.Tests) != len(ipnsHashes) {
+		t.Fatalf("expected tests %v, got %+v and published %+v", ipnsHashes, tests, published.Tests)
+	}
+
+	for index, ipns := range ipnsHashes {
+		if tests[index].Ipns != ipns || published.Tests[index].Ipns != ipns {
+			t.Fatalf("expected tests %v, got %+v and published %+v", ipnsHashes, tests, published.Tests)
+		}
+	}
+}
+
+func TestImportSuiteLeavesTheProjectOfThePublisher(t *testing.T) {
+	network := oneIpfs.NewMemoryNetwork()
+	owner := newMemoryTramonto(t, network)
+	device := newMemoryTramonto(t, network)
+
+	data, err := owner.CreateProject("Mobile", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	project := entities.Project{}
+	if err := json.Unmarshal(data, &project); err != nil {
+		t.Fatal(err)
+	}
+
+	if data, err = owner.CreateSuite(project.ID, "Release", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	suite := entities.Suite{}
+	if err := json.Unmarshal(data, &suite); err != nil {
+		t.Fatal(err)
+	}
+
+	if data, err = owner.ShareSuite(suite.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := json.Unmarshal(data, &suite); err != nil {
+		t.Fatal(err)
+	}
+
+	if data, err = device.ImportSuite(suite.Ipns, suite.Secret); err != nil {
+		t.Fatal(err)
+	}
+
+	imported := entities.Suite{}
+	if err := json.Unmarshal(data, &imported); err != nil {
+		t.Fatal(err)
+	}
+
+	stored, err := device.db.FindSuiteByID(suite.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if imported.IsOwner || imported.ProjectID != "" || stored.IsOwner || stored.ProjectID != "" {
+		t.Errorf("the suite should not be owned nor in a project of the device, got %+v %+v", imported, stored)
+	}
+}
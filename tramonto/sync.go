@@ -0,0 +1,41 @@
+package tramonto
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// recordSync registers that the IPNS of a test points to a revision
+func (t *TramontoOne) recordSync(ipnsHash, ipfsHash, source string) error {
+	if err := t.db.RecordSync(ipnsHash, ipfsHash, source); err != nil {
+		return errors.New("(Database) " + err.Error())
+	}
+
+	if err := t.tests.MarkSynced(ipnsHash, time.Now()); err != nil {
+		return errors.New("(Database) " + err.Error())
+	}
+
+	return nil
+}
+
+// recordSyncError registers a failed sync of a test
+// It is called while handling another error, so its own failure is ignored
+func (t *TramontoOne) recordSyncError(ipnsHash string, err error) {
+	t.tests.MarkSyncError(ipnsHash, err.Error())
+}
+
+// GetSyncHistory returns the revisions the IPNS of a test pointed to
+func (t *TramontoOne) GetSyncHistory(ipnsHash string) ([]byte, error) {
+	history, err := t.db.FindSyncHistory(ipnsHash)
+	if err != nil {
+		return nil, errors.New("(Database) " + err.Error())
+	}
+
+	jsonData, err := json.Marshal(history)
+	if err != nil {
+		return nil, errors.New("Error parsing to json: " + err.Error())
+	}
+
+	return jsonData, nil
+}
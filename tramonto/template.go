@@ -0,0 +1,129 @@
+package tramonto
+
+import (
+	"encoding/json"
+	"errors"
+
+	oneCrypto "gitlab.com/tramonto-one/go-tramonto/crypto"
+	"gitlab.com/tramonto-one/go-tramonto/entities"
+)
+
+// SaveTemplate creates or updates a template
+// The template is a JSON with name, description, members, testCases and customFields
+func (t *TramontoOne) SaveTemplate(templateJSON []byte) ([]byte, error) {
+	template, err := entities.TemplateFromJSON(templateJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keeps the sharing information of an existing template
+	if existing, err := t.db.FindTemplateByID(template.ID); err == nil {
+		template.Ipfs = existing.Ipfs
+		template.Secret = existing.Secret
+	}
+
+	if err := t.db.SaveTemplate(template); err != nil {
+		return nil, errors.New("(Database) Error saving template: " + err.Error())
+	}
+
+	jsonResponse, err := json.Marshal(template)
+	if err != nil {
+		return nil, errors.New("Error parsing to json: " + err.Error())
+	}
+
+	return jsonResponse, nil
+}
+
+// GetTemplates gets all the templates from the database
+func (t *TramontoOne) GetTemplates() ([]byte, error) {
+	templates, err := t.db.FindTemplates()
+	if err != nil {
+		return nil, errors.New("(Database) Error finding templates: " + err.Error())
+	}
+
+	jsonData, err := json.Marshal(templates)
+	if err != nil {
+		return nil, errors.New("Error parsing to json: " + err.Error())
+	}
+
+	return jsonData, nil
+}
+
+// ShareTemplate uploads a template to IPFS
+// Returns the template with the IPFS hash and secret to import it
+func (t *TramontoOne) ShareTemplate(templateID string) ([]byte, error) {
+	template, err := t.db.FindTemplateByID(templateID)
+	if err != nil {
+		return nil, errors.New("(Database) Could not find template: " + err.Error())
+	}
+
+	if template.Secret == "" {
+		secret, err := oneCrypto.GenerateSecret()
+		if err != nil {
+			return nil, errors.New("Error generating secret: " + err.Error())
+		}
+
+		template.Secret = secret
+	}
+
+	ipfsHash, err := t.ipfs.UploadTemplate(template, template.Secret)
+	if err != nil {
+		return nil, errors.New("(IPFS) Error uploading template: " + err.Error())
+	}
+
+	template.Ipfs = ipfsHash
+
+	if err := t.db.SaveTemplate(template); err != nil {
+		return nil, errors.New("(Database) Error saving template: " + err.Error())
+	}
+
+	jsonResponse, err := json.Marshal(template)
+	if err != nil {
+		return nil, errors.New("Error parsing to json: " + err.Error())
+	}
+
+	return jsonResponse, nil
+}
+
+// ImportTemplate imports a template shared in IPFS
+func (t *TramontoOne) ImportTemplate(ipfsHash, secret string) ([]byte, error) {
+	template, err := t.ipfs.GetTemplateByIPFS(ipfsHash, secret)
+	if err != nil {
+		return nil, errors.New("(IPFS) Could not find template: " + err.Error())
+	}
+
+	template.Ipfs = ipfsHash
+	template.Secret = secret
+
+	if err := t.db.SaveTemplate(template); err != nil {
+		return nil, errors.New("(Database) Error saving template: " + err.Error())
+	}
+
+	jsonResponse, err := json.Marshal(template)
+	if err != nil {
+		return nil, errors.New("Error parsing to json: " + err.Error())
+	}
+
+	return jsonResponse, nil
+}
+
+// CreateTestFromTemplate creates a new test with the structure of a template
+// A sequential name is generated when name is empty
+func (t *TramontoOne) CreateTestFromTemplate(templateID, name string) ([]byte, error) {
+	name, err := t.uniqueTestName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	template, err := t.db.FindTemplateByID(templateID)
+	if err != nil {
+		return nil, errors.New("(Database) Could not find template: " + err.Error())
+	}
+
+	metadata, err := template.NewMetadata(name)
+	if err != nil {
+		return nil, errors.New("Error creating test from template: " + err.Error())
+	}
+
+	return t.createTest(metadata)
+}
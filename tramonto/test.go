@@ -1,17 +1,23 @@
 package tramonto
 
 import (
+	"database/sql"
 	"encoding/json"
 	"errors"
 
 	oneCrypto "gitlab.com/tramonto-one/go-tramonto/crypto"
+	oneDb "gitlab.com/tramonto-one/go-tramonto/db"
 	"gitlab.com/tramonto-one/go-tramonto/entities"
 )
 
 // CreateTest creates a new test
 // Uploads to IPFS and inserts in the database
+// A sequential name is generated when name is empty
 func (t *TramontoOne) CreateTest(name, description string) ([]byte, error) {
-	testResult := entities.NewEmptyTest()
+	name, err := t.uniqueTestName(name)
+	if err != nil {
+		return nil, err
+	}
 
 	// Generates the test content
 	metadata, err := entities.NewMetadata(name, description)
@@ -19,6 +25,16 @@ func (t *TramontoOne) CreateTest(name, description string) ([]byte, error) {
 		return nil, err
 	}
 
+	return t.createTest(metadata)
+}
+
+// createTest creates a new test with the given metadata
+func (t *TramontoOne) createTest(metadata entities.Metadata) ([]byte, error) {
+	if err := t.appendActivity(&metadata, entities.ActivityCreateTest, metadata.Name); err != nil {
+		return nil, err
+	}
+
+	testResult := entities.NewEmptyTest()
 	testResult.Metadata = metadata
 
 	// Creates a secret
@@ -37,13 +53,18 @@ func (t *TramontoOne) CreateTest(name, description string) ([]byte, error) {
 
 	testResult.Ipfs = ipfsHash
 
-	// Adds test to database
-	if err := t.db.InsertTest(testResult); err != nil {
+	// Adds test to database, unless another test took its name meanwhile
+	err = t.tests.InsertTestWithUniqueName(testResult)
+	if err == oneDb.ErrTestNameExists {
+		return nil, errors.New("A test named " + metadata.Name + " already exists")
+	}
+
+	if err != nil {
 		return nil, errors.New("(Database) Error inserting to the database: " + err.Error())
 	}
 
 	// Shares in IPNS
-	ipnsHash, err := t.ShareTest(ipfsHash, name)
+	ipnsHash, err := t.ShareTest(ipfsHash, metadata.ID)
 	if err != nil {
 		return nil, err
 	}
@@ -51,6 +72,10 @@ func (t *TramontoOne) CreateTest(name, description string) ([]byte, error) {
 	testResult.Ipns = ipnsHash
 	testResult.IpnsKeyCreated = true
 
+	if err = t.cacheTest(ipnsHash, ipfsHash, secret, metadata); err != nil {
+		return nil, err
+	}
+
 	jsonResponse, err := json.Marshal(testResult)
 	if err != nil {
 		return nil, errors.New("Error parsing to json: " + err.Error())
@@ -60,6 +85,8 @@ func (t *TramontoOne) CreateTest(name, description string) ([]byte, error) {
 }
 
 // ImportTest imports a new test to the node
+// Importing a test already in the node refreshes it to the published revision,
+// restoring it when archived, and marks the response as alreadyImported
 func (t *TramontoOne) ImportTest(ipns, secret string) ([]byte, error) {
 	// Reads the test from IPNS
 	ipfs, test, err := t.ipfs.GetTestByIPNS(ipns, secret)
@@ -67,6 +94,11 @@ func (t *TramontoOne) ImportTest(ipns, secret string) ([]byte, error) {
 		return nil, errors.New("(IPNS) Could not find test: " + err.Error())
 	}
 
+	existingTest, exists, err := t.findImportedTest(ipns)
+	if err != nil {
+		return nil, err
+	}
+
 	testToInsert := entities.Test{
 		Ipfs:           ipfs,
 		Ipns:           ipns,
@@ -76,11 +108,42 @@ func (t *TramontoOne) ImportTest(ipns, secret string) ([]byte, error) {
 		Metadata:       test,
 	}
 
-	// Inserts the test in the database
-	if err = t.db.InsertTest(testToInsert); err != nil {
+	pending := false
+
+	if exists {
+		pending, err = t.db.HasPendingOperations(ipns)
+		if err != nil {
+			return nil, errors.New("(Database) " + err.Error())
+		}
+
+		// Edits pending sync are kept, the outbox merges them when publishing
+		if existingTest.Ipfs != ipfs && !pending {
+			if err = t.tests.UpdateIPFSHash(ipns, ipfs); err != nil {
+				return nil, errors.New("(Database) Could not update IPFS: " + err.Error())
+			}
+
+			existingTest.Ipfs = ipfs
+		}
+
+		testToInsert = existingTest
+		testToInsert.Metadata = test
+		testToInsert.AlreadyImported = true
+	} else if err = t.tests.InsertTest(testToInsert); err != nil {
+		// Inserts the test in the database
 		return nil, errors.New("(Database) Could not insert: " + err.Error())
 	}
 
+	if err = t.recordSync(ipns, ipfs, entities.SyncSourceResolve); err != nil {
+		return nil, err
+	}
+
+	// The cache of a test pending sync keeps its local revision
+	if !pending {
+		if err = t.cacheTest(ipns, testToInsert.Ipfs, secret, test); err != nil {
+			return nil, err
+		}
+	}
+
 	jsonResponse, err := json.Marshal(testToInsert)
 	if err != nil {
 		return nil, errors.New("Error parsing to json: " + err.Error())
@@ -89,14 +152,72 @@ func (t *TramontoOne) ImportTest(ipns, secret string) ([]byte, error) {
 	return jsonResponse, nil
 }
 
-// GetTests gets all the tests from the database
-func (t *TramontoOne) GetTests() ([]byte, error) {
+// findImportedTest finds a test already in the node
+// An archived test is restored
+func (t *TramontoOne) findImportedTest(ipns string) (entities.Test, bool, error) {
+	test, err := t.tests.FindTestByIpns(ipns)
+	if err == nil {
+		return test, true, nil
+	}
+
+	if err != sql.ErrNoRows {
+		return entities.Test{}, false, errors.New("(Database) Could not find test: " + err.Error())
+	}
+
+	test, err = t.tests.FindAnyTestByIpns(ipns)
+	if err == sql.ErrNoRows {
+		return entities.Test{}, false, nil
+	}
+
+	if err != nil {
+		return entities.Test{}, false, errors.New("(Database) Could not find test: " + err.Error())
+	}
+
+	if err = t.tests.RestoreTest(ipns); err != nil {
+		return entities.Test{}, false, errors.New("(Database) Error restoring test: " + err.Error())
+	}
+
+	return test, true, nil
+}
+
+// GetTests gets a page of tests from the database
+// The query is a JSON of entities.TestQuery, an empty query lists the active tests
+// Returns a JSON of entities.TestPage, its nextCursor is used to query the next page
+func (t *TramontoOne) GetTests(queryJSON []byte) ([]byte, error) {
+	query, err := entities.TestQueryFromJSON(queryJSON)
+	if err != nil {
+		return nil, err
+	}
+
 	// Finds tests
-	tests, err := t.db.FindTests()
+	page, err := t.tests.FindTestsByQuery(query)
+	if err != nil {
+		return nil, errors.New("(Database) Error finding tests: " + err.Error())
+	}
+
+	if err = t.attachCachedDetails(page.Tests); err != nil {
+		return nil, err
+	}
+
+	jsonData, err := json.Marshal(page)
+	if err != nil {
+		return nil, errors.New("Error parsing to json: " + err.Error())
+	}
+
+	return jsonData, nil
+}
+
+// GetFavoriteTests gets the tests marked as favorite
+func (t *TramontoOne) GetFavoriteTests() ([]byte, error) {
+	tests, err := t.tests.FindFavoriteTests()
 	if err != nil {
 		return nil, errors.New("(Database) Error finding tests: " + err.Error())
 	}
 
+	if err = t.attachCachedDetails(tests); err != nil {
+		return nil, err
+	}
+
 	jsonData, err := json.Marshal(tests)
 	if err != nil {
 		return nil, errors.New("Error parsing to json: " + err.Error())
@@ -105,15 +226,24 @@ func (t *TramontoOne) GetTests() ([]byte, error) {
 	return jsonData, nil
 }
 
+// SetFavorite marks or unmarks a test as favorite
+func (t *TramontoOne) SetFavorite(ipnsHash string, isFavorite bool) error {
+	if err := t.tests.SetFavorite(ipnsHash, isFavorite); err != nil {
+		return errors.New("(Database) Error updating favorite: " + err.Error())
+	}
+
+	return nil
+}
+
 // GetTestByIPFS returns a single test by its IPFS hash
 func (t *TramontoOne) GetTestByIPFS(ipfsHash, secret string) ([]byte, error) {
 	// Get Metadata from IPFS
-	metadata, err := t.ipfs.GetTestByIPFS(ipfsHash, secret)
+	metadata, err := t.readMetadata(ipfsHash, secret)
 	if err != nil {
 		return nil, errors.New("(IPFS) Cannot read from IPFS: " + err.Error())
 	}
 
-	ipnsKeyExists, ipnsKey, err := t.ipfs.GetKeyWithName(metadata.Name)
+	ipnsKeyExists, ipnsKey, err := t.ipfs.GetKeyWithName(metadata.ID)
 	if err != nil {
 		return nil, errors.New("Error verifing IPNS key: " + err.Error())
 	}
@@ -141,27 +271,52 @@ func (t *TramontoOne) GetTestByIPFS(ipfsHash, secret string) ([]byte, error) {
 // GetTestByIPNS returns a single test by its IPNS hash
 func (t *TramontoOne) GetTestByIPNS(ipnsHash, secret string) ([]byte, error) {
 	// Gets the test from database
-	databaseTest, err := t.db.FindTestByIpns(ipnsHash)
+	databaseTest, err := t.tests.FindTestByIpns(ipnsHash)
 	if err != nil {
 		return nil, errors.New("(Database) Could not find test: " + err.Error())
 	}
 
-	// Get Metadata from IPNS
-	ipfsHash, metadata, err := t.ipfs.GetTestByIPNS(ipnsHash, secret)
+	pending, err := t.db.HasPendingOperations(ipnsHash)
+	if err != nil {
+		return nil, errors.New("(Database) " + err.Error())
+	}
+
+	var ipfsHash string
+	var metadata entities.Metadata
+
+	if pending {
+		// Edits pending sync are not published yet, so the local revision is shown
+		ipfsHash = databaseTest.Ipfs
+		metadata, err = t.readMetadata(ipfsHash, databaseTest.Secret)
+	} else {
+		// Get Metadata from IPNS
+		ipfsHash, metadata, err = t.ipfs.GetTestByIPNS(ipnsHash, secret)
+		if err != nil {
+			t.recordSyncError(ipnsHash, err)
+		} else if err = t.recordSync(ipnsHash, ipfsHash, entities.SyncSourceResolve); err != nil {
+			return nil, err
+		}
+	}
+
 	if err != nil {
 		return nil, errors.New("(IPNS) Cannot read from IPNS: " + err.Error())
 	}
 
 	// The test was updated since the last access
 	if databaseTest.Ipfs != ipfsHash {
-		if err = t.db.UpdateIPFSHash(ipnsHash, ipfsHash); err != nil {
+		if err = t.tests.UpdateIPFSHash(ipnsHash, ipfsHash); err != nil {
 			return nil, errors.New("(Database) Could not update IPFS: " + err.Error())
 		}
 
 		databaseTest.Ipfs = ipfsHash
 	}
 
+	if err = t.cacheTest(ipnsHash, ipfsHash, databaseTest.Secret, metadata); err != nil {
+		return nil, err
+	}
+
 	databaseTest.Metadata = metadata
+	databaseTest.Approved = metadata.ApprovalState().Approved
 
 	// Return the Test
 	jsonData, err := json.Marshal(databaseTest)
@@ -173,18 +328,27 @@ func (t *TramontoOne) GetTestByIPNS(ipnsHash, secret string) ([]byte, error) {
 }
 
 // ShareTest shares a test with IPNS
-func (t *TramontoOne) ShareTest(ipfsHash, testName string) (string, error) {
+// The IPNS key is named after the test ID
+func (t *TramontoOne) ShareTest(ipfsHash, testID string) (string, error) {
 	// Share with IPNS
-	ipnsHash, err := t.ipfs.PublishToIPNS(ipfsHash, testName)
+	ipnsHash, err := t.ipfs.PublishToIPNS(ipfsHash, testID)
 	if err != nil {
 		return "", errors.New("(IPNS) Error sharing test: " + err.Error())
 	}
 
 	// Saves the IPNS hash in the database
-	if err = t.db.SaveSharedTest(ipfsHash, ipnsHash); err != nil {
+	if err = t.tests.SaveSharedTest(ipfsHash, ipnsHash); err != nil {
 		return "", errors.New("(Database) Error saving hash: " + err.Error())
 	}
 
+	if err = t.recordSync(ipnsHash, ipfsHash, entities.SyncSourcePublish); err != nil {
+		return "", err
+	}
+
+	if err = t.trackIPNSRecord(ipnsHash, testID, ipfsHash); err != nil {
+		return "", err
+	}
+
 	// Return the IPNS hash
 	return ipnsHash, nil
 }
@@ -192,7 +356,7 @@ func (t *TramontoOne) ShareTest(ipfsHash, testName string) (string, error) {
 // AddMember adds a new member to an existing test
 func (t *TramontoOne) AddMember(ipns, name, email, role string) ([]byte, error) {
 	// Finds test in the database
-	test, err := t.db.FindTestByIpns(ipns)
+	test, err := t.tests.FindTestByIpns(ipns)
 	if err != nil {
 		return nil, errors.New("(Database) Test not found: " + err.Error())
 	}
@@ -203,11 +367,16 @@ func (t *TramontoOne) AddMember(ipns, name, email, role string) ([]byte, error)
 	}
 
 	// Reads test config file from IPFS
-	ipfsTest, err := t.ipfs.GetTestByIPFS(test.Ipfs, test.Secret)
+	baseTest, err := t.readMetadata(test.Ipfs, test.Secret)
 	if err != nil {
 		return nil, errors.New("(IPFS) Test not found: " + err.Error())
 	}
 
+	// Members without a role get the default one
+	if role == "" {
+		role = t.currentSettings().DefaultMemberRole
+	}
+
 	// Creates the member entity
 	newMember, err := entities.NewMember(name, email, role)
 	if err != nil {
@@ -215,25 +384,18 @@ func (t *TramontoOne) AddMember(ipns, name, email, role string) ([]byte, error)
 	}
 
 	// Adds the member to the metadata
+	ipfsTest := baseTest
 	if err = ipfsTest.AddMember(newMember); err != nil {
 		return nil, errors.New("Error adding member: " + err.Error())
 	}
 
-	// Uploads the test to IPFS
-	newIpfsHash, err := t.ipfs.UploadTest(ipfsTest, test.Secret)
-	if err != nil {
-		return nil, errors.New("(IPFS) Error uploading test: " + err.Error())
-	}
-
-	// Publishes the new Metadata to IPNS
-	// We should update the database just after a succeded publish to IPNS
-	if _, err := t.ipfs.PublishToIPNS(newIpfsHash, test.Metadata.Name); err != nil {
-		return nil, errors.New("(IPNS) Error publishing: " + err.Error())
+	if err = t.appendActivity(&ipfsTest, entities.ActivityAddMember, newMember.Email); err != nil {
+		return nil, err
 	}
 
-	// Updates the database
-	if err = t.db.UpdateIPFSHash(ipns, newIpfsHash); err != nil {
-		return nil, errors.New("(Database) Error updating data: " + err.Error())
+	// Publishes the new revision
+	if _, ipfsTest, err = t.publishMetadata(test, baseTest, ipfsTest); err != nil {
+		return nil, err
 	}
 
 	// Return the Test
@@ -248,13 +410,13 @@ func (t *TramontoOne) AddMember(ipns, name, email, role string) ([]byte, error)
 // GetArtifact gets an artifact and shows it to the user
 func (t *TramontoOne) GetArtifact(ipnsHash, artifactHash string) (entities.Artifact, []byte, error) {
 	// Gets the test from database
-	databaseTest, err := t.db.FindTestByIpns(ipnsHash)
+	databaseTest, err := t.tests.FindTestByIpns(ipnsHash)
 	if err != nil {
 		return entities.Artifact{}, nil, errors.New("(Database) Could not find test: " + err.Error())
 	}
 
 	// Get Metadata from IPNS
-	metadata, err := t.ipfs.GetTestByIPFS(databaseTest.Ipfs, databaseTest.Secret)
+	metadata, err := t.readMetadata(databaseTest.Ipfs, databaseTest.Secret)
 	if err != nil {
 		return entities.Artifact{}, nil, errors.New("(IPFS) Cannot read from IPFS: " + err.Error())
 	}
@@ -279,13 +441,18 @@ func (t *TramontoOne) GetArtifact(ipnsHash, artifactHash string) (entities.Artif
 		return entities.Artifact{}, nil, errors.New("(IPFS) Could not read artifact: " + err.Error())
 	}
 
+	// Artifacts of imported tests are indexed when read
+	if err = t.indexArtifactContent(ipnsHash, artifactInfo.Name, artifactInfo.Hash, content, artifactInfo.Headers); err != nil {
+		return entities.Artifact{}, nil, err
+	}
+
 	return *artifactInfo, content, nil
 }
 
 // AddArtifact adds a new artifact to an existing test
 func (t *TramontoOne) AddArtifact(ipnsHash, name, description string, file []byte, fileHeaders map[string][]string) ([]byte, error) {
 	// Gets the test from database
-	databaseTest, err := t.db.FindTestByIpns(ipnsHash)
+	databaseTest, err := t.tests.FindTestByIpns(ipnsHash)
 	if err != nil {
 		return nil, errors.New("(Database) Could not find test: " + err.Error())
 	}
@@ -296,7 +463,7 @@ func (t *TramontoOne) AddArtifact(ipnsHash, name, description string, file []byt
 	}
 
 	// Get Metadata from IPNS
-	metadata, err := t.ipfs.GetTestByIPFS(databaseTest.Ipfs, databaseTest.Secret)
+	baseMetadata, err := t.readMetadata(databaseTest.Ipfs, databaseTest.Secret)
 	if err != nil {
 		return nil, errors.New("(IPFS) Cannot read from IPFS: " + err.Error())
 	}
@@ -308,30 +475,29 @@ func (t *TramontoOne) AddArtifact(ipnsHash, name, description string, file []byt
 	}
 
 	// Adds the artifact to the test
+	metadata := baseMetadata
 	if err = metadata.AddArtifact(name, description, ipfsHash, fileHeaders); err != nil {
 		return nil, errors.New("Error adding artifact to test: " + err.Error())
 	}
 
-	databaseTest.Metadata = metadata
+	if err = t.appendActivity(&metadata, entities.ActivityAddArtifact, ipfsHash); err != nil {
+		return nil, err
+	}
 
-	// Uploads the test to IPFS
-	newIpfsHash, err := t.ipfs.UploadTest(metadata, databaseTest.Secret)
+	// Publishes the new revision
+	newIpfsHash, metadata, err := t.publishMetadata(databaseTest, baseMetadata, metadata)
 	if err != nil {
-		return nil, errors.New("(IPFS) Error uploading test: " + err.Error())
+		return nil, err
 	}
 
-	// Publishes the new Metadata to IPNS
-	// We should update the database just after a succeded publish to IPNS
-	if _, err := t.ipfs.PublishToIPNS(newIpfsHash, databaseTest.Metadata.Name); err != nil {
-		return nil, errors.New("(IPNS) Error publishing: " + err.Error())
-	}
+	databaseTest.Ipfs = newIpfsHash
 
-	// Updates the database
-	if err = t.db.UpdateIPFSHash(ipnsHash, newIpfsHash); err != nil {
-		return nil, errors.New("(Database) Error updating data: " + err.Error())
+	if err = t.indexArtifactContent(ipnsHash, name, ipfsHash, file, fileHeaders); err != nil {
+		return nil, err
 	}
 
-	databaseTest.Ipfs = newIpfsHash
+	databaseTest.Metadata = metadata
+	databaseTest.Approved = metadata.ApprovalState().Approved
 
 	// Return the Test
 	jsonData, err := json.Marshal(databaseTest)
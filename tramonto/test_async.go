@@ -12,6 +12,11 @@ func (t *TramontoOne) ShareTestAsync(ipfsHash, keyName string, callback entities
 			return
 		}
 
+		if err = t.trackIPNSRecord(ipnsHash, keyName, ipfsHash); err != nil {
+			callback.Invoke("{\"error\":\"" + err.Error() + "\"}")
+			return
+		}
+
 		// Return the IPNS hash
 		callback.Invoke("{\"ipns\":\"" + ipnsHash + "\"}")
 	}()
@@ -2,6 +2,7 @@ package tramonto
 
 import (
 	"errors"
+	"sync"
 
 	"gitlab.com/tramonto-one/go-tramonto/entities"
 
@@ -14,25 +15,113 @@ import (
 
 // TramontoOne represents the Tramonto One lib
 type TramontoOne struct {
-	ipfs *oneIpfs.OneIPFS
+	ipfs oneIpfs.ContentStore
 	db   *db.OneSQLite
 	http *oneHttp.OneHTTP
+
+	// Storage of the tests
+	tests db.TestRepository
+
+	// Path of the database and the IPFS repo
+	path string
+
+	// If Setup started the instance
+	started bool
+
+	// Settings of the node, read by the scheduler too
+	settings    entities.Settings
+	settingsMux *sync.RWMutex
+
+	// Scheduler of the background jobs
+	schedulerStop chan struct{}
+	schedulerDone chan struct{}
+	schedulerWake chan struct{}
+
+	// Jobs requested to run right away
+	jobRequests map[string]bool
+	jobMux      *sync.Mutex
+
+	// Receives the records that could not be published again
+	republishCallback entities.Callback
 }
 
+// Storages of the tests
+const (
+	// StorageSQLite stores the tests in the SQLite database
+	StorageSQLite = "sqlite"
+
+	// StorageMemory stores the tests in memory, they are lost when the app closes
+	// Caches, jobs and the other records are still kept in the SQLite database,
+	// so the device cannot be backed up
+	StorageMemory = "memory"
+
+	// StorageDemo keeps the contents in memory instead of IPFS, working offline
+	// The tests point to contents lost when the app closes, so the path should be disposable
+	StorageDemo = "demo"
+)
+
 // NewTramontoOne returns a new instance of Tramonto One library
 func NewTramontoOne(path string) (*TramontoOne, error) {
-	// Initializes IPFS
-	ipfs, err := oneIpfs.InitializeOneIPFS(path)
+	return NewTramontoOneWithStorage(path, StorageSQLite)
+}
+
+// NewTramontoOneWithStorage returns a new instance of Tramonto One library
+// storing the tests in the given storage
+func NewTramontoOneWithStorage(path, storage string) (*TramontoOne, error) {
+	// Initializes the database
+	db, err := oneDb.OpenOneSQLite(path)
 	if err != nil {
-		return nil, errors.New("Error initializing OneIPFS: " + err.Error())
+		return nil, errors.New("Error initializing OneSQLite: " + err.Error())
+	}
+
+	var tests oneDb.TestRepository = db
+	var content oneIpfs.ContentStore
+
+	switch storage {
+	case StorageSQLite, StorageMemory:
+		// Initializes IPFS
+		content, err = oneIpfs.InitializeOneIPFS(path)
+		if err != nil {
+			return nil, errors.New("Error initializing OneIPFS: " + err.Error())
+		}
+
+		if storage == StorageMemory {
+			tests = oneDb.NewMemoryTestRepository()
+		}
+	case StorageDemo:
+		content, err = oneIpfs.NewMemoryStore(oneIpfs.NewMemoryNetwork())
+		if err != nil {
+			return nil, errors.New("Error initializing memory store: " + err.Error())
+		}
+	default:
+		return nil, errors.New("Unknown storage " + storage)
 	}
 
+	return newTramontoOne(path, db, tests, content)
+}
+
+// NewTramontoOneWithStores returns a new instance of Tramonto One library
+// storing the tests and the contents in the given stores, as the in-memory ones in unit tests
+// Only the tests and the contents are swapped: the caches, outbox, jobs, signing keys,
+// suites and the other records are still kept in a SQLite database, opened in memory
+// so nothing is written to the path. The instance still needs cgo SQLite
+func NewTramontoOneWithStores(path string, tests oneDb.TestRepository, content oneIpfs.ContentStore) (*TramontoOne, error) {
 	// Initializes the database
-	db, err := oneDb.OpenOneSQLite(path)
+	db, err := oneDb.OpenMemorySQLite()
 	if err != nil {
 		return nil, errors.New("Error initializing OneSQLite: " + err.Error())
 	}
 
+	// The database is new, so it is ready before Setup
+	if err = db.MigrateTables(); err != nil {
+		return nil, errors.New("Error migrating OneSQLite: " + err.Error())
+	}
+
+	return newTramontoOne(path, db, tests, content)
+}
+
+// newTramontoOne returns a new instance of Tramonto One library
+func newTramontoOne(path string, db *oneDb.OneSQLite, tests oneDb.TestRepository, content oneIpfs.ContentStore) (*TramontoOne, error) {
 	// Configures HTTP server
 	http, err := oneHttp.InitializeHTTPServer()
 	if err != nil {
@@ -40,9 +129,16 @@ func NewTramontoOne(path string) (*TramontoOne, error) {
 	}
 
 	tramontoOne := &TramontoOne{
-		ipfs: ipfs,
-		db:   db,
-		http: http,
+		ipfs:          content,
+		db:            db,
+		http:          http,
+		tests:         tests,
+		path:          path,
+		settings:      entities.DefaultSettings(),
+		settingsMux:   new(sync.RWMutex),
+		schedulerWake: make(chan struct{}, 1),
+		jobRequests:   map[string]bool{},
+		jobMux:        new(sync.Mutex),
 	}
 
 	return tramontoOne, nil
@@ -50,6 +146,16 @@ func NewTramontoOne(path string) (*TramontoOne, error) {
 
 // Setup starts everything in the One instance
 func (one *TramontoOne) Setup() error {
+	// Migrates database
+	if err := one.db.MigrateTables(); err != nil {
+		return errors.New("Error migrating IPFS: " + err.Error())
+	}
+
+	// Configures IPFS and HTTP from the stored settings
+	if err := one.loadSettings(); err != nil {
+		return err
+	}
+
 	// Initializes the repo if its not
 	if err := one.ipfs.InitRepo(); err != nil {
 		return errors.New("Error initializing repo: " + err.Error())
@@ -60,9 +166,9 @@ func (one *TramontoOne) Setup() error {
 		return errors.New("Error starting IPFS: " + err.Error())
 	}
 
-	// Migrates database
-	if err := one.db.MigrateTables(); err != nil {
-		return errors.New("Error migrating IPFS: " + err.Error())
+	// Runs the background jobs, as publishing the edits made while offline
+	if err := one.startScheduler(); err != nil {
+		return err
 	}
 
 	// Configures endpoints
@@ -74,7 +180,10 @@ func (one *TramontoOne) Setup() error {
 		return one.AddArtifact(ipns, name, description, file, fileHeaders)
 	})
 
+	one.started = true
+
 	// Starts HTTP server
+	one.http.SetPort(one.currentSettings().HTTPPort)
 	go func() {
 		if err := one.http.Start(); err != nil {
 			panic(err)
@@ -83,3 +192,15 @@ func (one *TramontoOne) Setup() error {
 
 	return nil
 }
+
+// Shutdown stops the background jobs and the IPFS node
+func (one *TramontoOne) Shutdown() error {
+	one.stopScheduler()
+	one.started = false
+
+	if err := one.ipfs.Stop(); err != nil {
+		return errors.New("Error stopping IPFS: " + err.Error())
+	}
+
+	return nil
+}